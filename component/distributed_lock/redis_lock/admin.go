@@ -0,0 +1,120 @@
+package common
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Holder 锁的持有者信息
+type Holder struct {
+	LockKey    string        // 锁key
+	Token      string        // 锁的值
+	Host       string        // 持有者主机名
+	Pid        int           // 持有者进程号
+	AcquiredAt time.Time     // 获取到锁的时间
+	TTL        time.Duration // 锁剩余时间
+}
+
+// lockAdmin 锁的管理
+type lockAdmin struct {
+	redis redis.UniversalClient // redis实例
+	count int64                 // scan每次的数量
+}
+
+// NewAdmin 初始化锁管理
+func NewAdmin(redis redis.UniversalClient) *lockAdmin {
+	return &lockAdmin{
+		redis: redis,
+		count: 100,
+	}
+}
+
+// SetScanCount 设置scan每次扫描的数量，初始为100
+func (admin *lockAdmin) SetScanCount(count int64) *lockAdmin {
+	admin.count = count
+	return admin
+}
+
+// ListHolders 列出前缀下当前所有锁的持有者
+// 集群模式下只会扫描到当前节点上的key
+func (admin *lockAdmin) ListHolders(ctx context.Context, prefix string) ([]*Holder, error) {
+	holders := make([]*Holder, 0)
+	var cursor uint64
+	for {
+		keys, next, err := admin.redis.Scan(ctx, cursor, prefix+"*", admin.count).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			// 跳过持有者信息的key
+			if strings.HasSuffix(key, HolderKeySuffix) {
+				continue
+			}
+			holder, err := admin.GetHolder(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if holder != nil {
+				holders = append(holders, holder)
+			}
+		}
+		cursor = next
+		if cursor == 0 {
+			break
+		}
+	}
+	return holders, nil
+}
+
+// GetHolder 获取锁的持有者，锁不存在时返回nil
+func (admin *lockAdmin) GetHolder(ctx context.Context, lockKey string) (*Holder, error) {
+	pipe := admin.redis.Pipeline()
+	tokenCmd := pipe.Get(ctx, lockKey)
+	ttlCmd := pipe.PTTL(ctx, lockKey)
+	metaCmd := pipe.HGetAll(ctx, lockKey+HolderKeySuffix)
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	token, err := tokenCmd.Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	holder := &Holder{
+		LockKey: lockKey,
+		Token:   token,
+		TTL:     ttlCmd.Val(),
+	}
+	meta := metaCmd.Val()
+	// 持有者信息与锁不是同一个持有者时不展示（锁已被其他人重新获取）
+	if meta[holderFieldToken] != token {
+		return holder, nil
+	}
+	holder.Host = meta[holderFieldHost]
+	holder.Pid, _ = strconv.Atoi(meta[holderFieldPid])
+	if acquiredAt, err := strconv.ParseInt(meta[holderFieldAcquiredAt], 10, 64); err == nil {
+		holder.AcquiredAt = time.Unix(0, acquiredAt*int64(time.Millisecond))
+	}
+	return holder, nil
+}
+
+// ForceRelease 强制释放锁（不校验token），返回锁是否存在
+func (admin *lockAdmin) ForceRelease(ctx context.Context, lockKey string) (bool, error) {
+	n, err := admin.redis.Del(ctx, lockKey).Result()
+	if err != nil {
+		return false, err
+	}
+	err = admin.redis.Del(ctx, lockKey+HolderKeySuffix).Err()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -3,12 +3,39 @@ package common
 import (
 	"context"
 	"errors"
+	"github.com/actorbuf/iota/trace"
 	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+const (
+	operationLockAcquire = "RedisLock-Acquire"
+	operationLockHold    = "RedisLock-Hold"
+	traceTagLockKey      = "lock.key"
+	traceTagLockAttempts = "lock.attempts"
+	traceTagLockAcquired = "lock.acquired"
+)
+
+// HolderKeySuffix 持有者信息的key后缀，与锁key放在一起
+const HolderKeySuffix = ":holder"
+
+// 持有者信息hash的字段
+const (
+	holderFieldToken      = "token"
+	holderFieldHost       = "host"
+	holderFieldPid        = "pid"
+	holderFieldAcquiredAt = "acquired_at"
+)
+
+// ErrLockWaitTimeout 等待锁超时（仅用于指标上报，GetLock依旧返回false）
+var ErrLockWaitTimeout = errors.New("lock wait timeout")
+
 type redisLock struct {
 	redis   redis.UniversalClient // redis实例
 	lockKey string                // 锁key
@@ -16,6 +43,11 @@ type redisLock struct {
 	ttl     int                   // 秒级ttl
 	wait    bool                  // 是否等待
 	step    int                   // 尝试锁次数
+	ctx     context.Context       // 上下文，用于链路追踪
+	metrics Metrics               // 指标上报
+
+	acquiredAt time.Time        // 获取到锁的时间
+	holdSpan   opentracing.Span // 持有锁的span，Unlock时结束
 }
 
 // NewLock 初始化锁
@@ -26,7 +58,27 @@ func NewLock(redis redis.UniversalClient) *redisLock {
 		ttl:     5,
 		wait:    true,
 		step:    250,
+		ctx:     context.Background(),
+		metrics: noopMetrics{},
+	}
+}
+
+// WithContext 设置上下文，获取锁与持有锁的span会挂在ctx的span下
+func (redisLock *redisLock) WithContext(ctx context.Context) *redisLock {
+	if ctx == nil {
+		ctx = context.Background()
 	}
+	redisLock.ctx = ctx
+	return redisLock
+}
+
+// SetMetrics 设置指标上报
+func (redisLock *redisLock) SetMetrics(metrics Metrics) *redisLock {
+	if metrics == nil {
+		metrics = noopMetrics{}
+	}
+	redisLock.metrics = metrics
+	return redisLock
 }
 
 // SetTTl 设置锁时间（秒）初始为5秒
@@ -60,14 +112,23 @@ func (redisLock *redisLock) GetLock() (res bool, err error) {
 		return false, errors.New("lock key is required")
 	}
 
+	span := trace.ObtainChildSpan(redisLock.ctx, operationLockAcquire)
+	span.SetTag(traceTagLockKey, redisLock.lockKey)
+	start := time.Now()
+
 	// 随机一个value
 	redisLock.token = uuid.NewString()
 	index := 0
+	attempts := 0
 	times := redisLock.ttl * 1000 / redisLock.step
 	for index < times {
-		res, err = redisLock.redis.SetNX(context.Background(), redisLock.lockKey, redisLock.token, time.Millisecond*time.Duration(redisLock.ttl*1000)).Result()
+		attempts++
+		res, err = redisLock.redis.SetNX(redisLock.ctx, redisLock.lockKey, redisLock.token, time.Millisecond*time.Duration(redisLock.ttl*1000)).Result()
 		if err != nil {
-			return
+			break
+		}
+		if !res {
+			redisLock.metrics.IncContention(redisLock.lockKey)
 		}
 		if redisLock.wait && !res {
 			// milliseconds
@@ -78,13 +139,80 @@ func (redisLock *redisLock) GetLock() (res bool, err error) {
 		}
 	}
 
+	// 记录持有者信息
+	if err == nil && res {
+		err = redisLock.setHolder()
+		if err != nil {
+			// 持有者信息写入失败，释放掉刚拿到的锁
+			_, _ = redisLock.release()
+			res = false
+		}
+	}
+
+	wait := time.Since(start)
+	redisLock.metrics.ObserveWait(redisLock.lockKey, wait, res)
+	span.SetTag(traceTagLockAttempts, attempts)
+	span.SetTag(traceTagLockAcquired, res)
+	switch {
+	case err != nil:
+		redisLock.metrics.IncFailure(redisLock.lockKey, err)
+		ext.Error.Set(span, true)
+		span.SetTag(trace.TagError, err.Error())
+	case !res && redisLock.wait:
+		redisLock.metrics.IncFailure(redisLock.lockKey, ErrLockWaitTimeout)
+	}
+	span.Finish()
+
+	if res {
+		redisLock.acquiredAt = time.Now()
+		redisLock.holdSpan = trace.ObtainChildSpan(redisLock.ctx, operationLockHold)
+		redisLock.holdSpan.SetTag(traceTagLockKey, redisLock.lockKey)
+	}
 	return
 }
 
+// setHolder 写入持有者信息：host、pid、acquired_at
+func (redisLock *redisLock) setHolder() error {
+	host, _ := os.Hostname()
+	holderKey := redisLock.lockKey + HolderKeySuffix
+	pipe := redisLock.redis.TxPipeline()
+	pipe.HSet(redisLock.ctx, holderKey,
+		holderFieldToken, redisLock.token,
+		holderFieldHost, host,
+		holderFieldPid, strconv.Itoa(os.Getpid()),
+		holderFieldAcquiredAt, strconv.FormatInt(time.Now().UnixNano()/1e6, 10),
+	)
+	pipe.Expire(redisLock.ctx, holderKey, time.Duration(redisLock.ttl)*time.Second)
+	_, err := pipe.Exec(redisLock.ctx)
+	return err
+}
+
 // Unlock
 // 用EVAL的解锁，删锁带token（锁的值），防止删除锁的时候出现误删
 // 结果res一般不需要接收，err接收一下
 func (redisLock *redisLock) Unlock() (res bool, err error) {
-	res, err = redisLock.redis.Do(context.Background(), "EVAL", "if redis.call(\"GET\", KEYS[1]) == ARGV[1] then return redis.call(\"DEL\", KEYS[1]) else return 0 end", "1", redisLock.lockKey, redisLock.token).Bool()
+	res, err = redisLock.release()
+	if err != nil {
+		redisLock.metrics.IncFailure(redisLock.lockKey, err)
+	}
+	if redisLock.holdSpan != nil {
+		redisLock.metrics.ObserveHold(redisLock.lockKey, time.Since(redisLock.acquiredAt))
+		if err != nil {
+			ext.Error.Set(redisLock.holdSpan, true)
+			redisLock.holdSpan.SetTag(trace.TagError, err.Error())
+		}
+		redisLock.holdSpan.Finish()
+		redisLock.holdSpan = nil
+	}
+	return
+}
+
+// release 删除锁以及持有者信息
+func (redisLock *redisLock) release() (res bool, err error) {
+	res, err = redisLock.redis.Do(redisLock.ctx, "EVAL", "if redis.call(\"GET\", KEYS[1]) == ARGV[1] then return redis.call(\"DEL\", KEYS[1]) else return 0 end", "1", redisLock.lockKey, redisLock.token).Bool()
+	if err != nil || !res {
+		return
+	}
+	err = redisLock.redis.Do(redisLock.ctx, "EVAL", "if redis.call(\"HGET\", KEYS[1], \"token\") == ARGV[1] then return redis.call(\"DEL\", KEYS[1]) else return 0 end", "1", redisLock.lockKey+HolderKeySuffix, redisLock.token).Err()
 	return
 }
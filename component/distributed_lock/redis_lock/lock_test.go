@@ -0,0 +1,103 @@
+package common
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestRedis(t *testing.T) redis.UniversalClient {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	return redis.NewClient(&redis.Options{Addr: server.Addr()})
+}
+
+func TestLockHolderAndMetrics(t *testing.T) {
+	client := newTestRedis(t)
+	ctx := context.Background()
+	stats := NewStatsMetrics()
+
+	lock := NewLock(client).SetLockKey("lock:order:1").SetMetrics(stats).WithContext(ctx)
+	res, err := lock.GetLock()
+	if err != nil || !res {
+		t.Fatalf("get lock: %v %v", res, err)
+	}
+
+	// 第二个不等待的锁获取失败，记录竞争
+	other := NewLock(client).SetLockKey("lock:order:1").SetWait(false).SetMetrics(stats)
+	res, err = other.GetLock()
+	if err != nil || res {
+		t.Fatalf("other get lock: %v %v", res, err)
+	}
+
+	admin := NewAdmin(client)
+	holders, err := admin.ListHolders(ctx, "lock:order:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders) != 1 {
+		t.Fatalf("holders: %d", len(holders))
+	}
+	host, _ := os.Hostname()
+	if holders[0].Host != host || holders[0].Pid != os.Getpid() || holders[0].AcquiredAt.IsZero() {
+		t.Fatalf("holder: %+v", holders[0])
+	}
+
+	res, err = lock.Unlock()
+	if err != nil || !res {
+		t.Fatalf("unlock: %v %v", res, err)
+	}
+	holders, err = admin.ListHolders(ctx, "lock:order:")
+	if err != nil || len(holders) != 0 {
+		t.Fatalf("holders after unlock: %d %v", len(holders), err)
+	}
+
+	snapshot := stats.Snapshot()
+	if snapshot.Acquired != 1 || snapshot.Contention != 1 {
+		t.Fatalf("snapshot: %+v", snapshot)
+	}
+}
+
+func TestLockForceRelease(t *testing.T) {
+	client := newTestRedis(t)
+	ctx := context.Background()
+
+	lock := NewLock(client).SetLockKey("lock:job").SetTTl(10)
+	if res, err := lock.GetLock(); err != nil || !res {
+		t.Fatalf("get lock: %v %v", res, err)
+	}
+
+	admin := NewAdmin(client)
+	holder, err := admin.GetHolder(ctx, "lock:job")
+	if err != nil || holder == nil {
+		t.Fatalf("holder: %v %v", holder, err)
+	}
+	if holder.TTL <= 0 || holder.TTL > 10*time.Second {
+		t.Fatalf("ttl: %v", holder.TTL)
+	}
+
+	released, err := admin.ForceRelease(ctx, "lock:job")
+	if err != nil || !released {
+		t.Fatalf("force release: %v %v", released, err)
+	}
+
+	// 被强制释放后，其他人可以直接拿到锁，原持有者解锁不会误删
+	other := NewLock(client).SetLockKey("lock:job").SetWait(false)
+	if res, err := other.GetLock(); err != nil || !res {
+		t.Fatalf("other get lock: %v %v", res, err)
+	}
+	if res, err := lock.Unlock(); err != nil || res {
+		t.Fatalf("stale unlock: %v %v", res, err)
+	}
+	holder, err = admin.GetHolder(ctx, "lock:job")
+	if err != nil || holder == nil || holder.Host == "" {
+		t.Fatalf("holder after stale unlock: %+v %v", holder, err)
+	}
+}
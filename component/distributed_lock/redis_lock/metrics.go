@@ -0,0 +1,78 @@
+package common
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics 锁指标上报接口，可以对接到 prometheus 等监控系统
+type Metrics interface {
+	ObserveWait(lockKey string, wait time.Duration, acquired bool) // 获取锁的等待耗时，acquired 是否最终拿到了锁
+	ObserveHold(lockKey string, hold time.Duration)                // 锁的持有时长
+	IncContention(lockKey string)                                  // 锁竞争，尝试获取时锁已被其他人持有
+	IncFailure(lockKey string, err error)                          // 获取/释放锁失败
+}
+
+var _ Metrics = new(noopMetrics)
+var _ Metrics = new(StatsMetrics)
+
+// noopMetrics 默认不做任何上报
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveWait(string, time.Duration, bool) {}
+func (noopMetrics) ObserveHold(string, time.Duration)       {}
+func (noopMetrics) IncContention(string)                    {}
+func (noopMetrics) IncFailure(string, error)                {}
+
+// StatsMetrics 进程内的简单累计指标，不区分锁key
+type StatsMetrics struct {
+	acquired   int64 // 成功获取锁次数
+	waitTotal  int64 // 等待总耗时（纳秒）
+	holdTotal  int64 // 持有总耗时（纳秒）
+	contention int64 // 竞争次数
+	failures   int64 // 失败次数
+}
+
+// StatsSnapshot StatsMetrics 的快照
+type StatsSnapshot struct {
+	Acquired   int64
+	WaitTotal  time.Duration
+	HoldTotal  time.Duration
+	Contention int64
+	Failures   int64
+}
+
+// NewStatsMetrics 初始化进程内指标
+func NewStatsMetrics() *StatsMetrics {
+	return new(StatsMetrics)
+}
+
+func (stats *StatsMetrics) ObserveWait(_ string, wait time.Duration, acquired bool) {
+	atomic.AddInt64(&stats.waitTotal, int64(wait))
+	if acquired {
+		atomic.AddInt64(&stats.acquired, 1)
+	}
+}
+
+func (stats *StatsMetrics) ObserveHold(_ string, hold time.Duration) {
+	atomic.AddInt64(&stats.holdTotal, int64(hold))
+}
+
+func (stats *StatsMetrics) IncContention(string) {
+	atomic.AddInt64(&stats.contention, 1)
+}
+
+func (stats *StatsMetrics) IncFailure(string, error) {
+	atomic.AddInt64(&stats.failures, 1)
+}
+
+// Snapshot 获取当前的指标快照
+func (stats *StatsMetrics) Snapshot() StatsSnapshot {
+	return StatsSnapshot{
+		Acquired:   atomic.LoadInt64(&stats.acquired),
+		WaitTotal:  time.Duration(atomic.LoadInt64(&stats.waitTotal)),
+		HoldTotal:  time.Duration(atomic.LoadInt64(&stats.holdTotal)),
+		Contention: atomic.LoadInt64(&stats.contention),
+		Failures:   atomic.LoadInt64(&stats.failures),
+	}
+}
@@ -0,0 +1,554 @@
+package es_log
+
+import (
+	"context"
+	"errors"
+	log2 "log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/actorbuf/iota/component/redact"
+	"github.com/actorbuf/iota/driver/rabbitmq"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultExchangeName = "es-log-exchange"
+	DefaultQueueName    = "es-log-queue"
+	DefaultRoutingKey   = "es-log-routingkey"
+	DefaultBufferSize   = 4096
+	DefaultBatchSize    = 100
+	DefaultBatchWait    = time.Second
+	DefaultSpoolSize    = 100 << 20
+)
+
+// DropPolicy 缓冲区满时的丢弃策略
+type DropPolicy int
+
+const (
+	// DropNewest 丢弃当前写入的日志，Send 返回 ErrBufferFull
+	DropNewest DropPolicy = iota
+	// DropOldest 丢弃缓冲区中最早的日志
+	DropOldest
+)
+
+var (
+	// ErrClientClosed 客户端已关闭
+	ErrClientClosed = errors.New("es_log: client closed")
+	// ErrBufferFull 缓冲区已满，日志被丢弃
+	ErrBufferFull = errors.New("es_log: buffer full, record dropped")
+	// ErrMqAddressEmpty 没有配置 RabbitMQ 地址
+	ErrMqAddressEmpty = errors.New("es_log: mq address is empty")
+)
+
+// Config 客户端配置
+type Config struct {
+	Project      string     `yaml:"project"`       // 为空时使用 ServerName + Environment
+	ServerName   string     `yaml:"server-name"`   // 服务名
+	Environment  string     `yaml:"environment"`   // 环境
+	MqAddress    string     `yaml:"mq-address"`    // RabbitMQ 地址
+	ExchangeName string     `yaml:"exchange-name"` // 默认 es-log-exchange
+	QueueName    string     `yaml:"queue-name"`    // 默认 es-log-queue
+	RoutingKey   string     `yaml:"routing-key"`   // 默认 es-log-routingkey
+	BufferSize   int        `yaml:"buffer-size"`   // 缓冲区大小，默认 4096
+	DropPolicy   DropPolicy `yaml:"drop-policy"`   // 缓冲区满时的丢弃策略，默认 DropNewest
+	// BatchSize 每批投递的日志数，默认 100；为1时每条日志单独投递
+	BatchSize int `yaml:"batch-size"`
+	// BatchWait 攒批的最长等待时间，默认 1s
+	BatchWait time.Duration `yaml:"batch-wait"`
+	// SpoolDir 投递失败时的磁盘缓存目录，为空时不缓存直接丢弃；恢复后按顺序重放
+	SpoolDir string `yaml:"spool-dir"`
+	// SpoolMaxBytes 磁盘缓存的最大字节数，默认 100MB，超出时丢弃最早的批次
+	SpoolMaxBytes int64 `yaml:"spool-max-bytes"`
+	// ES 配置后直接写入 Elasticsearch，不再经过 RabbitMQ
+	ES *ESConfig `yaml:"es"`
+	// Policy 采样、去重与限流，为空时不过滤
+	Policy *PolicyConfig `yaml:"policy"`
+	// Redactor 写入前对 Msg 与 Define 脱敏，为空时使用 redact.Default()
+	Redactor *redact.Redactor `yaml:"-"`
+	// Levels 运行时按 channel 控制最低级别，为空时不过滤
+	Levels *LevelController `yaml:"-"`
+}
+
+// Validate 校验并填充默认值
+func (config *Config) Validate() error {
+	if config.ServerName == "" {
+		return errors.New("es_log: server name is empty")
+	}
+	if config.Environment == "" {
+		return errors.New("es_log: environment is empty")
+	}
+	if config.Project == "" {
+		config.Project = config.ServerName + config.Environment
+	}
+	if config.ExchangeName == "" {
+		config.ExchangeName = DefaultExchangeName
+	}
+	if config.QueueName == "" {
+		config.QueueName = DefaultQueueName
+	}
+	if config.RoutingKey == "" {
+		config.RoutingKey = DefaultRoutingKey
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.BatchWait <= 0 {
+		config.BatchWait = DefaultBatchWait
+	}
+	if config.SpoolMaxBytes <= 0 {
+		config.SpoolMaxBytes = DefaultSpoolSize
+	}
+	return nil
+}
+
+// mqConfig 对应的 RabbitMQ 配置
+func (config *Config) mqConfig() *rabbitmq.Config {
+	return &rabbitmq.Config{
+		Address:         config.MqAddress,
+		ExchangeName:    config.ExchangeName,
+		ExchangeKind:    "direct",
+		ExchangeDurable: true,
+		QueueName:       config.QueueName,
+		QueueDurable:    true,
+		BindKey:         config.RoutingKey,
+		DeliveryMode:    2,
+		PrefetchCount:   1,
+	}
+}
+
+// flushReq 刷新请求
+type flushReq struct {
+	done chan error
+}
+
+// Stats 客户端计数
+type Stats struct {
+	Queued       int64 // 写入缓冲区的日志数
+	Sent         int64 // 投递成功的日志数
+	Spooled      int64 // 写入磁盘缓存的日志数
+	Dropped      int64 // 丢弃的日志数
+	Suppressed   int64 // 被级别、去重、采样或限流过滤的日志数
+	SpoolPending int64 // 磁盘缓存中待重放的日志数
+}
+
+// Client es_log 客户端；同一进程可以为不同项目创建多个客户端
+type Client struct {
+	config *Config
+	sink   Sink
+	spool  *spool
+	policy *policy
+	logger Logger
+	alarm  Alarm
+
+	buffer  chan *MqData
+	flushes chan *flushReq
+
+	queued     int64
+	sent       int64
+	spooled    int64
+	dropped    int64
+	suppressed int64
+
+	mu     sync.RWMutex
+	closed bool
+	done   chan struct{}
+	// ctx 用于输出端写入，Close 的 ctx 结束时取消，放弃剩余日志
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewClient 通过配置创建客户端，配置了 ES 时直接写入 Elasticsearch，否则投递到 RabbitMQ
+func NewClient(config *Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.ES != nil && config.ES.Address != "" {
+		sink, err := NewESSink(config.ES)
+		if err != nil {
+			return nil, err
+		}
+		return NewClientWithSink(config, sink)
+	}
+	if config.MqAddress == "" {
+		return nil, ErrMqAddressEmpty
+	}
+	return NewClientWithSink(config, NewMQSink(config.mqConfig(), nil))
+}
+
+// NewClientWithSink 通过配置与指定的输出端创建客户端
+func NewClientWithSink(config *Config, sink Sink) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	client := &Client{
+		config:  config,
+		sink:    sink,
+		buffer:  make(chan *MqData, config.BufferSize),
+		flushes: make(chan *flushReq),
+		done:    make(chan struct{}),
+	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	if config.Policy != nil {
+		client.policy = newPolicy(config.Policy)
+	}
+	if config.SpoolDir != "" {
+		var err error
+		if client.spool, err = openSpool(config.SpoolDir, config.SpoolMaxBytes); err != nil {
+			return nil, err
+		}
+	}
+	go client.run()
+	return client, nil
+}
+
+// SetLogger 设置内部日志
+func (client *Client) SetLogger(logger Logger) *Client {
+	client.logger = logger
+	return client
+}
+
+// SetAlarm 设置告警
+func (client *Client) SetAlarm(alarm Alarm) *Client {
+	client.alarm = alarm
+	return client
+}
+
+// Config 获取配置
+func (client *Client) Config() *Config {
+	return client.config
+}
+
+// NewLog 创建一条绑定到当前客户端的日志
+func (client *Client) NewLog() *log {
+	log := NewLog()
+	log.client = client
+	log.ServerName = client.config.ServerName
+	log.Environment = client.config.Environment
+	log.MqData.Project = client.config.Project
+	return log
+}
+
+// Send 写入缓冲区，不会阻塞；缓冲区满时按 DropPolicy 丢弃，被 Policy 过滤的日志不返回错误
+func (client *Client) Send(data *MqData) error {
+	client.mu.RLock()
+	defer client.mu.RUnlock()
+	if client.closed {
+		return ErrClientClosed
+	}
+	if data.Project == "" {
+		data.Project = client.config.Project
+	}
+	if data.Data.CreatedAt == 0 {
+		data.Data.CreatedAt = time.Now().UnixNano() / 1e6
+	}
+	if client.config.Levels != nil && !client.config.Levels.Enabled(data.Data.Channel, data.Data.Level) {
+		atomic.AddInt64(&client.suppressed, 1)
+		return nil
+	}
+	if redactor := client.redactor(); redactor != nil {
+		data.Data.Msg = redactor.JSON(data.Data.Msg)
+		data.Data.Define = redactor.Map(data.Data.Define)
+	}
+	if client.policy != nil {
+		allow, summaries := client.policy.allow(data, time.Now())
+		for _, summary := range summaries {
+			_ = client.enqueue(summary)
+		}
+		if !allow {
+			atomic.AddInt64(&client.suppressed, 1)
+			return nil
+		}
+	}
+	return client.enqueue(data)
+}
+
+// redactor 当前使用的脱敏器
+func (client *Client) redactor() *redact.Redactor {
+	if client.config.Redactor != nil {
+		return client.config.Redactor
+	}
+	return redact.Default()
+}
+
+// enqueue 写入缓冲区，没有 ID 时生成
+func (client *Client) enqueue(data *MqData) error {
+	if data.Data.ID == "" {
+		data.Data.ID = uuid.NewString()
+	}
+	select {
+	case client.buffer <- data:
+		atomic.AddInt64(&client.queued, 1)
+		return nil
+	default:
+	}
+	if client.config.DropPolicy == DropOldest {
+		// 丢弃最早的一条再写入
+		select {
+		case <-client.buffer:
+			atomic.AddInt64(&client.dropped, 1)
+		default:
+		}
+		select {
+		case client.buffer <- data:
+			atomic.AddInt64(&client.queued, 1)
+			return nil
+		default:
+		}
+	}
+	atomic.AddInt64(&client.dropped, 1)
+	return ErrBufferFull
+}
+
+// Dropped 被丢弃的日志数
+func (client *Client) Dropped() int64 {
+	return atomic.LoadInt64(&client.dropped)
+}
+
+// Stats 获取客户端计数
+func (client *Client) Stats() Stats {
+	stats := Stats{
+		Queued:     atomic.LoadInt64(&client.queued),
+		Sent:       atomic.LoadInt64(&client.sent),
+		Spooled:    atomic.LoadInt64(&client.spooled),
+		Dropped:    atomic.LoadInt64(&client.dropped),
+		Suppressed: atomic.LoadInt64(&client.suppressed),
+	}
+	if client.spool != nil {
+		stats.SpoolPending = client.spool.pendingCount()
+	}
+	return stats
+}
+
+// Flush 等待调用前写入的日志全部投递（或者写入磁盘缓存）
+func (client *Client) Flush(ctx context.Context) error {
+	req := &flushReq{done: make(chan error, 1)}
+	select {
+	case client.flushes <- req:
+	case <-client.done:
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-req.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 投递剩余日志后关闭客户端；ctx 结束时取消正在进行的写入并立即返回 ctx 的错误，
+// 剩余日志配置了磁盘缓存时写入缓存，否则计入丢弃，输出端在剩余日志处理完后关闭
+func (client *Client) Close(ctx context.Context) error {
+	client.mu.Lock()
+	if client.closed {
+		client.mu.Unlock()
+		return nil
+	}
+	client.closed = true
+	client.mu.Unlock()
+
+	err := client.Flush(ctx)
+	close(client.buffer)
+	select {
+	case <-client.done:
+	case <-ctx.Done():
+		client.cancel()
+		go func() {
+			<-client.done
+			_ = client.sink.Close()
+		}()
+		return ctx.Err()
+	}
+	client.cancel()
+	if closeErr := client.sink.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// run 消费缓冲区，按数量与时间攒批写入输出端
+func (client *Client) run() {
+	defer close(client.done)
+	ticker := time.NewTicker(client.config.BatchWait)
+	defer ticker.Stop()
+	batch := make([]*MqData, 0, client.config.BatchSize)
+	for {
+		select {
+		case record, ok := <-client.buffer:
+			if !ok {
+				if client.policy != nil {
+					// 关闭时补发所有窗口的汇总日志
+					summaries := client.policy.sweep(time.Now().Add(client.config.Policy.DedupWindow))
+					atomic.AddInt64(&client.queued, int64(len(summaries)))
+					batch = append(batch, summaries...)
+				}
+				_ = client.flushBatch(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= client.config.BatchSize {
+				_ = client.flushBatch(batch)
+				batch = make([]*MqData, 0, client.config.BatchSize)
+			}
+		case <-ticker.C:
+			if client.policy != nil {
+				summaries := client.policy.sweep(time.Now())
+				atomic.AddInt64(&client.queued, int64(len(summaries)))
+				batch = append(batch, summaries...)
+			}
+			_ = client.flushBatch(batch)
+			batch = make([]*MqData, 0, client.config.BatchSize)
+		case req := <-client.flushes:
+			req.done <- client.drain(batch)
+			batch = make([]*MqData, 0, client.config.BatchSize)
+		}
+	}
+}
+
+// drain 把当前批次与缓冲区中的日志全部写入
+func (client *Client) drain(batch []*MqData) error {
+	var err error
+	for {
+		select {
+		case record, ok := <-client.buffer:
+			if !ok {
+				if flushErr := client.flushBatch(batch); flushErr != nil {
+					err = flushErr
+				}
+				return err
+			}
+			batch = append(batch, record)
+			if len(batch) >= client.config.BatchSize {
+				if flushErr := client.flushBatch(batch); flushErr != nil {
+					err = flushErr
+				}
+				batch = make([]*MqData, 0, client.config.BatchSize)
+			}
+		default:
+			if flushErr := client.flushBatch(batch); flushErr != nil {
+				err = flushErr
+			}
+			return err
+		}
+	}
+}
+
+// flushBatch 先重放磁盘缓存保证顺序，再写入当前批次；失败时写入磁盘缓存
+func (client *Client) flushBatch(batch []*MqData) error {
+	if err := client.ctx.Err(); err != nil {
+		// 关闭超时，不再投递
+		if client.spool == nil {
+			atomic.AddInt64(&client.dropped, int64(len(batch)))
+		} else {
+			client.spoolBatch(batch)
+		}
+		return err
+	}
+	if client.spool != nil && !client.spool.empty() {
+		if err := client.replay(); err != nil {
+			client.spoolBatch(batch)
+			return err
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	err := client.sink.Write(client.ctx, batch)
+	if err == nil {
+		atomic.AddInt64(&client.sent, int64(len(batch)))
+		return nil
+	}
+	client.alarmError(context.Background(), "es_log.write", err)
+	client.error(context.Background(), "es_log.write", err)
+	// 部分失败时只处理失败的日志
+	if bulkErr := new(BulkError); errors.As(err, &bulkErr) {
+		atomic.AddInt64(&client.sent, int64(len(batch)-len(bulkErr.Records)-bulkErr.Rejected))
+		atomic.AddInt64(&client.dropped, int64(bulkErr.Rejected))
+		batch = bulkErr.Records
+	}
+	if client.spool == nil {
+		atomic.AddInt64(&client.dropped, int64(len(batch)))
+		return err
+	}
+	client.spoolBatch(batch)
+	return err
+}
+
+// replay 按顺序重放磁盘缓存，遇到投递失败时停止
+func (client *Client) replay() error {
+	for {
+		records, err := client.spool.peek()
+		if err != nil {
+			// 无法解析的批次直接丢弃，避免阻塞后续重放
+			client.error(context.Background(), "es_log.replay", err)
+			before := client.spool.pendingCount()
+			if popErr := client.spool.pop(); popErr != nil {
+				return popErr
+			}
+			atomic.AddInt64(&client.dropped, before-client.spool.pendingCount())
+			continue
+		}
+		if records == nil {
+			return nil
+		}
+		if len(records) > 0 {
+			if err = client.sink.Write(client.ctx, records); err != nil {
+				bulkErr := new(BulkError)
+				if !errors.As(err, &bulkErr) {
+					return err
+				}
+				// 部分失败：成功的不再重放，失败的重新写入缓存末尾
+				atomic.AddInt64(&client.sent, int64(len(records)-len(bulkErr.Records)-bulkErr.Rejected))
+				atomic.AddInt64(&client.dropped, int64(bulkErr.Rejected))
+				if popErr := client.spool.pop(); popErr != nil {
+					return popErr
+				}
+				if len(bulkErr.Records) > 0 {
+					client.spoolBatch(bulkErr.Records)
+					return err
+				}
+				continue
+			}
+			atomic.AddInt64(&client.sent, int64(len(records)))
+		}
+		if err = client.spool.pop(); err != nil {
+			return err
+		}
+	}
+}
+
+// spoolBatch 写入磁盘缓存，写入失败时丢弃
+func (client *Client) spoolBatch(batch []*MqData) {
+	if len(batch) == 0 {
+		return
+	}
+	dropped, err := client.spool.push(batch)
+	atomic.AddInt64(&client.dropped, int64(dropped))
+	if err != nil {
+		atomic.AddInt64(&client.dropped, int64(len(batch)))
+		client.error(context.Background(), "es_log.spool", err)
+		return
+	}
+	atomic.AddInt64(&client.spooled, int64(len(batch)))
+}
+
+func (client *Client) alarmError(ctx context.Context, step string, err error) {
+	if client.alarm == nil {
+		return
+	}
+	client.alarm.Error(ctx, step, err)
+}
+
+func (client *Client) error(ctx context.Context, step string, err error) {
+	if client.logger == nil {
+		log2.Printf("【error】 step %s: %s \n", step, err.Error())
+		return
+	}
+	client.logger.Error(ctx, step, err)
+}
@@ -0,0 +1,303 @@
+package es_log
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/redact"
+)
+
+// memorySink 记录写入的日志，gate 不为空时写入会等待 gate，err 不为空时写入失败
+type memorySink struct {
+	mu      sync.Mutex
+	records []*MqData
+	batches []int
+	gate    chan struct{}
+	err     error
+	closed  bool
+}
+
+func (sink *memorySink) Write(_ context.Context, records []*MqData) error {
+	if sink.gate != nil {
+		<-sink.gate
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.err != nil {
+		return sink.err
+	}
+	sink.records = append(sink.records, records...)
+	sink.batches = append(sink.batches, len(records))
+	return nil
+}
+
+func (sink *memorySink) setErr(err error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.err = err
+}
+
+func (sink *memorySink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.closed = true
+	return nil
+}
+
+func (sink *memorySink) msgs() []string {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	msgs := make([]string, 0, len(sink.records))
+	for i := range sink.records {
+		msgs = append(msgs, sink.records[i].Data.Msg)
+	}
+	return msgs
+}
+
+func newTestConfig(bufferSize int, policy DropPolicy) *Config {
+	return &Config{ServerName: "iota", Environment: "test", BufferSize: bufferSize, DropPolicy: policy}
+}
+
+func TestClientSendFlushClose(t *testing.T) {
+	sink := new(memorySink)
+	client, err := NewClientWithSink(newTestConfig(10, DropNewest), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.NewLog().SetChannel("order").Info("a").Send(); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.NewLog().Error("b").Send(); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sink.msgs()
+	if len(msgs) != 2 || msgs[0] != "a" || msgs[1] != "b" {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	if sink.records[0].Project != "iotatest" || sink.records[0].Data.CreatedAt == 0 {
+		t.Fatalf("record: %+v", sink.records[0])
+	}
+	if sink.records[0].Data.ID == "" || sink.records[0].Data.ID == sink.records[1].Data.ID {
+		t.Fatalf("ids: %s %s", sink.records[0].Data.ID, sink.records[1].Data.ID)
+	}
+
+	if err = client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !sink.closed {
+		t.Fatal("sink not closed")
+	}
+	if err = client.NewLog().Info("c").Send(); err != ErrClientClosed {
+		t.Fatalf("send after close: %v", err)
+	}
+}
+
+func TestClientCloseTimeout(t *testing.T) {
+	sink := &memorySink{gate: make(chan struct{})}
+	config := newTestConfig(10, DropNewest)
+	config.BatchSize = 1
+	client, err := NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一条阻塞在sink中，其余两条留在缓冲区
+	_ = client.NewLog().Info("1").Send()
+	time.Sleep(50 * time.Millisecond)
+	_ = client.NewLog().Info("2").Send()
+	_ = client.NewLog().Info("3").Send()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = client.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("close: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("close blocked %s", elapsed)
+	}
+
+	// 阻塞的写入结束后，剩余日志计入丢弃并关闭输出端
+	close(sink.gate)
+	<-client.done
+	closed := false
+	for i := 0; i < 100 && !closed; i++ {
+		time.Sleep(10 * time.Millisecond)
+		sink.mu.Lock()
+		closed = sink.closed
+		sink.mu.Unlock()
+	}
+	if msgs := sink.msgs(); len(msgs) != 1 || msgs[0] != "1" {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	if client.Dropped() != 2 || !closed {
+		t.Fatalf("dropped %d closed %v", client.Dropped(), closed)
+	}
+}
+
+func TestClientDropPolicy(t *testing.T) {
+	for _, policy := range []DropPolicy{DropNewest, DropOldest} {
+		sink := &memorySink{gate: make(chan struct{})}
+		config := newTestConfig(2, policy)
+		config.BatchSize = 1
+		client, err := NewClientWithSink(config, sink)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 第一条被worker取出并阻塞在sink中，之后两条填满缓冲区
+		_ = client.NewLog().Info("1").Send()
+		time.Sleep(50 * time.Millisecond)
+		_ = client.NewLog().Info("2").Send()
+		_ = client.NewLog().Info("3").Send()
+		err = client.NewLog().Info("4").Send()
+		if policy == DropNewest && err != ErrBufferFull {
+			t.Fatalf("policy %d: %v", policy, err)
+		}
+		if policy == DropOldest && err != nil {
+			t.Fatalf("policy %d: %v", policy, err)
+		}
+		close(sink.gate)
+		if err = client.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"1", "2", "3"}
+		if policy == DropOldest {
+			want = []string{"1", "3", "4"}
+		}
+		msgs := sink.msgs()
+		if len(msgs) != len(want) {
+			t.Fatalf("policy %d msgs: %v", policy, msgs)
+		}
+		for i := range want {
+			if msgs[i] != want[i] {
+				t.Fatalf("policy %d msgs: %v", policy, msgs)
+			}
+		}
+		if client.Dropped() != 1 {
+			t.Fatalf("policy %d dropped: %d", policy, client.Dropped())
+		}
+	}
+}
+
+func TestClientWithoutMqAddress(t *testing.T) {
+	if _, err := NewClient(newTestConfig(0, DropNewest)); err != ErrMqAddressEmpty {
+		t.Fatalf("err: %v", err)
+	}
+	// 兼容旧用法：没有地址时返回错误而不是阻塞
+	err := NewLog().SetServerName("iota").SetEnvironment("test").Info("a").Send()
+	if err != ErrMqAddressEmpty {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestClientBatchAndSpool(t *testing.T) {
+	sink := new(memorySink)
+	sink.setErr(errors.New("mq down"))
+	config := newTestConfig(10, DropNewest)
+	config.BatchSize = 3
+	config.BatchWait = time.Hour
+	config.SpoolDir = t.TempDir()
+	client, err := NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(from, to int) {
+		for i := from; i <= to; i++ {
+			if err := client.NewLog().Info(strconv.Itoa(i)).Send(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// MQ 不可用时写入磁盘缓存
+	send(1, 5)
+	if err = client.Flush(context.Background()); err == nil {
+		t.Fatal("flush should fail while mq down")
+	}
+	if stats := client.Stats(); stats.Spooled != 5 || stats.SpoolPending != 5 || stats.Sent != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+
+	// 恢复后先按顺序重放磁盘缓存
+	sink.setErr(nil)
+	send(6, 6)
+	if err = client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sink.msgs()
+	for i := range msgs {
+		if msgs[i] != strconv.Itoa(i+1) {
+			t.Fatalf("msgs: %v", msgs)
+		}
+	}
+	if len(msgs) != 6 || len(sink.batches) != 3 || sink.batches[0] != 3 || sink.batches[1] != 2 {
+		t.Fatalf("msgs: %v batches: %v", msgs, sink.batches)
+	}
+	stats := client.Stats()
+	if stats.Queued != 6 || stats.Sent != 6 || stats.Spooled != 5 || stats.Dropped != 0 || stats.SpoolPending != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	_ = client.Close(context.Background())
+}
+
+func TestClientSpoolSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	down := new(memorySink)
+	down.setErr(errors.New("mq down"))
+	config := newTestConfig(10, DropNewest)
+	config.SpoolDir = dir
+	client, err := NewClientWithSink(config, down)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.NewLog().Info("a").Send()
+	_ = client.NewLog().Info("b").Send()
+	_ = client.Close(context.Background())
+
+	sink := new(memorySink)
+	config = newTestConfig(10, DropNewest)
+	config.SpoolDir = dir
+	client, err = NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := client.Stats().SpoolPending; pending != 2 {
+		t.Fatalf("pending: %d", pending)
+	}
+	if err = client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := sink.msgs(); len(msgs) != 2 || msgs[0] != "a" || msgs[1] != "b" {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	_ = client.Close(context.Background())
+}
+
+func TestClientRedact(t *testing.T) {
+	sink := new(memorySink)
+	config := newTestConfig(10, DropNewest)
+	config.Redactor = redact.NewDefault()
+	client, err := NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.NewLog().InfoByMap(map[string]interface{}{"phone": "13800138000"}).AddDefine("token", "abcdefghijk").Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Close(context.Background())
+	if len(sink.records) != 1 {
+		t.Fatalf("msgs: %v", sink.msgs())
+	}
+	record := sink.records[0].Data
+	if record.Msg != `{"phone":"138****8000"}` || record.Define["token"] != "abc****hijk" {
+		t.Fatalf("record: %+v", record)
+	}
+}
@@ -0,0 +1,437 @@
+package es_log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	log2 "log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/driver/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DefaultDeadLetterExchange   = "es-log-dead-letter-exchange"
+	DefaultDeadLetterQueue      = "es-log-dead-letter-queue"
+	DefaultDeadLetterRoutingKey = "es-log-dead-letter-routingkey"
+	DefaultConsumerBatchSize    = 500
+)
+
+// ConsumerConfig 消费端配置：从 es-log-queue 读取日志写入 Elasticsearch
+type ConsumerConfig struct {
+	MqAddress    string `yaml:"mq-address"`    // RabbitMQ 地址
+	ExchangeName string `yaml:"exchange-name"` // 默认 es-log-exchange
+	QueueName    string `yaml:"queue-name"`    // 默认 es-log-queue
+	RoutingKey   string `yaml:"routing-key"`   // 默认 es-log-routingkey
+	ChannelNum   int    `yaml:"channel-num"`   // 并发消费的 channel 数，默认 1
+	// BatchSize 每次 bulk 写入的日志数，默认 500；每个 channel 的 prefetch 也取该值，
+	// 未 ack 的消息可以攒到同一批中
+	BatchSize int `yaml:"batch-size"`
+	// BatchWait 攒批的最长等待时间，默认 1s
+	BatchWait time.Duration `yaml:"batch-wait"`
+	// DeadLetterQueue 无法解析的消息与被 ES 拒绝的日志投递到该队列，默认 es-log-dead-letter-queue
+	DeadLetterExchange   string `yaml:"dead-letter-exchange"`
+	DeadLetterQueue      string `yaml:"dead-letter-queue"`
+	DeadLetterRoutingKey string `yaml:"dead-letter-routing-key"`
+	// ES Elasticsearch 配置
+	ES *ESConfig `yaml:"es"`
+	// Indices 按项目指定索引前缀，未配置的项目使用项目名；索引名为 {前缀}-{日期}
+	Indices map[string]string `yaml:"indices"`
+}
+
+// Validate 校验并填充默认值
+func (config *ConsumerConfig) Validate() error {
+	if config.MqAddress == "" {
+		return ErrMqAddressEmpty
+	}
+	if config.ES == nil {
+		return ErrESAddressEmpty
+	}
+	if config.ExchangeName == "" {
+		config.ExchangeName = DefaultExchangeName
+	}
+	if config.QueueName == "" {
+		config.QueueName = DefaultQueueName
+	}
+	if config.RoutingKey == "" {
+		config.RoutingKey = DefaultRoutingKey
+	}
+	if config.ChannelNum <= 0 {
+		config.ChannelNum = 1
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultConsumerBatchSize
+	}
+	if config.BatchWait <= 0 {
+		config.BatchWait = DefaultBatchWait
+	}
+	if config.DeadLetterExchange == "" {
+		config.DeadLetterExchange = DefaultDeadLetterExchange
+	}
+	if config.DeadLetterQueue == "" {
+		config.DeadLetterQueue = DefaultDeadLetterQueue
+	}
+	if config.DeadLetterRoutingKey == "" {
+		config.DeadLetterRoutingKey = DefaultDeadLetterRoutingKey
+	}
+	if config.ES.IndexFunc == nil && len(config.Indices) > 0 {
+		config.ES.IndexFunc = config.index
+	}
+	return nil
+}
+
+// index 按项目路由索引
+func (config *ConsumerConfig) index(record *MqData) string {
+	layout := config.ES.IndexLayout
+	if layout == "" {
+		layout = DefaultESIndexLayout
+	}
+	prefix, has := config.Indices[record.Project]
+	if !has {
+		return ESIndex(record, layout)
+	}
+	return strings.ToLower(prefix) + "-" + time.Unix(0, record.Data.CreatedAt*1e6).Format(layout)
+}
+
+func (config *ConsumerConfig) mqConfig(exchange, queue, routingKey string) *rabbitmq.Config {
+	return &rabbitmq.Config{
+		Address:         config.MqAddress,
+		ExchangeName:    exchange,
+		ExchangeKind:    "direct",
+		ExchangeDurable: true,
+		QueueName:       queue,
+		QueueDurable:    true,
+		BindKey:         routingKey,
+		DeliveryMode:    2,
+		PrefetchCount:   1,
+		ChannelNum:      config.ChannelNum,
+	}
+}
+
+// consumeReq 一条消息中的日志，bulk 写入后通过 done 返回结果
+type consumeReq struct {
+	body    []byte
+	records []*MqData
+	done    func(rabbitmq.Action)
+	// blocking 调用方阻塞等待结果，占用一个 channel
+	blocking bool
+}
+
+// Consumer 消费 es_log 队列写入 Elasticsearch，ES 确认后才 ack
+type Consumer struct {
+	config     *ConsumerConfig
+	sink       Sink
+	mqLogger   rabbitmq.Logger
+	mu         sync.Mutex
+	mq         *rabbitmq.RabbitMQ // 死信队列生产者
+	deadLetter func(body []byte) error
+	logger     Logger
+	alarm      Alarm
+
+	requests chan *consumeReq
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewConsumer 通过配置创建消费端
+func NewConsumer(config *ConsumerConfig) (*Consumer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	sink, err := NewESSink(config.ES)
+	if err != nil {
+		return nil, err
+	}
+	return NewConsumerWithSink(config, sink)
+}
+
+// NewConsumerWithSink 通过配置与指定的输出端创建消费端
+func NewConsumerWithSink(config *ConsumerConfig, sink Sink) (*Consumer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	consumer := &Consumer{
+		config:   config,
+		sink:     sink,
+		requests: make(chan *consumeReq),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	consumer.deadLetter = consumer.publishDeadLetter
+	go consumer.run()
+	return consumer, nil
+}
+
+// SetLogger 设置内部日志
+func (consumer *Consumer) SetLogger(logger Logger) *Consumer {
+	consumer.logger = logger
+	return consumer
+}
+
+// SetAlarm 设置告警
+func (consumer *Consumer) SetAlarm(alarm Alarm) *Consumer {
+	consumer.alarm = alarm
+	return consumer
+}
+
+// SetMqLogger 设置 RabbitMQ 日志
+func (consumer *Consumer) SetMqLogger(mqLogger rabbitmq.Logger) *Consumer {
+	consumer.mqLogger = mqLogger
+	return consumer
+}
+
+// Start 开始消费，连接与重连由 rabbitmq.StartConsumer 负责；
+// 消息进入批次后立即返回，写入 ES 后再按 delivery tag ack，批次大小不受 ChannelNum 限制
+func (consumer *Consumer) Start(ctx context.Context) {
+	config := consumer.config
+	mqConfig := config.mqConfig(config.ExchangeName, config.QueueName, config.RoutingKey)
+	mqConfig.PrefetchCount = config.BatchSize
+	mq := new(rabbitmq.RabbitMQ).
+		WithContext(ctx).
+		SetConfig(mqConfig).
+		SetHandle(consumer.handleAsync)
+	if consumer.mqLogger != nil {
+		mq.WithLogger(consumer.mqLogger)
+	}
+	mq.StartConsumer()
+}
+
+// Handle 处理一条消息：解析失败投递到死信队列，否则等待所在批次写入 ES 后返回结果。
+// 每次调用占用一个 channel，所有 channel 都在等待时立即写入当前批次
+func (consumer *Consumer) Handle(ctx context.Context, delivery *amqp.Delivery) rabbitmq.Action {
+	action := make(chan rabbitmq.Action, 1)
+	consumer.submit(ctx, delivery.Body, true, func(result rabbitmq.Action) {
+		action <- result
+	})
+	return <-action
+}
+
+// handleAsync 消息进入批次后返回 rabbitmq.Manual，写入 ES 后再 ack
+func (consumer *Consumer) handleAsync(ctx context.Context, delivery *amqp.Delivery) rabbitmq.Action {
+	d := *delivery
+	consumer.submit(ctx, d.Body, false, func(action rabbitmq.Action) {
+		var err error
+		switch action {
+		case rabbitmq.Ack:
+			err = d.Ack(false)
+		case rabbitmq.NackDiscard:
+			err = d.Nack(false, false)
+		default:
+			err = d.Nack(false, true)
+		}
+		if err != nil {
+			consumer.error(ctx, "es_log.consumer.ack", err)
+		}
+	})
+	return rabbitmq.Manual
+}
+
+// submit 解析消息并加入批次，结果通过 done 返回
+func (consumer *Consumer) submit(ctx context.Context, body []byte, blocking bool, done func(rabbitmq.Action)) {
+	records, err := DecodeMqData(body)
+	if err != nil {
+		consumer.error(ctx, "es_log.consumer.decode", err)
+		done(consumer.toDeadLetter(ctx, body))
+		return
+	}
+	if len(records) == 0 {
+		done(rabbitmq.Ack)
+		return
+	}
+	req := &consumeReq{body: body, records: records, done: done, blocking: blocking}
+	select {
+	case consumer.requests <- req:
+	case <-consumer.stop:
+		done(rabbitmq.NackRequeue)
+	}
+}
+
+// Close 写入当前批次后停止
+func (consumer *Consumer) Close() error {
+	consumer.once.Do(func() {
+		close(consumer.stop)
+	})
+	<-consumer.done
+	consumer.mu.Lock()
+	if consumer.mq != nil {
+		consumer.mq.CloseProducer()
+		consumer.mq = nil
+	}
+	consumer.mu.Unlock()
+	return consumer.sink.Close()
+}
+
+// DecodeMqData 解析队列消息，兼容单条对象与批量的 JSON 数组
+func DecodeMqData(body []byte) ([]*MqData, error) {
+	body = bytes.TrimSpace(body)
+	records := make([]*MqData, 0)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &records); err != nil {
+			return nil, err
+		}
+	} else {
+		record := new(MqData)
+		if err := json.Unmarshal(body, record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	for _, record := range records {
+		if record == nil || record.Project == "" {
+			return nil, errors.New("es_log: record project is empty")
+		}
+	}
+	return records, nil
+}
+
+// run 攒批：日志数达到 BatchSize、阻塞调用 Handle 的 channel 都在等待或者超过 BatchWait 时写入
+func (consumer *Consumer) run() {
+	defer close(consumer.done)
+	timer := time.NewTimer(consumer.config.BatchWait)
+	timer.Stop()
+	batch := make([]*consumeReq, 0)
+	count, blocked := 0, 0
+	flush := func() {
+		if len(batch) > 0 {
+			consumer.flush(batch)
+		}
+		batch = make([]*consumeReq, 0)
+		count, blocked = 0, 0
+		timer.Stop()
+	}
+	for {
+		select {
+		case req := <-consumer.requests:
+			if len(batch) == 0 {
+				timer.Reset(consumer.config.BatchWait)
+			}
+			batch = append(batch, req)
+			count += len(req.records)
+			if req.blocking {
+				blocked++
+			}
+			if count >= consumer.config.BatchSize || blocked >= consumer.config.ChannelNum {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		case <-consumer.stop:
+			flush()
+			return
+		}
+	}
+}
+
+// flush 写入一批消息，根据结果决定每条消息 ack、重新入队或者将被拒绝的日志投递到死信队列
+func (consumer *Consumer) flush(batch []*consumeReq) {
+	ctx := context.Background()
+	records := make([]*MqData, 0)
+	owner := make(map[*MqData]*consumeReq)
+	for _, req := range batch {
+		for _, record := range req.records {
+			records = append(records, record)
+			owner[record] = req
+		}
+	}
+
+	err := consumer.sink.Write(ctx, records)
+	if err == nil {
+		for _, req := range batch {
+			req.done(rabbitmq.Ack)
+		}
+		return
+	}
+	consumer.error(ctx, "es_log.consumer.write", err)
+	bulkErr := new(BulkError)
+	if !errors.As(err, &bulkErr) {
+		consumer.alarmError(ctx, "es_log.consumer.write", err)
+		for _, req := range batch {
+			req.done(rabbitmq.NackRequeue)
+		}
+		return
+	}
+
+	// 有可重试失败的消息整条重新入队，已写入的日志 _id 固定，重放时不会重复；
+	// 其余消息中被拒绝的日志重新编码后进入死信队列，写入成功的日志不会进入死信队列
+	requeue := make(map[*consumeReq]bool, len(batch))
+	for _, record := range bulkErr.Records {
+		requeue[owner[record]] = true
+	}
+	rejected := make(map[*consumeReq][]*MqData, len(batch))
+	for _, record := range bulkErr.RejectedRecords {
+		rejected[owner[record]] = append(rejected[owner[record]], record)
+	}
+	for _, req := range batch {
+		switch {
+		case requeue[req]:
+			req.done(rabbitmq.NackRequeue)
+		case len(rejected[req]) > 0:
+			req.done(consumer.rejectedToDeadLetter(ctx, req, rejected[req]))
+		default:
+			req.done(rabbitmq.Ack)
+		}
+	}
+}
+
+// rejectedToDeadLetter 被拒绝的日志编码为一条消息投递到死信队列；全部被拒绝时投递原消息
+func (consumer *Consumer) rejectedToDeadLetter(ctx context.Context, req *consumeReq, records []*MqData) rabbitmq.Action {
+	if len(records) == len(req.records) {
+		return consumer.toDeadLetter(ctx, req.body)
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		consumer.error(ctx, "es_log.consumer.dead_letter", err)
+		return rabbitmq.NackDiscard
+	}
+	return consumer.toDeadLetter(ctx, body)
+}
+
+// toDeadLetter 投递到死信队列后 ack；投递失败时 NackDiscard，交给服务端配置的死信交换机
+func (consumer *Consumer) toDeadLetter(ctx context.Context, body []byte) rabbitmq.Action {
+	if err := consumer.deadLetter(body); err != nil {
+		consumer.alarmError(ctx, "es_log.consumer.dead_letter", err)
+		consumer.error(ctx, "es_log.consumer.dead_letter", err)
+		return rabbitmq.NackDiscard
+	}
+	return rabbitmq.Ack
+}
+
+// publishDeadLetter 投递到死信队列，首次使用时连接
+func (consumer *Consumer) publishDeadLetter(body []byte) error {
+	consumer.mu.Lock()
+	defer consumer.mu.Unlock()
+	if consumer.mq == nil {
+		config := consumer.config
+		mqConfig := config.mqConfig(config.DeadLetterExchange, config.DeadLetterQueue, config.DeadLetterRoutingKey)
+		mq := new(rabbitmq.RabbitMQ).SetConfig(mqConfig)
+		if consumer.mqLogger != nil {
+			mq.WithLogger(consumer.mqLogger)
+		}
+		if err := mq.StartSyncProducer(); err != nil {
+			mq.CloseProducer()
+			return err
+		}
+		consumer.mq = mq
+	}
+	return consumer.mq.Publish(body)
+}
+
+func (consumer *Consumer) alarmError(ctx context.Context, step string, err error) {
+	if consumer.alarm == nil {
+		return
+	}
+	consumer.alarm.Error(ctx, step, err)
+}
+
+func (consumer *Consumer) error(ctx context.Context, step string, err error) {
+	if consumer.logger == nil {
+		log2.Printf("【error】 step %s: %s \n", step, err.Error())
+		return
+	}
+	consumer.logger.Error(ctx, step, err)
+}
@@ -0,0 +1,202 @@
+package es_log
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/driver/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// bulkSink 模拟 ES：msg 为 bad 的日志被拒绝，为 retry 的日志写入失败
+type bulkSink struct {
+	memorySink
+}
+
+func (sink *bulkSink) Write(ctx context.Context, records []*MqData) error {
+	bulkErr := new(BulkError)
+	ok := make([]*MqData, 0)
+	for _, record := range records {
+		switch record.Data.Msg {
+		case "bad":
+			bulkErr.RejectedRecords = append(bulkErr.RejectedRecords, record)
+		case "retry":
+			bulkErr.Records = append(bulkErr.Records, record)
+		default:
+			ok = append(ok, record)
+		}
+	}
+	_ = sink.memorySink.Write(ctx, ok)
+	if len(bulkErr.Records) > 0 || len(bulkErr.RejectedRecords) > 0 {
+		bulkErr.Rejected = len(bulkErr.RejectedRecords)
+		return bulkErr
+	}
+	return nil
+}
+
+func newTestConsumer(t *testing.T, sink Sink, channelNum int) (*Consumer, *[][]byte) {
+	config := &ConsumerConfig{MqAddress: "amqp://127.0.0.1:5672", ES: &ESConfig{}, ChannelNum: channelNum, BatchWait: time.Hour}
+	consumer, err := NewConsumerWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	deadLetters := make([][]byte, 0)
+	consumer.deadLetter = func(body []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		deadLetters = append(deadLetters, body)
+		return nil
+	}
+	return consumer, &deadLetters
+}
+
+func TestConsumerBatchAck(t *testing.T) {
+	sink := new(bulkSink)
+	consumer, deadLetters := newTestConsumer(t, sink, 3)
+	single, _ := json.Marshal(newTestRecord("a"))
+	batch, _ := json.Marshal([]*MqData{newTestRecord("b"), newTestRecord("bad")})
+	retry, _ := json.Marshal(newTestRecord("retry"))
+	bodies := [][]byte{single, batch, retry}
+
+	// 三条消息在同一批写入，写入完成前不会返回
+	actions := make([]rabbitmq.Action, len(bodies))
+	var wg sync.WaitGroup
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actions[i] = consumer.Handle(context.Background(), &amqp.Delivery{Body: bodies[i]})
+		}(i)
+	}
+	wg.Wait()
+
+	if actions[0] != rabbitmq.Ack || actions[1] != rabbitmq.Ack || actions[2] != rabbitmq.NackRequeue {
+		t.Fatalf("actions: %v", actions)
+	}
+	if len(sink.batches) != 1 || len(sink.msgs()) != 2 {
+		t.Fatalf("batches %v msgs %v", sink.batches, sink.msgs())
+	}
+	rejected, _ := json.Marshal([]*MqData{newTestRecord("bad")})
+	if len(*deadLetters) != 1 || string((*deadLetters)[0]) != string(rejected) {
+		t.Fatalf("dead letters: %s", *deadLetters)
+	}
+
+	// 无法解析的消息直接进入死信队列
+	if action := consumer.Handle(context.Background(), &amqp.Delivery{Body: []byte("{oops")}); action != rabbitmq.Ack {
+		t.Fatalf("action: %v", action)
+	}
+	if len(*deadLetters) != 2 {
+		t.Fatalf("dead letters: %s", *deadLetters)
+	}
+	_ = consumer.Close()
+}
+
+func TestConsumerMixedBatch(t *testing.T) {
+	sink := new(bulkSink)
+	consumer, deadLetters := newTestConsumer(t, sink, 1)
+	defer consumer.Close()
+
+	// 同时有可重试与被拒绝的日志：整条重新入队，被拒绝的日志等重放写入后再进入死信队列
+	mixed, _ := json.Marshal([]*MqData{newTestRecord("a"), newTestRecord("bad"), newTestRecord("retry")})
+	if action := consumer.Handle(context.Background(), &amqp.Delivery{Body: mixed}); action != rabbitmq.NackRequeue {
+		t.Fatalf("action: %v", action)
+	}
+	if len(*deadLetters) != 0 {
+		t.Fatalf("dead letters: %s", *deadLetters)
+	}
+
+	// 部分被拒绝：只有被拒绝的日志进入死信队列，写入成功的日志不会重放
+	partial, _ := json.Marshal([]*MqData{newTestRecord("a"), newTestRecord("bad"), newTestRecord("c")})
+	if action := consumer.Handle(context.Background(), &amqp.Delivery{Body: partial}); action != rabbitmq.Ack {
+		t.Fatalf("action: %v", action)
+	}
+	if len(*deadLetters) != 1 {
+		t.Fatalf("dead letters: %s", *deadLetters)
+	}
+	records, err := DecodeMqData((*deadLetters)[0])
+	if err != nil || len(records) != 1 || records[0].Data.Msg != "bad" {
+		t.Fatalf("dead letter: %s", (*deadLetters)[0])
+	}
+
+	// 全部被拒绝：原消息进入死信队列
+	bad, _ := json.Marshal(newTestRecord("bad"))
+	if action := consumer.Handle(context.Background(), &amqp.Delivery{Body: bad}); action != rabbitmq.Ack {
+		t.Fatalf("action: %v", action)
+	}
+	if len(*deadLetters) != 2 || string((*deadLetters)[1]) != string(bad) {
+		t.Fatalf("dead letters: %s", *deadLetters)
+	}
+}
+
+// recordAcks 记录 ack 结果
+type recordAcks struct {
+	mu      sync.Mutex
+	actions map[uint64]string
+	done    chan struct{}
+}
+
+func (acks *recordAcks) set(tag uint64, action string) error {
+	acks.mu.Lock()
+	defer acks.mu.Unlock()
+	acks.actions[tag] = action
+	acks.done <- struct{}{}
+	return nil
+}
+
+func (acks *recordAcks) Ack(tag uint64, _ bool) error { return acks.set(tag, "ack") }
+func (acks *recordAcks) Nack(tag uint64, _ bool, requeue bool) error {
+	if requeue {
+		return acks.set(tag, "requeue")
+	}
+	return acks.set(tag, "discard")
+}
+func (acks *recordAcks) Reject(tag uint64, _ bool) error { return acks.set(tag, "reject") }
+
+func TestConsumerAsyncBatch(t *testing.T) {
+	sink := new(bulkSink)
+	consumer, _ := newTestConsumer(t, sink, 1)
+	consumer.config.BatchSize = 3
+	defer consumer.Close()
+
+	// 只有一个 channel 时消息也会攒到同一批，写入后才 ack
+	acks := &recordAcks{actions: make(map[uint64]string), done: make(chan struct{}, 3)}
+	for i, msg := range []string{"a", "b", "retry"} {
+		body, _ := json.Marshal(newTestRecord(msg))
+		delivery := &amqp.Delivery{Acknowledger: acks, DeliveryTag: uint64(i + 1), Body: body}
+		if action := consumer.handleAsync(context.Background(), delivery); action != rabbitmq.Manual {
+			t.Fatalf("action: %v", action)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-acks.done
+	}
+	if len(sink.batches) != 1 || sink.batches[0] != 2 {
+		t.Fatalf("batches: %v", sink.batches)
+	}
+	if acks.actions[1] != "ack" || acks.actions[2] != "ack" || acks.actions[3] != "requeue" {
+		t.Fatalf("acks: %v", acks.actions)
+	}
+}
+
+func TestConsumerIndexRouting(t *testing.T) {
+	config := &ConsumerConfig{
+		MqAddress: "amqp://127.0.0.1:5672",
+		ES:        &ESConfig{Address: "http://127.0.0.1:9200"},
+		Indices:   map[string]string{"IotaTest": "Iota-Logs"},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	record := newTestRecord("a")
+	if index := config.ES.IndexFunc(record); index != "iota-logs-2022.03.04" {
+		t.Fatalf("index: %s", index)
+	}
+	record.Project = "Other"
+	if index := config.ES.IndexFunc(record); index != "other-2022.03.04" {
+		t.Fatalf("index: %s", index)
+	}
+}
@@ -0,0 +1,270 @@
+package es_log
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultESIndexLayout  = "2006.01.02"
+	DefaultESMaxRetries   = 3
+	DefaultESRetryBackoff = 500 * time.Millisecond
+	DefaultESMaxInflight  = 2
+	DefaultESTimeout      = 10 * time.Second
+)
+
+// ErrESAddressEmpty 没有配置 Elasticsearch 地址
+var ErrESAddressEmpty = errors.New("es_log: es address is empty")
+
+// ESConfig Elasticsearch 输出端配置
+type ESConfig struct {
+	Address      string        `yaml:"address"`       // 如 http://127.0.0.1:9200
+	Username     string        `yaml:"username"`      // basic auth 用户名
+	Password     string        `yaml:"password"`      // basic auth 密码
+	IndexLayout  string        `yaml:"index-layout"`  // 索引日期格式，默认 2006.01.02
+	MaxRetries   int           `yaml:"max-retries"`   // 部分失败、429、5xx 的重试次数，默认 3
+	RetryBackoff time.Duration `yaml:"retry-backoff"` // 首次重试间隔，之后翻倍，默认 500ms
+	MaxInflight  int           `yaml:"max-inflight"`  // 同时进行的 bulk 请求数，默认 2
+	Timeout      time.Duration `yaml:"timeout"`       // 单次请求超时，默认 10s
+	// IndexFunc 自定义索引名，默认 {project}-{yyyy.MM.dd}
+	IndexFunc func(record *MqData) string `yaml:"-"`
+}
+
+// Validate 校验并填充默认值
+func (config *ESConfig) Validate() error {
+	if config.Address == "" {
+		return ErrESAddressEmpty
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+	if config.IndexLayout == "" {
+		config.IndexLayout = DefaultESIndexLayout
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	} else if config.MaxRetries == 0 {
+		config.MaxRetries = DefaultESMaxRetries
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultESRetryBackoff
+	}
+	if config.MaxInflight <= 0 {
+		config.MaxInflight = DefaultESMaxInflight
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultESTimeout
+	}
+	return nil
+}
+
+// BulkError bulk 写入部分失败；Records 为重试后仍失败、可以稍后重放的日志，
+// RejectedRecords 为 ES 拒绝（如 mapping 错误）不会再重试的日志，Rejected 为其数量
+type BulkError struct {
+	Records         []*MqData
+	RejectedRecords []*MqData
+	Rejected        int
+	Reason          string
+}
+
+func (err *BulkError) Error() string {
+	return fmt.Sprintf("es_log: bulk failed %d, rejected %d: %s", len(err.Records), err.Rejected, err.Reason)
+}
+
+// esDocument 写入 ES 的文档
+type esDocument struct {
+	Project   string `json:"project"`
+	Timestamp string `json:"@timestamp"`
+	MqDataBody
+}
+
+// esBulkResponse _bulk 接口返回
+type esBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+var _ Sink = new(esSink)
+
+// esSink 通过 _bulk 接口直接写入 Elasticsearch
+type esSink struct {
+	config   *ESConfig
+	client   *http.Client
+	inflight chan struct{} // 限制并发请求数，写满时 Write 阻塞，由 Client 缓冲区承接背压
+}
+
+// NewESSink 初始化 Elasticsearch 输出端
+func NewESSink(config *ESConfig) (Sink, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &esSink{
+		config:   config,
+		client:   &http.Client{Timeout: config.Timeout},
+		inflight: make(chan struct{}, config.MaxInflight),
+	}, nil
+}
+
+// Index 日志写入的索引名
+func (sink *esSink) Index(record *MqData) string {
+	if sink.config.IndexFunc != nil {
+		return sink.config.IndexFunc(record)
+	}
+	return ESIndex(record, sink.config.IndexLayout)
+}
+
+// ESIndex 默认索引名 {project}-{日期}，ES 索引名只能是小写
+func ESIndex(record *MqData, layout string) string {
+	createdAt := time.Unix(0, record.Data.CreatedAt*1e6)
+	return strings.ToLower(record.Project) + "-" + createdAt.Format(layout)
+}
+
+// Write 写入一批日志，429、5xx 与部分失败按退避时间重试
+func (sink *esSink) Write(ctx context.Context, records []*MqData) error {
+	if len(records) == 0 {
+		return nil
+	}
+	select {
+	case sink.inflight <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		<-sink.inflight
+	}()
+
+	pending := records
+	rejected := make([]*MqData, 0)
+	backoff := sink.config.RetryBackoff
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		failed, refused, err := sink.bulk(ctx, pending)
+		rejected = append(rejected, refused...)
+		if err == nil && len(failed) == 0 {
+			break
+		}
+		if err != nil {
+			lastErr = err
+		} else {
+			pending = failed
+			lastErr = fmt.Errorf("%d items failed", len(failed))
+		}
+		if attempt >= sink.config.MaxRetries {
+			return &BulkError{Records: pending, RejectedRecords: rejected, Rejected: len(rejected), Reason: lastErr.Error()}
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return &BulkError{Records: pending, RejectedRecords: rejected, Rejected: len(rejected), Reason: ctx.Err().Error()}
+		}
+		backoff *= 2
+	}
+	if len(rejected) > 0 {
+		return &BulkError{RejectedRecords: rejected, Rejected: len(rejected), Reason: "rejected by es"}
+	}
+	return nil
+}
+
+// esDocumentID 按索引与日志 ID 生成固定的 _id，重试与消息重放时覆盖已写入的文档而不是重复写入；
+// 没有 ID 的日志（旧版本的生产端）返回空，由 ES 生成 _id
+func esDocumentID(index string, record *MqData) string {
+	if record.Data.ID == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(index + "\x00" + record.Data.ID))
+	return hex.EncodeToString(sum[:])
+}
+
+// bulk 发送一次 _bulk 请求，返回可以重试的失败日志与被拒绝的日志；
+// 请求整体失败时返回 error
+func (sink *esSink) bulk(ctx context.Context, records []*MqData) ([]*MqData, []*MqData, error) {
+	body := new(bytes.Buffer)
+	encoder := json.NewEncoder(body)
+	for _, record := range records {
+		doc, err := json.Marshal(&esDocument{
+			Project:    record.Project,
+			Timestamp:  time.Unix(0, record.Data.CreatedAt*1e6).Format("2006-01-02T15:04:05.000Z07:00"),
+			MqDataBody: record.Data,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		index := sink.Index(record)
+		meta := map[string]string{"_index": index}
+		if id := esDocumentID(index, record); id != "" {
+			meta["_id"] = id
+		}
+		action := map[string]map[string]string{"index": meta}
+		if err = encoder.Encode(action); err != nil {
+			return nil, nil, err
+		}
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.config.Address+"/_bulk", body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if sink.config.Username != "" {
+		req.SetBasicAuth(sink.config.Username, sink.config.Password)
+	}
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, nil, fmt.Errorf("es bulk status %d: %s", resp.StatusCode, data)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		// 整个请求被拒绝，重试也不会成功
+		return nil, records, nil
+	}
+
+	result := new(esBulkResponse)
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, nil, err
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	failed := make([]*MqData, 0)
+	rejected := make([]*MqData, 0)
+	for i, item := range result.Items {
+		if i >= len(records) {
+			break
+		}
+		for _, status := range item {
+			switch {
+			case status.Status == http.StatusTooManyRequests || status.Status >= http.StatusInternalServerError:
+				failed = append(failed, records[i])
+			case status.Status >= http.StatusBadRequest:
+				rejected = append(rejected, records[i])
+			}
+		}
+	}
+	return failed, rejected, nil
+}
+
+func (sink *esSink) Close() error {
+	sink.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,143 @@
+package es_log
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeES 模拟 _bulk 接口，statusFunc 返回每条文档的状态码
+type fakeES struct {
+	mu         sync.Mutex
+	requests   int
+	indices    []string
+	ids        []string
+	docs       []map[string]interface{}
+	statusFunc func(request int, msg string) int
+}
+
+func (es *fakeES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.requests++
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	items := make([]string, 0)
+	hasErr := false
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		action := make(map[string]map[string]string)
+		_ = json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		doc := make(map[string]interface{})
+		_ = json.Unmarshal(scanner.Bytes(), &doc)
+		status := es.statusFunc(es.requests, doc["msg"].(string))
+		if status == http.StatusServiceUnavailable {
+			w.WriteHeader(status)
+			return
+		}
+		if status >= http.StatusBadRequest {
+			hasErr = true
+		} else {
+			es.indices = append(es.indices, action["index"]["_index"])
+			es.ids = append(es.ids, action["index"]["_id"])
+			es.docs = append(es.docs, doc)
+		}
+		items = append(items, fmt.Sprintf(`{"index":{"status":%d}}`, status))
+	}
+	_, _ = fmt.Fprintf(w, `{"errors":%v,"items":[%s]}`, hasErr, strings.Join(items, ","))
+}
+
+func newTestRecord(msg string) *MqData {
+	createdAt := time.Date(2022, 3, 4, 10, 0, 0, 0, time.Local).UnixNano() / 1e6
+	return &MqData{Project: "IotaTest", Data: MqDataBody{Msg: msg, Level: LevelInfo, CreatedAt: createdAt}}
+}
+
+func TestESSinkRetryPartialFailure(t *testing.T) {
+	es := &fakeES{statusFunc: func(request int, msg string) int {
+		if request == 1 && msg == "b" {
+			return http.StatusTooManyRequests
+		}
+		return http.StatusCreated
+	}}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	sink, err := NewESSink(&ESConfig{Address: server.URL, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sink.Write(context.Background(), []*MqData{newTestRecord("a"), newTestRecord("b")}); err != nil {
+		t.Fatal(err)
+	}
+	if es.requests != 2 || len(es.docs) != 2 || es.docs[1]["msg"] != "b" {
+		t.Fatalf("requests %d docs %v", es.requests, es.docs)
+	}
+	if es.indices[0] != "iotatest-2022.03.04" || es.docs[0]["project"] != "IotaTest" || es.docs[0]["@timestamp"] == "" {
+		t.Fatalf("indices %v docs %v", es.indices, es.docs)
+	}
+
+	// _id 由索引与日志 ID 决定：内容相同的不同日志分别写入，重放同一条日志时覆盖
+	if len(es.ids) != 2 || es.ids[0] != "" {
+		t.Fatalf("ids without record id: %v", es.ids)
+	}
+	first, second := newTestRecord("same"), newTestRecord("same")
+	first.Data.ID, second.Data.ID = "id-1", "id-2"
+	if err = sink.Write(context.Background(), []*MqData{first, second, first}); err != nil {
+		t.Fatal(err)
+	}
+	ids := es.ids[2:]
+	if len(ids) != 3 || ids[0] == "" || ids[0] == ids[1] || ids[2] != ids[0] {
+		t.Fatalf("ids: %v", ids)
+	}
+}
+
+func TestESSinkFailure(t *testing.T) {
+	es := &fakeES{statusFunc: func(request int, msg string) int {
+		switch msg {
+		case "bad":
+			return http.StatusBadRequest
+		case "down":
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusCreated
+	}}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	sink, err := NewESSink(&ESConfig{Address: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 整体不可用：重试后返回全部日志
+	err = sink.Write(context.Background(), []*MqData{newTestRecord("down")})
+	bulkErr := new(BulkError)
+	if !errors.As(err, &bulkErr) || len(bulkErr.Records) != 1 || es.requests != 3 {
+		t.Fatalf("err %v requests %d", err, es.requests)
+	}
+
+	// 被拒绝的日志不重试，通过 Client 计入丢弃
+	client, err := NewClientWithSink(newTestConfig(10, DropNewest), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = client.Send(newTestRecord("ok"))
+	_ = client.Send(newTestRecord("bad"))
+	if err = client.Flush(context.Background()); !errors.As(err, &bulkErr) || bulkErr.Rejected != 1 {
+		t.Fatalf("err: %v", err)
+	}
+	if stats := client.Stats(); stats.Sent != 1 || stats.Dropped != 1 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	_ = client.Close(context.Background())
+}
@@ -0,0 +1,245 @@
+package es_log
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/actorbuf/iota/trace"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// ChannelField logrus 字段中指定 channel 的 key，未设置时使用 hook 的默认 channel
+	ChannelField = "channel"
+	// TraceIDKey 写入 Define 的链路ID
+	TraceIDKey = "trace_id"
+	// SpanIDKey 写入 Define 的SpanID
+	SpanIDKey = "span_id"
+
+	zapContextKey = "es_log.ctx"
+)
+
+// LevelFromLogrus logrus 日志级别对应的 es_log 级别
+func LevelFromLogrus(level logrus.Level) string {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel:
+		return LevelError
+	case logrus.WarnLevel:
+		return LevelWarning
+	case logrus.InfoLevel:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// LevelFromZap zap 日志级别对应的 es_log 级别
+func LevelFromZap(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return LevelError
+	case level == zapcore.WarnLevel:
+		return LevelWarning
+	case level == zapcore.InfoLevel:
+		return LevelInfo
+	default:
+		return LevelDebug
+	}
+}
+
+// addTrace 把 ctx 中的链路信息写入 Define
+func addTrace(ctx context.Context, define map[string]interface{}) {
+	if ctx == nil {
+		return
+	}
+	if traceID := trace.ObtainTraceID(ctx); traceID != "" {
+		define[TraceIDKey] = traceID
+	}
+	if spanID := trace.ObtainSpanID(ctx); spanID != "" {
+		define[SpanIDKey] = spanID
+	}
+}
+
+// sendEntry 投递转换后的日志；缓冲区满已经计入 Dropped，不再作为错误返回给日志库
+func sendEntry(client *Client, data *MqData) error {
+	if err := client.Send(data); err != nil && err != ErrBufferFull {
+		return err
+	}
+	return nil
+}
+
+var _ logrus.Hook = new(LogrusHook)
+
+// LogrusHook 把 logrus 日志转发到 es_log
+type LogrusHook struct {
+	client   *Client
+	minLevel logrus.Level
+	channel  string
+	levels   *LevelController
+}
+
+// NewLogrusHook 创建 logrus hook，默认转发 Info 及以上级别
+func NewLogrusHook(client *Client) *LogrusHook {
+	return &LogrusHook{client: client, minLevel: logrus.InfoLevel}
+}
+
+// SetMinLevel 设置转发的最低级别
+func (hook *LogrusHook) SetMinLevel(level logrus.Level) *LogrusHook {
+	hook.minLevel = level
+	return hook
+}
+
+// SetChannel 设置默认 channel，字段中带 ChannelField 时以字段为准
+func (hook *LogrusHook) SetChannel(channel string) *LogrusHook {
+	hook.channel = channel
+	return hook
+}
+
+// SetLevelController 运行时按 channel 控制最低级别，channel 有配置时优先于 SetMinLevel；
+// 需要在 AddHook 之前设置
+func (hook *LogrusHook) SetLevelController(levels *LevelController) *LogrusHook {
+	hook.levels = levels
+	return hook
+}
+
+// Levels 实现 logrus.Hook
+func (hook *LogrusHook) Levels() []logrus.Level {
+	if hook.levels != nil {
+		// 级别在运行时变化，由 Fire 过滤
+		return logrus.AllLevels
+	}
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, level := range logrus.AllLevels {
+		if level <= hook.minLevel {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// Fire 实现 logrus.Hook，ctx 通过 logrus.WithContext 传入
+func (hook *LogrusHook) Fire(entry *logrus.Entry) error {
+	data := &MqData{Data: MqDataBody{
+		Msg:       entry.Message,
+		Level:     LevelFromLogrus(entry.Level),
+		Channel:   hook.channel,
+		CreatedAt: entry.Time.UnixNano() / 1e6,
+		Define:    make(map[string]interface{}, len(entry.Data)+2),
+	}}
+	for key, value := range entry.Data {
+		if key == ChannelField {
+			data.Data.Channel = fmt.Sprint(value)
+			continue
+		}
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		data.Data.Define[key] = value
+	}
+	if hook.levels != nil {
+		if minLevel, has := hook.levels.Level(data.Data.Channel); has {
+			if !LevelEnabled(data.Data.Level, minLevel) {
+				return nil
+			}
+		} else if entry.Level > hook.minLevel {
+			return nil
+		}
+	}
+	addTrace(entry.Context, data.Data.Define)
+	return sendEntry(hook.client, data)
+}
+
+// ZapContext 携带 ctx 的 zap 字段，供 ZapCore 获取链路信息；其他 encoder 会忽略该字段
+func ZapContext(ctx context.Context) zap.Field {
+	return zap.Field{Key: zapContextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+var _ zapcore.Core = new(zapCore)
+
+// zapCore 把 zap 日志转发到 es_log，channel 使用 logger 名称
+type zapCore struct {
+	enab   zapcore.LevelEnabler
+	levels *LevelController
+	client *Client
+	fields []zapcore.Field
+}
+
+// NewZapCore 创建 zapcore.Core，enab 为转发的最低级别；通常与原有 core 通过 zapcore.NewTee 组合使用
+func NewZapCore(client *Client, enab zapcore.LevelEnabler) zapcore.Core {
+	return &zapCore{enab: enab, client: client}
+}
+
+// NewZapCoreWithLevels 创建 zapcore.Core，channel（logger 名称）在 levels 中有配置时优先于 enab
+func NewZapCoreWithLevels(client *Client, enab zapcore.LevelEnabler, levels *LevelController) zapcore.Core {
+	return &zapCore{enab: enab, levels: levels, client: client}
+}
+
+// Enabled 预先过滤：enab 或者 levels 中任意配置允许即可，Write 时再按 channel 判断
+func (core *zapCore) Enabled(level zapcore.Level) bool {
+	if core.enab.Enabled(level) {
+		return true
+	}
+	if core.levels == nil {
+		return false
+	}
+	minLevel, has := core.levels.MinLevel()
+	return has && LevelEnabled(LevelFromZap(level), minLevel)
+}
+
+// enabled 按 channel 判断是否转发
+func (core *zapCore) enabled(entry zapcore.Entry) bool {
+	if core.levels != nil {
+		if minLevel, has := core.levels.Level(entry.LoggerName); has {
+			return LevelEnabled(LevelFromZap(entry.Level), minLevel)
+		}
+	}
+	return core.enab.Enabled(entry.Level)
+}
+
+func (core *zapCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := &zapCore{enab: core.enab, levels: core.levels, client: core.client}
+	clone.fields = make([]zapcore.Field, 0, len(core.fields)+len(fields))
+	clone.fields = append(clone.fields, core.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return clone
+}
+
+func (core *zapCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if core.Enabled(entry.Level) {
+		return checked.AddCore(entry, core)
+	}
+	return checked
+}
+
+func (core *zapCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !core.enabled(entry) {
+		return nil
+	}
+	encoder := zapcore.NewMapObjectEncoder()
+	var ctx context.Context
+	for _, list := range [][]zapcore.Field{core.fields, fields} {
+		for i := range list {
+			if list[i].Key == zapContextKey {
+				ctx, _ = list[i].Interface.(context.Context)
+				continue
+			}
+			list[i].AddTo(encoder)
+		}
+	}
+	addTrace(ctx, encoder.Fields)
+	data := &MqData{Data: MqDataBody{
+		Msg:       entry.Message,
+		Level:     LevelFromZap(entry.Level),
+		Channel:   entry.LoggerName,
+		CreatedAt: entry.Time.UnixNano() / 1e6,
+		Define:    encoder.Fields,
+	}}
+	return sendEntry(core.client, data)
+}
+
+// Sync 等待已写入的日志投递完成
+func (core *zapCore) Sync() error {
+	return core.client.Flush(context.Background())
+}
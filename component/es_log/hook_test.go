@@ -0,0 +1,66 @@
+package es_log
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogrusHook(t *testing.T) {
+	sink := new(memorySink)
+	client, err := NewClientWithSink(newTestConfig(10, DropNewest), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.AddHook(NewLogrusHook(client).SetMinLevel(logrus.WarnLevel).SetChannel("core"))
+	logger.WithContext(context.Background()).Info("skip")
+	logger.WithField("uid", 1).Warn("a")
+	logger.WithFields(logrus.Fields{ChannelField: "order", "err": errors.New("boom")}).Error("b")
+	if err = client.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.records) != 2 {
+		t.Fatalf("msgs: %v", sink.msgs())
+	}
+	a, b := sink.records[0].Data, sink.records[1].Data
+	if a.Msg != "a" || a.Level != LevelWarning || a.Channel != "core" || a.Define["uid"] != 1 {
+		t.Fatalf("record: %+v", a)
+	}
+	if b.Level != LevelError || b.Channel != "order" || b.Define["err"] != "boom" {
+		t.Fatalf("record: %+v", b)
+	}
+	_ = client.Close(context.Background())
+}
+
+func TestZapCore(t *testing.T) {
+	sink := new(memorySink)
+	client, err := NewClientWithSink(newTestConfig(10, DropNewest), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.New(NewZapCore(client, zapcore.InfoLevel)).Named("rabbitmq").With(zap.String("queue", "q"))
+	logger.Debug("skip")
+	logger.Warn("a", zap.Int("retry", 3), ZapContext(context.Background()))
+	if err = logger.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.records) != 1 {
+		t.Fatalf("msgs: %v", sink.msgs())
+	}
+	record := sink.records[0].Data
+	if record.Msg != "a" || record.Level != LevelWarning || record.Channel != "rabbitmq" ||
+		record.Define["queue"] != "q" || record.Define["retry"] != int64(3) {
+		t.Fatalf("record: %+v", record)
+	}
+	if _, has := record.Define[zapContextKey]; has {
+		t.Fatalf("ctx field leaked: %+v", record.Define)
+	}
+	_ = client.Close(context.Background())
+}
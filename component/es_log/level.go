@@ -0,0 +1,184 @@
+package es_log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	log2 "log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/driver/etcd"
+)
+
+// levelRank 级别从低到高
+var levelRank = map[string]int{
+	LevelDebug:   0,
+	LevelInfo:    1,
+	LevelNotice:  2,
+	LevelWarning: 3,
+	LevelError:   4,
+}
+
+// LevelEnabled level 是否不低于 minLevel，未知级别视为启用
+func LevelEnabled(level, minLevel string) bool {
+	rank, has := levelRank[strings.ToUpper(level)]
+	minRank, minHas := levelRank[strings.ToUpper(minLevel)]
+	if !has || !minHas {
+		return true
+	}
+	return rank >= minRank
+}
+
+// LevelConfig 日志级别配置，etcd 中以 JSON 保存，如：
+// {"default":"INFO","channels":{"order":"DEBUG"},"expire_at":"2022-03-04T12:00:00+08:00"}
+type LevelConfig struct {
+	Default  string            `json:"default"`   // 默认最低级别，为空时不限制
+	Channels map[string]string `json:"channels"`  // 各 channel 的最低级别，优先于 Default
+	ExpireAt time.Time         `json:"expire_at"` // 过期后恢复为基础配置，零值不过期
+}
+
+// LevelController 运行时日志级别，通过 etcd 修改后无需重启即可生效
+type LevelController struct {
+	logger Logger
+
+	mu      sync.RWMutex
+	base    *LevelConfig // 没有 etcd 配置或者配置过期时使用
+	current *LevelConfig
+	timer   *time.Timer
+}
+
+var _ etcd.Listener = new(LevelController)
+
+// NewLevelController 创建级别控制器，base 为基础配置，可以为空
+func NewLevelController(base *LevelConfig) *LevelController {
+	if base == nil {
+		base = new(LevelConfig)
+	}
+	return &LevelController{base: base, current: base}
+}
+
+// SetLogger 设置内部日志
+func (c *LevelController) SetLogger(logger Logger) *LevelController {
+	c.logger = logger
+	return c
+}
+
+// Watch 通过 etcd.Watcher 监听 key 上的级别配置，先加载已有的配置，key 删除后恢复为基础配置
+func (c *LevelController) Watch(watcher *etcd.Watcher, key string) bool {
+	return watcher.AddWatchWithLoad(key, false, c)
+}
+
+// Apply 应用新的级别配置，设置了 ExpireAt 时到期自动恢复
+func (c *LevelController) Apply(config *LevelConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if config == nil || (!config.ExpireAt.IsZero() && !config.ExpireAt.After(time.Now())) {
+		c.current = c.base
+		return
+	}
+	c.current = config
+	if !config.ExpireAt.IsZero() {
+		c.timer = time.AfterFunc(time.Until(config.ExpireAt), func() {
+			c.revert(config)
+		})
+	}
+}
+
+// Reset 恢复为基础配置
+func (c *LevelController) Reset() {
+	c.Apply(nil)
+}
+
+// revert 到期恢复，配置已经被替换时不处理
+func (c *LevelController) revert(config *LevelConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == config {
+		c.current = c.base
+		c.timer = nil
+	}
+}
+
+// Level 获取 channel 的最低级别，没有配置时返回 false
+func (c *LevelController) Level(channel string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if level, has := c.current.Channels[channel]; has && level != "" {
+		return level, true
+	}
+	if c.current.Default != "" {
+		return c.current.Default, true
+	}
+	return "", false
+}
+
+// Enabled channel 下 level 的日志是否需要记录
+func (c *LevelController) Enabled(channel, level string) bool {
+	minLevel, has := c.Level(channel)
+	return !has || LevelEnabled(level, minLevel)
+}
+
+// MinLevel 所有配置中最低的级别，没有任何配置时返回 false；供 zap 等需要按级别预先过滤的日志库使用
+func (c *LevelController) MinLevel() (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	minLevel := c.current.Default
+	for _, level := range c.current.Channels {
+		if minLevel == "" || levelRank[strings.ToUpper(level)] < levelRank[strings.ToUpper(minLevel)] {
+			minLevel = level
+		}
+	}
+	return minLevel, minLevel != ""
+}
+
+func (c *LevelController) update(value []byte) {
+	if len(value) == 0 {
+		c.Reset()
+		return
+	}
+	config := new(LevelConfig)
+	if err := json.Unmarshal(value, config); err != nil {
+		c.error(context.Background(), "es_log.level", err)
+		return
+	}
+	c.Apply(config)
+}
+
+// Set 实现 etcd.Listener
+func (c *LevelController) Set(_ []byte, value []byte, _ int64) {
+	c.update(value)
+}
+
+// Create 实现 etcd.Listener
+func (c *LevelController) Create(_ []byte, value []byte, _ int64) {
+	c.update(value)
+}
+
+// Modify 实现 etcd.Listener
+func (c *LevelController) Modify(_ []byte, value []byte, _ int64) {
+	c.update(value)
+}
+
+// Delete 实现 etcd.Listener
+func (c *LevelController) Delete(_ []byte, _ int64) {
+	c.Reset()
+}
+
+// Exit 实现 etcd.Listener，保持退出前的配置
+func (c *LevelController) Exit(err string) {
+	c.error(context.Background(), "es_log.level", errors.New("watch exit: "+err))
+}
+
+func (c *LevelController) error(ctx context.Context, step string, err error) {
+	if c.logger == nil {
+		log2.Printf("【error】 step %s: %s \n", step, err.Error())
+		return
+	}
+	c.logger.Error(ctx, step, err)
+}
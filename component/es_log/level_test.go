@@ -0,0 +1,126 @@
+package es_log
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/driver/etcd"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelController(t *testing.T) {
+	c := NewLevelController(&LevelConfig{Default: LevelInfo})
+	if c.Enabled("order", LevelDebug) || !c.Enabled("order", LevelError) {
+		t.Fatal("base level not applied")
+	}
+
+	c.Set(nil, []byte(`{"default":"ERROR","channels":{"order":"DEBUG"}}`), 1)
+	if !c.Enabled("order", LevelDebug) || c.Enabled("pay", LevelWarning) {
+		t.Fatal("etcd level not applied")
+	}
+	if minLevel, _ := c.MinLevel(); minLevel != LevelDebug {
+		t.Fatalf("min level: %s", minLevel)
+	}
+
+	// 非法配置保持原配置，删除后恢复基础配置
+	c.Modify(nil, []byte(`{oops`), 2)
+	if !c.Enabled("order", LevelDebug) {
+		t.Fatal("invalid config applied")
+	}
+	c.Delete(nil, 0)
+	if c.Enabled("order", LevelDebug) || !c.Enabled("pay", LevelWarning) {
+		t.Fatal("not reverted after delete")
+	}
+
+	// 到期自动恢复
+	c.Apply(&LevelConfig{Channels: map[string]string{"order": LevelDebug}, ExpireAt: time.Now().Add(50 * time.Millisecond)})
+	if !c.Enabled("order", LevelDebug) {
+		t.Fatal("level not applied")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if c.Enabled("order", LevelDebug) {
+		t.Fatal("not reverted after expiry")
+	}
+	c.Apply(&LevelConfig{Default: LevelDebug, ExpireAt: time.Now().Add(-time.Second)})
+	if c.Enabled("order", LevelDebug) {
+		t.Fatal("expired config applied")
+	}
+}
+
+func TestLevelControllerAdapters(t *testing.T) {
+	sink := new(memorySink)
+	levels := NewLevelController(nil)
+	config := newTestConfig(100, DropNewest)
+	config.Levels = levels
+	client, err := NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(NewLogrusHook(client).SetMinLevel(logrus.WarnLevel).SetLevelController(levels))
+	zapLogger := zap.New(NewZapCoreWithLevels(client, zapcore.WarnLevel, levels))
+
+	send := func() {
+		logger.WithField(ChannelField, "order").Debug("logrus order")
+		logger.WithField(ChannelField, "pay").Info("logrus pay")
+		zapLogger.Named("order").Debug("zap order")
+		zapLogger.Named("pay").Info("zap pay")
+		_ = client.NewLog().SetChannel("pay").Info("client pay").Send()
+	}
+	send()
+	levels.Apply(&LevelConfig{Default: LevelError, Channels: map[string]string{"order": LevelDebug}})
+	send()
+	if err = client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := sink.msgs()
+	want := []string{"client pay", "logrus order", "zap order"}
+	if len(msgs) != len(want) {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	for i := range want {
+		if msgs[i] != want[i] {
+			t.Fatalf("msgs: %v", msgs)
+		}
+	}
+}
+
+// 需要真实的etcd，通过环境变量 ETCD_ENDPOINTS 指定，如：127.0.0.1:2379
+func TestLevelControllerWatch(t *testing.T) {
+	endpoints := os.Getenv("ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("ETCD_ENDPOINTS not set")
+	}
+	watcher, err := etcd.NewEtcdWatcher(strings.Split(endpoints, ","))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+	key := "/iota/test/es_log/level"
+	if err = watcher.Put(context.Background(), key, `{"default":"ERROR"}`); err != nil {
+		t.Fatal(err)
+	}
+	c := NewLevelController(nil)
+	c.Watch(watcher, key)
+	waitLevel := func(want string) {
+		for i := 0; i < 50; i++ {
+			if level, _ := c.Level("order"); level == want {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Fatalf("level not %s", want)
+	}
+	waitLevel(LevelError)
+	if err = watcher.Put(context.Background(), key, `{"channels":{"order":"DEBUG"}}`); err != nil {
+		t.Fatal(err)
+	}
+	waitLevel(LevelDebug)
+}
@@ -16,14 +16,19 @@ const LevelDebug = "DEBUG"
 const LevelNotice = "NOTICE"
 
 type MqData struct {
-	Project string `json:"project"`
-	Data    struct {
-		Msg       string                 `json:"msg"`
-		Level     string                 `json:"level"`
-		Channel   string                 `json:"channel"`
-		CreatedAt int64                  `json:"created_at"`
-		Define    map[string]interface{} `json:"define"`
-	} `json:"data"`
+	Project string     `json:"project"`
+	Data    MqDataBody `json:"data"`
+}
+
+// MqDataBody 日志内容
+type MqDataBody struct {
+	// ID 日志的唯一标识，投递前由 Client 生成，写入 ES 时据此生成 _id，重放时不会重复写入
+	ID        string                 `json:"id,omitempty"`
+	Msg       string                 `json:"msg"`
+	Level     string                 `json:"level"`
+	Channel   string                 `json:"channel"`
+	CreatedAt int64                  `json:"created_at"`
+	Define    map[string]interface{} `json:"define"`
 }
 
 type log struct {
@@ -34,6 +39,7 @@ type log struct {
 	Alarm       Alarm
 	MqLogger    rabbitmq.Logger
 	MaAddress   string
+	client      *Client // 绑定的客户端，为空时使用默认客户端
 }
 
 type Alarm interface {
@@ -164,5 +170,9 @@ func (log *log) Send() error {
 	if log.MqData.Data.CreatedAt == 0 {
 		log.MqData.Data.CreatedAt = time.Now().UnixNano() / 1e6
 	}
+	if log.client != nil {
+		data := log.MqData
+		return log.client.Send(&data)
+	}
 	return LogMqPush(log)
 }
@@ -0,0 +1,174 @@
+package es_log
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultDedupFirst = 10
+
+	// SuppressedKey 汇总日志 Define 中被抑制的条数
+	SuppressedKey = "suppressed"
+	// TemplateKey 汇总日志 Define 中的消息模板
+	TemplateKey = "template"
+	// WindowKey 汇总日志 Define 中的窗口时长
+	WindowKey = "window"
+)
+
+var (
+	templateUUID   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	templateHex    = regexp.MustCompile(`0[xX][0-9a-fA-F]+|\b[0-9a-fA-F]{16,}\b`)
+	templateNumber = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// MessageTemplate 默认的消息模板：把 uuid、十六进制串与数字替换为占位符，
+// 使只有参数不同的日志被视为相同
+func MessageTemplate(msg string) string {
+	msg = templateUUID.ReplaceAllString(msg, "<uuid>")
+	msg = templateHex.ReplaceAllString(msg, "<hex>")
+	return templateNumber.ReplaceAllString(msg, "<num>")
+}
+
+// PolicyConfig 采样、去重与限流配置，各项为零值时不启用
+type PolicyConfig struct {
+	// DedupWindow 去重窗口，窗口内相同级别、channel 与消息模板的日志只保留前 DedupFirst 条，
+	// 窗口结束后补发一条带抑制条数的汇总日志
+	DedupWindow time.Duration `yaml:"dedup-window"`
+	// DedupFirst 窗口内保留的条数，默认 10
+	DedupFirst int `yaml:"dedup-first"`
+	// SampleRatios 按级别的采样比例 (0,1]，未配置的级别全部保留
+	SampleRatios map[string]float64 `yaml:"sample-ratios"`
+	// MaxPerSecond 全局每秒最多日志数，汇总日志不受限制
+	MaxPerSecond int `yaml:"max-per-second"`
+	// TemplateFunc 自定义消息模板，默认 MessageTemplate
+	TemplateFunc func(msg string) string `yaml:"-"`
+}
+
+// dedupEntry 一个去重窗口
+type dedupEntry struct {
+	start      time.Time
+	count      int
+	suppressed int
+	template   string
+	first      *MqData
+}
+
+// policy 日志写入缓冲区之前的过滤
+type policy struct {
+	config *PolicyConfig
+
+	mu      sync.Mutex
+	dedup   map[string]*dedupEntry
+	sampled map[string]int64 // 各级别已经过采样的条数
+	tokens  float64
+	last    time.Time
+}
+
+func newPolicy(config *PolicyConfig) *policy {
+	if config.DedupFirst <= 0 {
+		config.DedupFirst = DefaultDedupFirst
+	}
+	if config.TemplateFunc == nil {
+		config.TemplateFunc = MessageTemplate
+	}
+	return &policy{
+		config:  config,
+		dedup:   make(map[string]*dedupEntry),
+		sampled: make(map[string]int64),
+		tokens:  float64(config.MaxPerSecond),
+	}
+}
+
+// allow 判断日志是否保留，同时返回已结束窗口的汇总日志
+func (p *policy) allow(data *MqData, now time.Time) (bool, []*MqData) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var summaries []*MqData
+	if p.config.DedupWindow > 0 {
+		template := p.config.TemplateFunc(data.Data.Msg)
+		key := data.Data.Level + "|" + data.Data.Channel + "|" + template
+		entry, has := p.dedup[key]
+		if has && now.Sub(entry.start) >= p.config.DedupWindow {
+			if summary := p.summary(entry); summary != nil {
+				summaries = append(summaries, summary)
+			}
+			has = false
+		}
+		if !has {
+			entry = &dedupEntry{start: now, template: template, first: data}
+			p.dedup[key] = entry
+		}
+		entry.count++
+		if entry.count > p.config.DedupFirst {
+			entry.suppressed++
+			return false, summaries
+		}
+	}
+	if ratio, has := p.config.SampleRatios[data.Data.Level]; has && ratio < 1 {
+		// 按比例均匀保留：第 n 条在 floor(n*ratio) 增加时保留
+		n := p.sampled[data.Data.Level]
+		p.sampled[data.Data.Level] = n + 1
+		if ratio <= 0 || int64(float64(n+1)*ratio) == int64(float64(n)*ratio) {
+			return false, summaries
+		}
+	}
+	if p.config.MaxPerSecond > 0 {
+		// 令牌桶，容量为一秒的配额
+		if !p.last.IsZero() {
+			p.tokens += now.Sub(p.last).Seconds() * float64(p.config.MaxPerSecond)
+			if p.tokens > float64(p.config.MaxPerSecond) {
+				p.tokens = float64(p.config.MaxPerSecond)
+			}
+		}
+		p.last = now
+		if p.tokens < 1 {
+			return false, summaries
+		}
+		p.tokens--
+	}
+	return true, summaries
+}
+
+// sweep 返回已结束窗口的汇总日志并清理过期窗口
+func (p *policy) sweep(now time.Time) []*MqData {
+	if p.config.DedupWindow <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var summaries []*MqData
+	for key, entry := range p.dedup {
+		if now.Sub(entry.start) < p.config.DedupWindow {
+			continue
+		}
+		if summary := p.summary(entry); summary != nil {
+			summaries = append(summaries, summary)
+		}
+		delete(p.dedup, key)
+	}
+	return summaries
+}
+
+// summary 窗口内有日志被抑制时生成汇总日志
+func (p *policy) summary(entry *dedupEntry) *MqData {
+	if entry.suppressed == 0 {
+		return nil
+	}
+	return &MqData{
+		Project: entry.first.Project,
+		Data: MqDataBody{
+			Msg:       fmt.Sprintf("%s (suppressed %d in %s)", entry.template, entry.suppressed, p.config.DedupWindow),
+			Level:     entry.first.Data.Level,
+			Channel:   entry.first.Data.Channel,
+			CreatedAt: time.Now().UnixNano() / 1e6,
+			Define: map[string]interface{}{
+				SuppressedKey: entry.suppressed,
+				TemplateKey:   entry.template,
+				WindowKey:     p.config.DedupWindow.String(),
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package es_log
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPolicyDedup(t *testing.T) {
+	p := newPolicy(&PolicyConfig{DedupWindow: time.Minute, DedupFirst: 2})
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		data := &MqData{Project: "p", Data: MqDataBody{Level: LevelError, Msg: fmt.Sprintf("call user %d failed", i)}}
+		if ok, _ := p.allow(data, now.Add(time.Duration(i)*time.Second)); ok {
+			allowed++
+		}
+	}
+	// 不同 channel 不受影响
+	if ok, _ := p.allow(&MqData{Data: MqDataBody{Level: LevelError, Channel: "other", Msg: "call user 9 failed"}}, now); !ok {
+		t.Fatal("other channel suppressed")
+	}
+	if allowed != 2 {
+		t.Fatalf("allowed: %d", allowed)
+	}
+
+	// 窗口结束后的第一条日志带出汇总
+	ok, summaries := p.allow(&MqData{Data: MqDataBody{Level: LevelError, Msg: "call user 7 failed"}}, now.Add(time.Minute))
+	if !ok || len(summaries) != 1 {
+		t.Fatalf("ok %v summaries %d", ok, len(summaries))
+	}
+	summary := summaries[0]
+	if summary.Project != "p" || summary.Data.Level != LevelError || summary.Data.Define[SuppressedKey] != 3 ||
+		summary.Data.Define[TemplateKey] != "call user <num> failed" {
+		t.Fatalf("summary: %+v", summary)
+	}
+	if summaries = p.sweep(now.Add(2 * time.Minute)); len(summaries) != 0 {
+		t.Fatalf("summaries: %d", len(summaries))
+	}
+}
+
+func TestPolicySampleAndRate(t *testing.T) {
+	p := newPolicy(&PolicyConfig{SampleRatios: map[string]float64{LevelDebug: 0.25}})
+	now := time.Now()
+	debug, info := 0, 0
+	for i := 0; i < 100; i++ {
+		if ok, _ := p.allow(&MqData{Data: MqDataBody{Level: LevelDebug}}, now); ok {
+			debug++
+		}
+		if ok, _ := p.allow(&MqData{Data: MqDataBody{Level: LevelInfo}}, now); ok {
+			info++
+		}
+	}
+	if debug != 25 || info != 100 {
+		t.Fatalf("debug %d info %d", debug, info)
+	}
+
+	p = newPolicy(&PolicyConfig{MaxPerSecond: 10})
+	allowed := 0
+	for i := 0; i < 30; i++ {
+		// 1.5 秒内均匀写入 30 条
+		if ok, _ := p.allow(&MqData{}, now.Add(time.Duration(i)*50*time.Millisecond)); ok {
+			allowed++
+		}
+	}
+	if allowed < 24 || allowed > 26 {
+		t.Fatalf("allowed: %d", allowed)
+	}
+}
+
+func TestClientPolicy(t *testing.T) {
+	sink := new(memorySink)
+	config := newTestConfig(100, DropNewest)
+	config.Policy = &PolicyConfig{DedupWindow: time.Hour, DedupFirst: 1}
+	client, err := NewClientWithSink(config, sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err = client.NewLog().Error(fmt.Sprintf("timeout after %dms", i)).Send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = client.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sink.msgs()
+	if len(msgs) != 2 || msgs[0] != "timeout after 0ms" || sink.records[1].Data.Define[SuppressedKey] != 3 {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	if stats := client.Stats(); stats.Suppressed != 3 || stats.Sent != 2 {
+		t.Fatalf("stats: %+v", stats)
+	}
+}
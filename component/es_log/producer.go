@@ -2,76 +2,83 @@ package es_log
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/actorbuf/iota/driver/rabbitmq"
 	"sync"
-	"time"
 )
 
-var ExLogProducer chan<- []byte
-var once sync.Once
-
-// 内部方法
-func send(msg []byte) error {
-	ExLogProducer <- msg
-	return nil
-}
-
-func SendLogDataToMq(msg []byte) error {
-	return send(msg)
-}
+// defaultClients 兼容 NewLog().Send() 的默认客户端，按 MQ 地址、ServerName 与 Environment 区分
+var defaultClients = make(map[string]*Client)
+var defaultClientsLock sync.Mutex
 
+// LogMqPush 通过默认客户端投递日志
+// 默认客户端在首次投递时按 log 上的配置创建，Logger、Alarm 与 MqLogger 取自首条日志；
+// 创建失败返回错误，下次投递时重试。投递是异步的，退出前需要调用 CloseDefault，否则缓冲区中的日志会丢失
 func LogMqPush(log *log) error {
-	// 启动拓客的生产者
-	initProducer(log)
-	dataByte, _ := json.Marshal(log.MqData)
-	// 推到队列里
-	return SendLogDataToMq(dataByte)
+	client, err := defaultClient(log)
+	if err != nil {
+		return err
+	}
+	data := log.MqData
+	return client.Send(&data)
 }
 
-func initProducer(log *log) {
-	once.Do(func() {
-		initTime := 1
-	initTry:
-		// 有配置用配的，没有用默认的
-		address := log.MaAddress
-		if address == "" {
-			// TODO ADD address
-			address = ""
-		}
-		mq := new(rabbitmq.RabbitMQ).SetConfig(&rabbitmq.Config{
-			Address:         address,
-			ExchangeName:    "es-log-exchange",
-			ExchangeKind:    "direct",
-			ExchangeDurable: true,
-			QueueName:       "es-log-queue",
-			QueueDurable:    true,
-			BindKey:         "es-log-routingkey",
-			DeliveryMode:    2,
-		})
-		if log.MqLogger != nil {
-			mq.WithLogger(log.MqLogger)
+// FlushDefault 等待默认客户端中已写入的日志全部投递
+func FlushDefault(ctx context.Context) error {
+	defaultClientsLock.Lock()
+	clients := make([]*Client, 0, len(defaultClients))
+	for _, client := range defaultClients {
+		clients = append(clients, client)
+	}
+	defaultClientsLock.Unlock()
+	var err error
+	for _, client := range clients {
+		if flushErr := client.Flush(ctx); flushErr != nil && err == nil {
+			err = flushErr
 		}
-		var err error
+	}
+	return err
+}
 
-		ExLogProducer, err = mq.StartProducer()
-		if err != nil {
-			log.alarmError(context.Background(), "initProducer", err)
-			log.error(context.Background(), "initProducer", err)
-			return
-		}
-		if err != nil {
-			// 重试三次
-			if initTime > 3 {
-				log.alarmError(context.Background(), "initProducer", err)
-				log.error(context.Background(), "initProducer", err)
-			} else {
-				initTime++
-				time.Sleep(time.Second * time.Duration(3))
-				goto initTry
-			}
-		} else {
-			log.info(context.Background(), "initProducer", "ExLogProducer init success")
+// CloseDefault 投递剩余日志后关闭所有默认客户端，进程退出前调用；之后的投递会重新创建默认客户端
+func CloseDefault(ctx context.Context) error {
+	defaultClientsLock.Lock()
+	clients := defaultClients
+	defaultClients = make(map[string]*Client)
+	defaultClientsLock.Unlock()
+	var err error
+	for _, client := range clients {
+		if closeErr := client.Close(ctx); closeErr != nil && err == nil {
+			err = closeErr
 		}
-	})
+	}
+	return err
+}
+
+func defaultClient(log *log) (*Client, error) {
+	key := log.MaAddress + "|" + log.ServerName + "|" + log.Environment
+	defaultClientsLock.Lock()
+	defer defaultClientsLock.Unlock()
+	if client, has := defaultClients[key]; has {
+		return client, nil
+	}
+	config := &Config{
+		ServerName:  log.ServerName,
+		Environment: log.Environment,
+		MqAddress:   log.MaAddress,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MqAddress == "" {
+		log.alarmError(context.Background(), "defaultClient", ErrMqAddressEmpty)
+		log.error(context.Background(), "defaultClient", ErrMqAddressEmpty)
+		return nil, ErrMqAddressEmpty
+	}
+	client, err := NewClientWithSink(config, NewMQSink(config.mqConfig(), log.MqLogger))
+	if err != nil {
+		return nil, err
+	}
+	client.SetLogger(log.Logger).SetAlarm(log.Alarm)
+	defaultClients[key] = client
+	log.info(context.Background(), "defaultClient", "es_log default client init success")
+	return client, nil
 }
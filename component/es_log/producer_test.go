@@ -0,0 +1,33 @@
+package es_log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCloseDefault(t *testing.T) {
+	sink := new(memorySink)
+	client, err := NewClientWithSink(newTestConfig(10, DropNewest), sink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultClientsLock.Lock()
+	defaultClients["amqp://test|iota|test"] = client
+	defaultClientsLock.Unlock()
+
+	if err = NewLog().SetServerName("iota").SetEnvironment("test").SetMqAddress("amqp://test").Info("a").Send(); err != nil {
+		t.Fatal(err)
+	}
+	if err = FlushDefault(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := sink.msgs(); len(msgs) != 1 || msgs[0] != "a" {
+		t.Fatalf("msgs: %v", msgs)
+	}
+	if err = CloseDefault(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !sink.closed || len(defaultClients) != 0 {
+		t.Fatalf("closed %v clients %d", sink.closed, len(defaultClients))
+	}
+}
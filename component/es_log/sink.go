@@ -0,0 +1,101 @@
+package es_log
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/actorbuf/iota/driver/rabbitmq"
+)
+
+// Sink 日志的输出端
+type Sink interface {
+	// Write 写入一批日志，返回错误时由 Client 决定重试或者丢弃
+	Write(ctx context.Context, records []*MqData) error
+	// Close 关闭输出端
+	Close() error
+}
+
+var _ Sink = new(mqSink)
+
+// mqSink 通过 RabbitMQ 投递日志
+type mqSink struct {
+	config   *rabbitmq.Config
+	mqLogger rabbitmq.Logger
+
+	mu        sync.Mutex
+	mq        *rabbitmq.RabbitMQ // 首次写入时连接，连接失败下次写入再重试
+	publishMu sync.Mutex         // 取消的投递在后台继续，与之后的投递串行
+}
+
+// NewMQSink 初始化 RabbitMQ 输出端
+func NewMQSink(config *rabbitmq.Config, mqLogger rabbitmq.Logger) Sink {
+	return &mqSink{config: config, mqLogger: mqLogger}
+}
+
+func (sink *mqSink) connect() (*rabbitmq.RabbitMQ, error) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.mq != nil {
+		return sink.mq, nil
+	}
+	mq := new(rabbitmq.RabbitMQ).SetConfig(sink.config)
+	if sink.mqLogger != nil {
+		mq.WithLogger(sink.mqLogger)
+	}
+	if err := mq.StartSyncProducer(); err != nil {
+		mq.CloseProducer()
+		return nil, err
+	}
+	sink.mq = mq
+	return mq, nil
+}
+
+// Write 一批日志作为一个消息投递（JSON数组）；只有一条时投递单个对象，兼容逐条消费的消费端。
+// 连接与投递不支持取消，ctx 结束时立即返回 ctx 的错误，已发起的投递在后台继续
+func (sink *mqSink) Write(ctx context.Context, records []*MqData) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var data []byte
+	var err error
+	if len(records) == 1 {
+		data, err = json.Marshal(records[0])
+	} else {
+		data, err = json.Marshal(records)
+	}
+	if err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.publish(data)
+	}()
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// publish 连接并投递，同一时间只有一个投递
+func (sink *mqSink) publish(data []byte) error {
+	sink.publishMu.Lock()
+	defer sink.publishMu.Unlock()
+	mq, err := sink.connect()
+	if err != nil {
+		return err
+	}
+	return mq.Publish(data)
+}
+
+func (sink *mqSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.mq != nil {
+		sink.mq.CloseProducer()
+		sink.mq = nil
+	}
+	return nil
+}
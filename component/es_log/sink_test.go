@@ -0,0 +1,42 @@
+package es_log
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMQSinkWriteContext(t *testing.T) {
+	// 只接受连接不响应握手，模拟卡住的 broker
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	config := newTestConfig(10, DropNewest)
+	config.MqAddress = "amqp://guest:guest@" + l.Addr().String() + "/"
+	if err = config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sink := NewMQSink(config.mqConfig(), nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err = sink.Write(ctx, []*MqData{newTestRecord("a")}); err != context.DeadlineExceeded {
+		t.Fatalf("write: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("write blocked %s", elapsed)
+	}
+}
@@ -0,0 +1,185 @@
+package es_log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const spoolExt = ".spool"
+
+// errSpoolOverflow 单批日志超出磁盘缓存大小
+var errSpoolOverflow = errors.New("es_log: batch exceeds spool max bytes")
+
+// spoolSegment 磁盘缓存中的一批日志，文件名：序号-条数.spool
+type spoolSegment struct {
+	seq   uint64
+	count int
+	size  int64
+	path  string
+}
+
+// spool 投递失败时的磁盘缓存，按写入顺序重放；超出大小时丢弃最早的批次
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	segments []*spoolSegment
+	size     int64
+	pending  int64 // 待重放的日志数
+	nextSeq  uint64
+}
+
+// openSpool 打开磁盘缓存目录，目录中已有的批次会被重放
+func openSpool(dir string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &spool{dir: dir, maxBytes: maxBytes, segments: make([]*spoolSegment, 0)}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), spoolExt) {
+			continue
+		}
+		var seq uint64
+		var count int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(file.Name(), spoolExt), "%d-%d", &seq, &count); err != nil {
+			continue
+		}
+		s.segments = append(s.segments, &spoolSegment{
+			seq:   seq,
+			count: count,
+			size:  file.Size(),
+			path:  filepath.Join(dir, file.Name()),
+		})
+		s.size += file.Size()
+		s.pending += int64(count)
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].seq < s.segments[j].seq
+	})
+	return s, nil
+}
+
+// push 写入一批日志，返回因超出大小被丢弃的日志数
+func (s *spool) push(records []*MqData) (int, error) {
+	buf := new(bytes.Buffer)
+	encoder := json.NewEncoder(buf)
+	for i := range records {
+		if err := encoder.Encode(records[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 单批超出上限直接丢弃
+	if s.maxBytes > 0 && int64(buf.Len()) > s.maxBytes {
+		return 0, errSpoolOverflow
+	}
+	dropped := 0
+	for s.maxBytes > 0 && s.size+int64(buf.Len()) > s.maxBytes && len(s.segments) > 0 {
+		n, err := s.removeOldest()
+		if err != nil {
+			return dropped, err
+		}
+		dropped += n
+	}
+
+	segment := &spoolSegment{seq: s.nextSeq, count: len(records), size: int64(buf.Len())}
+	segment.path = filepath.Join(s.dir, fmt.Sprintf("%020d-%d%s", segment.seq, segment.count, spoolExt))
+	tmp := segment.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return dropped, err
+	}
+	if err := os.Rename(tmp, segment.path); err != nil {
+		return dropped, err
+	}
+	s.nextSeq++
+	s.segments = append(s.segments, segment)
+	s.size += segment.size
+	s.pending += int64(segment.count)
+	return dropped, nil
+}
+
+// peek 读取最早的一批日志，没有时返回nil
+func (s *spool) peek() ([]*MqData, error) {
+	s.mu.Lock()
+	if len(s.segments) == 0 {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	path := s.segments[0].path
+	s.mu.Unlock()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	records := make([]*MqData, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		record := new(MqData)
+		if err = json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// pop 删除最早的一批日志
+func (s *spool) pop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 {
+		return nil
+	}
+	_, err := s.removeOldest()
+	return err
+}
+
+// removeOldest 删除最早的一批，返回删除的日志数
+func (s *spool) removeOldest() (int, error) {
+	segment := s.segments[0]
+	if err := os.Remove(segment.path); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	s.segments = s.segments[1:]
+	s.size -= segment.size
+	s.pending -= int64(segment.count)
+	return segment.count, nil
+}
+
+// empty 是否没有待重放的日志
+func (s *spool) empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) == 0
+}
+
+// pendingCount 待重放的日志数
+func (s *spool) pendingCount() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
@@ -0,0 +1,190 @@
+package builder
+
+import (
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// DefaultAutoWidthMin 自动列宽默认的最小宽度（Excel 默认列宽）
+	DefaultAutoWidthMin = 8.43
+	// DefaultAutoWidthMax 自动列宽默认的最大宽度
+	DefaultAutoWidthMax = 60
+	// DefaultAutoWidthSample 流式导出时默认采样的行数
+	DefaultAutoWidthSample = 1000
+	// autoWidthPadding 内容两边留出的宽度
+	autoWidthPadding = 2
+)
+
+// AutoWidth 自动列宽：按每列最长的显示内容设置宽度，中日韩文字算2个字符，数字与时间按列的数字格式计算；
+// 通过 AddColWidth 与 excel_width 设置的宽度优先
+type AutoWidth struct {
+	Min    float64 // 最小宽度
+	Max    float64 // 最大宽度
+	Sample int     // 流式导出时采样的行数（不包括表头）
+}
+
+// NewAutoWidth 新建自动列宽，使用默认的宽度范围与采样行数
+func NewAutoWidth() *AutoWidth {
+	return &AutoWidth{Min: DefaultAutoWidthMin, Max: DefaultAutoWidthMax, Sample: DefaultAutoWidthSample}
+}
+
+// SetBounds 设置宽度范围
+func (autoWidth *AutoWidth) SetBounds(min, max float64) *AutoWidth {
+	autoWidth.Min = min
+	autoWidth.Max = max
+	return autoWidth
+}
+
+// SetSample 设置流式导出时采样的行数
+func (autoWidth *AutoWidth) SetSample(sample int) *AutoWidth {
+	autoWidth.Sample = sample
+	return autoWidth
+}
+
+// width 按显示的字符数计算宽度
+func (autoWidth *AutoWidth) width(chars int) float64 {
+	w := float64(chars)
+	if chars > 0 {
+		w += autoWidthPadding
+	}
+	if w < autoWidth.Min {
+		w = autoWidth.Min
+	}
+	if autoWidth.Max > 0 && w > autoWidth.Max {
+		w = autoWidth.Max
+	}
+	return w
+}
+
+// SetAutoWidth 设置自动列宽
+func SetAutoWidth(autoWidth *AutoWidth) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.autoWidth = autoWidth
+	}
+}
+
+// SetAutoWidth 设置自动列宽，优先于 File 的设置
+func (sheet *sheet) SetAutoWidth(autoWidth *AutoWidth) *sheet {
+	sheet.autoWidth = autoWidth
+	return sheet
+}
+
+// SetAutoWidth 设置所有sheet的自动列宽，sheet有设置则不生效
+func (file *File) SetAutoWidth(autoWidth *AutoWidth) *File {
+	file.autoWidth = autoWidth
+	return file
+}
+
+// getAutoWidth 生效的自动列宽设置
+func (sheet *sheet) getAutoWidth() *AutoWidth {
+	if sheet.autoWidth != nil {
+		return sheet.autoWidth
+	}
+	if sheet.file != nil {
+		return sheet.file.autoWidth
+	}
+	return nil
+}
+
+// widthMeter 统计每列最长的显示内容
+type widthMeter struct {
+	chars   []int
+	formats []string
+	merged  map[[2]int]struct{} // 横向合并的单元格不参与计算
+	value   func(value interface{}) interface{}
+}
+
+// newWidthMeter 按列配置的数字格式与合并单元格创建
+func (sheet *sheet) newWidthMeter() (*widthMeter, error) {
+	meter := &widthMeter{merged: make(map[[2]int]struct{}), value: sheet.cellValue}
+	styles, err := sheet.colStyles()
+	if err != nil {
+		return nil, err
+	}
+	for _, style := range styles {
+		format := ""
+		if style != nil && style.CustomNumFmt != nil {
+			format = *style.CustomNumFmt
+		}
+		meter.formats = append(meter.formats, format)
+	}
+	for _, merge := range sheet.mergeCells() {
+		hCol, hLine, err := excelize.CellNameToCoordinates(merge.GetHCell())
+		if err != nil {
+			return nil, err
+		}
+		vCol, vLine, err := excelize.CellNameToCoordinates(merge.GetVCell())
+		if err != nil {
+			return nil, err
+		}
+		if hCol == vCol {
+			continue
+		}
+		if hLine > vLine {
+			hLine, vLine = vLine, hLine
+		}
+		if hCol > vCol {
+			hCol, vCol = vCol, hCol
+		}
+		for line := hLine; line <= vLine; line++ {
+			for col := hCol; col <= vCol; col++ {
+				meter.merged[[2]int{col, line}] = struct{}{}
+			}
+		}
+	}
+	return meter, nil
+}
+
+// measure 统计一行，lineI 从1开始；表头不使用数字格式
+func (meter *widthMeter) measure(lineI int, line []interface{}, isHead bool) {
+	for i, value := range line {
+		if _, has := meter.merged[[2]int{i + 1, lineI}]; has {
+			continue
+		}
+		format := ""
+		if !isHead && i < len(meter.formats) {
+			format = meter.formats[i]
+		}
+		value = meter.value(value)
+		for len(meter.chars) <= i {
+			meter.chars = append(meter.chars, 0)
+		}
+		if w := util.ValueWidth(value, format); w > meter.chars[i] {
+			meter.chars[i] = w
+		}
+	}
+}
+
+// widths 每列的宽度，下标为列号减1
+func (meter *widthMeter) widths(autoWidth *AutoWidth) []float64 {
+	widths := make([]float64, len(meter.chars))
+	for i, chars := range meter.chars {
+		widths[i] = autoWidth.width(chars)
+	}
+	return widths
+}
+
+// upAutoWidth 按已经写入的数据设置自动列宽
+func (sheet *sheet) upAutoWidth() error {
+	autoWidth := sheet.getAutoWidth()
+	if autoWidth == nil {
+		return nil
+	}
+	meter, err := sheet.newWidthMeter()
+	if err != nil {
+		return err
+	}
+	for i, head := range sheet.heads {
+		meter.measure(i+1, head, true)
+	}
+	for lineI, line := range sheet.lines {
+		meter.measure(lineI, line, lineI <= len(sheet.heads))
+	}
+	for i, w := range meter.widths(autoWidth) {
+		if err = setColWidth(sheet.file.f, sheet.sheetName, NewColWidthByNum(i+1, i+1, w)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,457 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ChartLine     = "line"     // 折线图
+	ChartCol      = "col"      // 柱状图（竖向）
+	ChartBar      = "bar"      // 条形图（横向）
+	ChartArea     = "area"     // 面积图
+	ChartPie      = "pie"      // 饼图
+	ChartDoughnut = "doughnut" // 环形图
+)
+
+const (
+	ChartLegendTop      = "top"
+	ChartLegendBottom   = "bottom"
+	ChartLegendLeft     = "left"
+	ChartLegendRight    = "right"
+	ChartLegendTopRight = "top_right"
+	ChartLegendNone     = "none" // 不显示图例
+)
+
+// ChartSeries 图表的系列，数据区域通过列名（表头最后一行的值）或者 CellArea 指定
+type ChartSeries struct {
+	Column   string    // 列名，数据区域为该列的所有数据行
+	CellArea *CellArea // 数据区域，设置后 Column 不生效
+	Sheet    string    // 数据所在的sheet，默认为图表所在的sheet；按列名引用时该sheet需要先导出（在 File 中先添加）
+	Name     string    // 系列名所在的单元格，如 B1；按列名时默认为表头单元格
+	Type     string    // 系列的图表类型，与图表不同时组成组合图，如柱状图加折线图
+}
+
+// NewChartSeries 新建系列通过列名
+func NewChartSeries(column string) *ChartSeries {
+	return &ChartSeries{Column: column}
+}
+
+// NewChartSeriesByArea 新建系列通过单元格区域
+func NewChartSeriesByArea(area *CellArea) *ChartSeries {
+	return &ChartSeries{CellArea: area}
+}
+
+// SetSheet 设置数据所在的sheet
+func (series *ChartSeries) SetSheet(sheetName string) *ChartSeries {
+	series.Sheet = sheetName
+	return series
+}
+
+// SetName 设置系列名所在的单元格
+func (series *ChartSeries) SetName(cell string) *ChartSeries {
+	series.Name = cell
+	return series
+}
+
+// SetType 设置系列的图表类型，用于组合图
+func (series *ChartSeries) SetType(chartType string) *ChartSeries {
+	series.Type = chartType
+	return series
+}
+
+// ChartAxis 坐标轴
+type ChartAxis struct {
+	None           bool    // 不显示
+	Minimum        float64 // 最小值，为0时自动
+	Maximum        float64 // 最大值，为0时自动
+	MajorGridlines bool    // 主要网格线
+	MinorGridlines bool    // 次要网格线
+	ReverseOrder   bool    // 逆序
+}
+
+// NewChartAxis 新建坐标轴
+func NewChartAxis() *ChartAxis {
+	return new(ChartAxis)
+}
+
+// SetNone 设置不显示
+func (axis *ChartAxis) SetNone(none bool) *ChartAxis {
+	axis.None = none
+	return axis
+}
+
+// SetRange 设置刻度范围
+func (axis *ChartAxis) SetRange(min, max float64) *ChartAxis {
+	axis.Minimum = min
+	axis.Maximum = max
+	return axis
+}
+
+// SetGridlines 设置网格线
+func (axis *ChartAxis) SetGridlines(major, minor bool) *ChartAxis {
+	axis.MajorGridlines = major
+	axis.MinorGridlines = minor
+	return axis
+}
+
+// SetReverseOrder 设置逆序
+func (axis *ChartAxis) SetReverseOrder(reverse bool) *ChartAxis {
+	axis.ReverseOrder = reverse
+	return axis
+}
+
+// Chart 图表，在数据写入之后添加到sheet
+type Chart struct {
+	Cell          *Cell  // 图表左上角所在的单元格
+	Type          string // 图表类型
+	Title         string
+	Width         int    // 宽度（像素），为0时默认480
+	Height        int    // 高度（像素），为0时默认290
+	Legend        string // 图例位置，默认在下方
+	Categories    string // 分类（X轴）的列名
+	CategoryArea  *CellArea
+	CategorySheet string // 分类数据所在的sheet，默认为图表所在的sheet
+	Series        []*ChartSeries
+	XAxis         *ChartAxis
+	YAxis         *ChartAxis
+	ShowValue     bool // 显示数据标签
+	ShowPercent   bool // 显示百分比，用于饼图
+}
+
+// NewChartByNum 新建图表通过数字行列；如：(column = 5, line = 2) 图表放在"E2"
+func NewChartByNum(chartType string, column, line int) *Chart {
+	return &Chart{
+		Cell: &Cell{CellNum: &CellNum{Column: column, Line: line}},
+		Type: chartType,
+	}
+}
+
+// NewChartByChar 新建图表通过字母；如：(cell = "E2")
+func NewChartByChar(chartType, cell string) *Chart {
+	return &Chart{
+		Cell: &Cell{CellChar: &CellChar{Cell: cell}},
+		Type: chartType,
+	}
+}
+
+// SetTitle 设置标题
+func (chart *Chart) SetTitle(title string) *Chart {
+	chart.Title = title
+	return chart
+}
+
+// SetSize 设置大小（像素）
+func (chart *Chart) SetSize(width, height int) *Chart {
+	chart.Width = width
+	chart.Height = height
+	return chart
+}
+
+// SetLegend 设置图例位置，ChartLegendNone 不显示
+func (chart *Chart) SetLegend(position string) *Chart {
+	chart.Legend = position
+	return chart
+}
+
+// SetCategories 设置分类（X轴）的列名
+func (chart *Chart) SetCategories(column string) *Chart {
+	chart.Categories = column
+	return chart
+}
+
+// SetCategoryArea 设置分类（X轴）的单元格区域，设置后列名不生效
+func (chart *Chart) SetCategoryArea(area *CellArea) *Chart {
+	chart.CategoryArea = area
+	return chart
+}
+
+// SetCategorySheet 设置分类数据所在的sheet
+func (chart *Chart) SetCategorySheet(sheetName string) *Chart {
+	chart.CategorySheet = sheetName
+	return chart
+}
+
+// AddSeries 添加系列
+func (chart *Chart) AddSeries(series ...*ChartSeries) *Chart {
+	chart.Series = append(chart.Series, series...)
+	return chart
+}
+
+// SetXAxis 设置X轴
+func (chart *Chart) SetXAxis(axis *ChartAxis) *Chart {
+	chart.XAxis = axis
+	return chart
+}
+
+// SetYAxis 设置Y轴
+func (chart *Chart) SetYAxis(axis *ChartAxis) *Chart {
+	chart.YAxis = axis
+	return chart
+}
+
+// SetShowValue 设置显示数据标签
+func (chart *Chart) SetShowValue(show bool) *Chart {
+	chart.ShowValue = show
+	return chart
+}
+
+// SetShowPercent 设置显示百分比
+func (chart *Chart) SetShowPercent(show bool) *Chart {
+	chart.ShowPercent = show
+	return chart
+}
+
+// AddChart 添加图表
+func AddChart(chart *Chart) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.AddChart(chart)
+	}
+}
+
+// AddChart 添加图表
+func (sheet *sheet) AddChart(chart *Chart) *sheet {
+	sheet.charts = append(sheet.charts, chart)
+	return sheet
+}
+
+// 以下结构体对应 excelize AddChart 的 json 格式
+type chartFormat struct {
+	Type      string              `json:"type"`
+	Series    []chartSeriesFormat `json:"series"`
+	Dimension *chartDimension     `json:"dimension,omitempty"`
+	Legend    *chartLegend        `json:"legend,omitempty"`
+	Title     *chartTitle         `json:"title,omitempty"`
+	XAxis     *chartAxisFormat    `json:"x_axis,omitempty"`
+	YAxis     *chartAxisFormat    `json:"y_axis,omitempty"`
+	Plotarea  *chartPlotarea      `json:"plotarea,omitempty"`
+}
+
+type chartSeriesFormat struct {
+	Name       string `json:"name,omitempty"`
+	Categories string `json:"categories,omitempty"`
+	Values     string `json:"values"`
+}
+
+type chartDimension struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type chartLegend struct {
+	None     bool   `json:"none,omitempty"`
+	Position string `json:"position,omitempty"`
+}
+
+type chartTitle struct {
+	Name string `json:"name"`
+}
+
+type chartAxisFormat struct {
+	None           bool    `json:"none,omitempty"`
+	Minimum        float64 `json:"minimum,omitempty"`
+	Maximum        float64 `json:"maximum,omitempty"`
+	MajorGridlines bool    `json:"major_grid_lines,omitempty"`
+	MinorGridlines bool    `json:"minor_grid_lines,omitempty"`
+	ReverseOrder   bool    `json:"reverse_order,omitempty"`
+}
+
+type chartPlotarea struct {
+	ShowVal     bool `json:"show_val"`
+	ShowPercent bool `json:"show_percent"`
+}
+
+// toAxisFormat 坐标轴的json格式
+func (axis *ChartAxis) toAxisFormat() *chartAxisFormat {
+	if axis == nil {
+		return nil
+	}
+	return &chartAxisFormat{
+		None:           axis.None,
+		Minimum:        axis.Minimum,
+		Maximum:        axis.Maximum,
+		MajorGridlines: axis.MajorGridlines,
+		MinorGridlines: axis.MinorGridlines,
+		ReverseOrder:   axis.ReverseOrder,
+	}
+}
+
+// chartRef 图表数据的引用，如 'Sheet1'!$B$2:$B$10
+func chartRef(sheetName, hCell, vCell string) (string, error) {
+	hCol, hLine, err := excelize.CellNameToCoordinates(hCell)
+	if err != nil {
+		return "", err
+	}
+	ref := "'" + strings.ReplaceAll(sheetName, "'", "''") + "'!"
+	hCell, err = excelize.CoordinatesToCellName(hCol, hLine, true)
+	if err != nil {
+		return "", err
+	}
+	if vCell == "" || vCell == hCell {
+		return ref + hCell, nil
+	}
+	vCol, vLine, err := excelize.CellNameToCoordinates(vCell)
+	if err != nil {
+		return "", err
+	}
+	vCell, err = excelize.CoordinatesToCellName(vCol, vLine, true)
+	if err != nil {
+		return "", err
+	}
+	return ref + hCell + ":" + vCell, nil
+}
+
+// chartSheet 图表引用的sheet，为空时为当前sheet；不在 File 中时返回nil
+func (sheet *sheet) chartSheet(sheetName string) *sheet {
+	if sheetName == "" || sheetName == sheet.sheetName {
+		return sheet
+	}
+	for _, other := range sheet.file.sheets {
+		if other.sheetName == sheetName {
+			return other
+		}
+	}
+	return nil
+}
+
+// columnArea 按列名找到该列的表头单元格与数据区域
+func (sheet *sheet) columnArea(column string) (head, hCell, vCell string, err error) {
+	col := 0
+	if len(sheet.heads) > 0 {
+		for i, value := range sheet.heads[len(sheet.heads)-1] {
+			if fmt.Sprint(value) == column {
+				col = i + 1
+				break
+			}
+		}
+	}
+	if col == 0 {
+		return "", "", "", fmt.Errorf("%w: %s!%s", error2.ChartColumnErr, sheet.sheetName, column)
+	}
+	maxLine := sheet.maxLine
+	if maxLine <= len(sheet.heads) {
+		maxLine = len(sheet.heads) + 1
+	}
+	if head, err = excelize.CoordinatesToCellName(col, len(sheet.heads)); err != nil {
+		return
+	}
+	if hCell, err = excelize.CoordinatesToCellName(col, len(sheet.heads)+1); err != nil {
+		return
+	}
+	vCell, err = excelize.CoordinatesToCellName(col, maxLine)
+	return
+}
+
+// chartArea 按列名或者单元格区域得到引用，按列名时 name 为表头单元格的引用
+func (sheet *sheet) chartArea(sheetName, column string, area *CellArea) (name, ref string, err error) {
+	if sheetName == "" {
+		sheetName = sheet.sheetName
+	}
+	if area != nil {
+		ref, err = chartRef(sheetName, area.GetHCell(), area.GetVCell())
+		return
+	}
+	data := sheet.chartSheet(sheetName)
+	if data == nil {
+		return "", "", fmt.Errorf("%w: %s!%s", error2.ChartColumnErr, sheetName, column)
+	}
+	head, hCell, vCell, err := data.columnArea(column)
+	if err != nil {
+		return
+	}
+	if name, err = chartRef(sheetName, head, ""); err != nil {
+		return
+	}
+	ref, err = chartRef(sheetName, hCell, vCell)
+	return
+}
+
+// chartFormats 图表的json格式，不同类型的系列组成组合图
+func (sheet *sheet) chartFormats(chart *Chart) (string, []string, error) {
+	categories := ""
+	if chart.CategoryArea != nil || chart.Categories != "" {
+		var err error
+		if _, categories, err = sheet.chartArea(chart.CategorySheet, chart.Categories, chart.CategoryArea); err != nil {
+			return "", nil, err
+		}
+	}
+	formats := []*chartFormat{{Type: chart.Type}}
+	for _, series := range chart.Series {
+		name, values, err := sheet.chartArea(series.Sheet, series.Column, series.CellArea)
+		if err != nil {
+			return "", nil, err
+		}
+		if series.Name != "" {
+			sheetName := series.Sheet
+			if sheetName == "" {
+				sheetName = sheet.sheetName
+			}
+			if name, err = chartRef(sheetName, series.Name, ""); err != nil {
+				return "", nil, err
+			}
+		}
+		chartType := series.Type
+		if chartType == "" {
+			chartType = chart.Type
+		}
+		var format *chartFormat
+		for _, f := range formats {
+			if f.Type == chartType {
+				format = f
+				break
+			}
+		}
+		if format == nil {
+			format = &chartFormat{Type: chartType}
+			formats = append(formats, format)
+		}
+		format.Series = append(format.Series, chartSeriesFormat{Name: name, Categories: categories, Values: values})
+	}
+
+	main := formats[0]
+	if chart.Width > 0 && chart.Height > 0 {
+		main.Dimension = &chartDimension{Width: chart.Width, Height: chart.Height}
+	}
+	if chart.Legend == ChartLegendNone {
+		main.Legend = &chartLegend{None: true}
+	} else if chart.Legend != "" {
+		main.Legend = &chartLegend{Position: chart.Legend}
+	}
+	if chart.Title != "" {
+		main.Title = &chartTitle{Name: chart.Title}
+	}
+	if chart.ShowValue || chart.ShowPercent {
+		main.Plotarea = &chartPlotarea{ShowVal: chart.ShowValue, ShowPercent: chart.ShowPercent}
+	}
+
+	formatJSON := make([]string, 0, len(formats))
+	for _, format := range formats {
+		// 组合图共用坐标轴，excelize 以最后一个图表的设置为准，每个都需要设置
+		format.XAxis = chart.XAxis.toAxisFormat()
+		format.YAxis = chart.YAxis.toAxisFormat()
+		data, err := json.Marshal(format)
+		if err != nil {
+			return "", nil, err
+		}
+		formatJSON = append(formatJSON, string(data))
+	}
+	return formatJSON[0], formatJSON[1:], nil
+}
+
+// upChart 添加图表，在数据写入之后处理；流式导出时需要在 Flush 之前处理
+func (sheet *sheet) upChart() error {
+	for _, chart := range sheet.charts {
+		format, combo, err := sheet.chartFormats(chart)
+		if err != nil {
+			return err
+		}
+		if err = sheet.file.f.AddChart(sheet.sheetName, chart.Cell.GetCell(), format, combo...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+package builder
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const (
+	CsvContentType = "text/csv"
+	TsvContentType = "text/tab-separated-values"
+)
+
+// CsvTimeLayout 导出csv时时间的格式
+const CsvTimeLayout = "2006-01-02 15:04:05"
+
+// utf8BOM Excel 打开 UTF-8 编码的csv需要 BOM 才能正确识别中文
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvOptions csv导出配置
+type csvOptions struct {
+	comma     rune
+	bom       bool
+	gbk       bool
+	useCRLF   bool
+	sheetName string
+}
+
+// CsvOptionFunc csv导出修饰方法
+type CsvOptionFunc func(options *csvOptions)
+
+// SetCsvComma 设置分隔符，默认为逗号
+func SetCsvComma(comma rune) CsvOptionFunc {
+	return func(options *csvOptions) {
+		options.comma = comma
+	}
+}
+
+// SetCsvTSV 使用制表符分隔，即 tsv 格式
+func SetCsvTSV() CsvOptionFunc {
+	return SetCsvComma('\t')
+}
+
+// SetCsvBOM 是否写入 UTF-8 BOM，默认写入，便于 Excel 直接打开；GBK 编码时不写入
+func SetCsvBOM(bom bool) CsvOptionFunc {
+	return func(options *csvOptions) {
+		options.bom = bom
+	}
+}
+
+// SetCsvGBK 使用 GBK 编码输出，兼容只支持 GBK 的老系统
+func SetCsvGBK(gbk bool) CsvOptionFunc {
+	return func(options *csvOptions) {
+		options.gbk = gbk
+	}
+}
+
+// SetCsvCRLF 是否使用 \r\n 换行，默认 \n
+func SetCsvCRLF(useCRLF bool) CsvOptionFunc {
+	return func(options *csvOptions) {
+		options.useCRLF = useCRLF
+	}
+}
+
+// SetCsvSheetName csv 只有一个sheet，指定导出哪个sheet，默认第一个
+func SetCsvSheetName(sheetName string) CsvOptionFunc {
+	return func(options *csvOptions) {
+		options.sheetName = sheetName
+	}
+}
+
+func newCsvOptions(opts []CsvOptionFunc) *csvOptions {
+	options := &csvOptions{comma: ',', bom: true}
+	for i := range opts {
+		opts[i](options)
+	}
+	return options
+}
+
+// contentType web输出的 content-type
+func (options *csvOptions) contentType() string {
+	contentType := CsvContentType
+	if options.comma == '\t' {
+		contentType = TsvContentType
+	}
+	if options.gbk {
+		return contentType + "; charset=gbk"
+	}
+	return contentType + "; charset=utf-8"
+}
+
+// ext 文件后缀
+func (options *csvOptions) ext() string {
+	if options.comma == '\t' {
+		return ".tsv"
+	}
+	return ".csv"
+}
+
+// csvSheet 获取需要导出的sheet
+func (file *File) csvSheet(options *csvOptions) (*sheet, error) {
+	for i := range file.sheets {
+		name := file.sheets[i].sheetName
+		if name == "" {
+			name = "Sheet1"
+		}
+		if options.sheetName == "" || options.sheetName == name {
+			return file.sheets[i], nil
+		}
+	}
+	return nil, error2.SheetNotFoundErr
+}
+
+// ExportCsv 导出为csv（或tsv），只导出一个sheet，样式、宽度与合并等不生效
+func (file *File) ExportCsv(w io.Writer, opts ...CsvOptionFunc) error {
+	options := newCsvOptions(opts)
+	sheet, err := file.csvSheet(options)
+	if err != nil {
+		return err
+	}
+	return sheet.exportCsv(w, options)
+}
+
+// ExportWebCsv 导出csv给web
+func (file *File) ExportWebCsv(webContext ExportWebInterface, opts ...CsvOptionFunc) error {
+	options := newCsvOptions(opts)
+	if file.fileName == "" {
+		file.fileName = fmt.Sprintf("excel_%s%s", time.Now().Format("20060102150405"), options.ext())
+	}
+	sheet, err := file.csvSheet(options)
+	if err != nil {
+		return err
+	}
+	setWebHeader(webContext, file.fileName, options.contentType())
+	return sheet.exportCsv(webContext.GetWriter(), options)
+}
+
+// ExportCsv 导出为csv（或tsv）
+func (sheet *sheet) ExportCsv(w io.Writer, opts ...CsvOptionFunc) error {
+	return sheet.exportCsv(w, newCsvOptions(opts))
+}
+
+func (sheet *sheet) exportCsv(w io.Writer, options *csvOptions) (err error) {
+	buf := bufio.NewWriter(w)
+	var out io.Writer = buf
+	if options.gbk {
+		out = simplifiedchinese.GBK.NewEncoder().Writer(buf)
+	} else if options.bom {
+		if _, err = buf.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+	writer := csv.NewWriter(out)
+	writer.Comma = options.comma
+	writer.UseCRLF = options.useCRLF
+	writeLine := func(line []interface{}) error {
+		record := make([]string, len(line))
+		for i := range line {
+			record[i] = csvValue(line[i])
+		}
+		return writer.Write(record)
+	}
+
+	if sheet.streamData != nil {
+		if closer, ok := sheet.streamData.(io.Closer); ok {
+			defer func() {
+				if closeErr := closer.Close(); err == nil {
+					err = closeErr
+				}
+			}()
+		}
+		for _, head := range sheet.streamData.GetHeads() {
+			if err = writeLine(head); err != nil {
+				return err
+			}
+		}
+		for {
+			line, err := sheet.streamData.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err = writeLine(line); err != nil {
+				return err
+			}
+		}
+	} else {
+		dataBuilder := sheet.DataBuilder
+		if dataBuilder == nil {
+			dataBuilder = defaultDataBuilder
+		}
+		heads := dataBuilder.GetHeads()
+		lines, err := dataBuilder.GetLines()
+		if err != nil {
+			return err
+		}
+		for _, head := range heads {
+			if err = writeLine(head); err != nil {
+				return err
+			}
+		}
+		// 行号与xlsx一致，中间缺少的行输出空单元格（空行读取时会被忽略）
+		width := 0
+		for _, head := range heads {
+			if len(head) > width {
+				width = len(head)
+			}
+		}
+		lineIs := make([]int, 0, len(lines))
+		for lineI := range lines {
+			if lineI <= len(heads) {
+				return error2.NewLineCoverError(lineI)
+			}
+			if len(lines[lineI]) > width {
+				width = len(lines[lineI])
+			}
+			lineIs = append(lineIs, lineI)
+		}
+		sort.Ints(lineIs)
+		lineI := len(heads)
+		for _, i := range lineIs {
+			for lineI+1 < i {
+				lineI++
+				if err = writeLine(make([]interface{}, width)); err != nil {
+					return err
+				}
+			}
+			lineI = i
+			if err = writeLine(lines[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// csvValue 单元格的值转为文本，数字不会转为科学计数法
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(CsvTimeLayout)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(CsvTimeLayout)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,93 @@
+package builder
+
+import (
+	"context"
+	"io"
+
+	"github.com/actorbuf/iota/driver/mongodb"
+)
+
+// CursorDataBuilder 把 mongodb.Cursor 作为流式数据来源，每条文档解码为结构体后按 excel 的tag输出一行
+type CursorDataBuilder struct {
+	ctx       context.Context
+	cursor    *mongodb.Cursor
+	newItem   func() interface{}
+	convert   func(item interface{}) ([]interface{}, error)
+	heads     [][]interface{}
+	mergeCell []*MergeCell // 多级表头的合并
+}
+
+// NewCursorDataBuilder newItem 返回用于解码的结构体指针，如：func() interface{} { return new(User) }；
+// 默认按 newItem 的 excel_head 生成头信息
+func NewCursorDataBuilder(ctx context.Context, cursor *mongodb.Cursor, newItem func() interface{}) *CursorDataBuilder {
+	heads, merges := appendStructHead(nil, newItem())
+	return &CursorDataBuilder{
+		ctx:       ctx,
+		cursor:    cursor,
+		newItem:   newItem,
+		convert:   StructLine,
+		heads:     heads,
+		mergeCell: merges,
+	}
+}
+
+// AddHead 添加头信息
+func (dataBuilder *CursorDataBuilder) AddHead(head []interface{}) *CursorDataBuilder {
+	dataBuilder.heads = append(dataBuilder.heads, head)
+	return dataBuilder
+}
+
+// AddHeads 替换头信息，同时去掉按结构体生成的表头合并
+func (dataBuilder *CursorDataBuilder) AddHeads(heads [][]interface{}) *CursorDataBuilder {
+	dataBuilder.heads = heads
+	dataBuilder.mergeCell = nil
+	return dataBuilder
+}
+
+// SetConvert 设置解码后的结构体转为一行数据的方法，默认按 excel 的tag转换
+func (dataBuilder *CursorDataBuilder) SetConvert(convert func(item interface{}) ([]interface{}, error)) *CursorDataBuilder {
+	dataBuilder.convert = convert
+	return dataBuilder
+}
+
+func (dataBuilder *CursorDataBuilder) GetHeads() [][]interface{} {
+	return dataBuilder.heads
+}
+
+// GetColumns 按 newItem 的结构体获取列配置
+func (dataBuilder *CursorDataBuilder) GetColumns() []*Column {
+	if t := structType(dataBuilder.newItem()); t != nil {
+		return structColumns(t)
+	}
+	return nil
+}
+
+// GetMergeCells 多级表头需要合并的单元格
+func (dataBuilder *CursorDataBuilder) GetMergeCells() []*MergeCell {
+	return dataBuilder.mergeCell
+}
+
+func (dataBuilder *CursorDataBuilder) Next() ([]interface{}, error) {
+	if dataBuilder.cursor == nil {
+		return nil, mongodb.CursorIsNil
+	}
+	if !dataBuilder.cursor.Next(dataBuilder.ctx) {
+		if err := dataBuilder.cursor.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	item := dataBuilder.newItem()
+	if err := dataBuilder.cursor.Decode(dataBuilder.ctx, item); err != nil {
+		return nil, err
+	}
+	return dataBuilder.convert(item)
+}
+
+// Close 关闭 cursor，导出结束后自动调用
+func (dataBuilder *CursorDataBuilder) Close() error {
+	if dataBuilder.cursor == nil {
+		return nil
+	}
+	return dataBuilder.cursor.Close(dataBuilder.ctx)
+}
@@ -10,6 +10,13 @@ const ExcelHeadTag = "excel_head"
 const ExcelExclude = "excel_exclude"
 const ExcelExcludeTrue = "true"
 
+// ExcelMapTag 枚举值与展示文本的映射，格式为 值:文本，多个用逗号分隔，如 excel_map:"1:启用,0:禁用"
+const ExcelMapTag = "excel_map"
+
+// ExcelFormatTag Excel 数字格式，导出时设置为列的数字格式，如 excel_format:"yyyy-mm-dd"、"#,##0.00"、"0%"；
+// 导入时间字段时转为对应的解析格式，也可以直接写 Go 的时间格式，多个用 | 分隔
+const ExcelFormatTag = "excel_format"
+
 // DataBuilder 数据来源builder
 type DataBuilder interface {
 	GetHeads() [][]interface{}                // 获取头部数据
@@ -18,6 +25,8 @@ type DataBuilder interface {
 
 var _ DataBuilder = new(ArrDataBuilder)
 var _ DataBuilder = new(StructDataBuilder)
+var _ ColumnDataBuilder = new(StructDataBuilder)
+var _ MergeDataBuilder = new(StructDataBuilder)
 
 // ArrDataBuilder 通过二维数组创建数据
 type ArrDataBuilder struct {
@@ -60,10 +69,13 @@ func (dataBuilder *ArrDataBuilder) GetLines() (map[int][]interface{}, error) {
 	return dataBuilder.lines, nil
 }
 
-// StructDataBuilder 通过结构体建立数据（当前结构体不支持变长）
+// StructDataBuilder 通过结构体建立数据：嵌套的结构体展开为多列，设置了 excel_head 的嵌套结构体生成多级表头；
+// 行数据为 []interface{} 时原样输出
 type StructDataBuilder struct {
-	heads [][]interface{}
-	lines map[int]interface{}
+	heads     [][]interface{}
+	lines     map[int]interface{}
+	dataType  reflect.Type // 结构体类型，用于生成列配置
+	mergeCell []*MergeCell // 多级表头的合并
 }
 
 // AddHead 添加头信息
@@ -74,33 +86,19 @@ func (dataBuilder *StructDataBuilder) AddHead(head []interface{}) *StructDataBui
 
 // AddHeadByStruct 通过结构体添加头信息
 func (dataBuilder *StructDataBuilder) AddHeadByStruct(s interface{}) *StructDataBuilder {
-	value := reflect.TypeOf(s)
-	if value.Kind() == reflect.Ptr {
-		value = value.Elem()
-	}
-	if value.Kind() != reflect.Struct {
-		return dataBuilder
-	}
-	head := make([]interface{}, 0)
-	for i := 0; i < value.NumField(); i++ {
-		tag := value.Field(i).Tag
-		// 去掉不需要excel处理的字段
-		exclude := tag.Get(ExcelExclude)
-		if exclude != "" && strings.Contains(exclude, ExcelExcludeTrue) {
-			continue
-		}
-		tagHead := tag.Get(ExcelHeadTag)
-		head = append(head, tagHead)
-	}
-	if len(head) > 0 {
-		dataBuilder.AddHead(head)
+	if t := structType(s); t != nil {
+		dataBuilder.dataType = t
 	}
+	var merges []*MergeCell
+	dataBuilder.heads, merges = appendStructHead(dataBuilder.heads, s)
+	dataBuilder.mergeCell = append(dataBuilder.mergeCell, merges...)
 	return dataBuilder
 }
 
-// AddHeads 添加头信息
+// AddHeads 替换头信息，同时去掉按结构体生成的表头合并
 func (dataBuilder *StructDataBuilder) AddHeads(heads [][]interface{}) *StructDataBuilder {
 	dataBuilder.heads = heads
+	dataBuilder.mergeCell = nil
 	return dataBuilder
 }
 
@@ -133,27 +131,10 @@ func (dataBuilder *StructDataBuilder) AddStructs(lines []interface{}) *StructDat
 func (dataBuilder *StructDataBuilder) AddStructAndHead(lines []interface{}) *StructDataBuilder {
 	// 按tag拿到对应head
 	for i := range lines {
-		value := reflect.TypeOf(lines[i])
-		if value.Kind() == reflect.Ptr {
-			value = value.Elem()
-		}
-		if value.Kind() != reflect.Struct {
+		if structType(lines[i]) == nil {
 			continue
 		}
-		head := make([]interface{}, 0)
-		for i := 0; i < value.NumField(); i++ {
-			tag := value.Field(i).Tag
-			// 去掉不需要excel处理的字段
-			exclude := tag.Get(ExcelExclude)
-			if exclude != "" && strings.Contains(exclude, ExcelExcludeTrue) {
-				continue
-			}
-			tagHead := tag.Get(ExcelHeadTag)
-			head = append(head, tagHead)
-		}
-		if len(head) > 0 {
-			dataBuilder.AddHead(head)
-		}
+		dataBuilder.AddHeadByStruct(lines[i])
 		break
 	}
 
@@ -172,27 +153,71 @@ func (dataBuilder *StructDataBuilder) GetHeads() [][]interface{} {
 func (dataBuilder *StructDataBuilder) GetLines() (map[int][]interface{}, error) {
 	m := make(map[int][]interface{}, len(dataBuilder.lines))
 	for lineI := range dataBuilder.lines {
-		value := reflect.ValueOf(dataBuilder.lines[lineI])
-		if value.Kind() == reflect.Ptr {
-			value = value.Elem()
+		// 非结构体的行（如合计行）原样输出
+		if line, ok := dataBuilder.lines[lineI].([]interface{}); ok {
+			m[lineI] = line
+			continue
 		}
-		if value.Kind() != reflect.Struct {
-			return nil, error2.StructBuilderDataErr
-		}
-		line := make([]interface{}, 0, value.NumField())
-		valueT := value.Type()
-
-		for i := 0; i < value.NumField(); i++ {
-			tag := valueT.Field(i).Tag
-			// 去掉不需要excel处理的字段
-			exclude := tag.Get(ExcelExclude)
-			if exclude != "" && strings.Contains(exclude, ExcelExcludeTrue) {
-				continue
-			}
-			line = append(line, value.Field(i).Interface())
+		line, err := StructLine(dataBuilder.lines[lineI])
+		if err != nil {
+			return nil, err
 		}
 		m[lineI] = line
 	}
 
 	return m, nil
 }
+
+// GetColumns 按结构体的tag获取列配置
+func (dataBuilder *StructDataBuilder) GetColumns() []*Column {
+	if dataBuilder.dataType == nil {
+		for lineI := range dataBuilder.lines {
+			if t := structType(dataBuilder.lines[lineI]); t != nil {
+				dataBuilder.dataType = t
+				break
+			}
+		}
+	}
+	if dataBuilder.dataType == nil {
+		return nil
+	}
+	return structColumns(dataBuilder.dataType)
+}
+
+// GetMergeCells 多级表头需要合并的单元格
+func (dataBuilder *StructDataBuilder) GetMergeCells() []*MergeCell {
+	return dataBuilder.mergeCell
+}
+
+// isExclude 字段是否不需要excel处理
+func isExclude(tag reflect.StructTag) bool {
+	exclude := tag.Get(ExcelExclude)
+	return exclude != "" && strings.Contains(exclude, ExcelExcludeTrue)
+}
+
+// appendStructHead 按结构体的tag追加表头，有嵌套分组时追加多行并返回需要合并的单元格，不是结构体时不处理
+func appendStructHead(heads [][]interface{}, s interface{}) ([][]interface{}, []*MergeCell) {
+	t := structType(s)
+	if t == nil {
+		return heads, nil
+	}
+	structHeads, merges := structHeads(t, len(heads))
+	return append(heads, structHeads...), merges
+}
+
+// StructLine 按 excel 的tag把结构体转为一行数据，可用于自定义的 StreamDataBuilder
+func StructLine(data interface{}) ([]interface{}, error) {
+	value := reflect.ValueOf(data)
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, error2.StructBuilderDataErr
+	}
+	fields := parseStructFields(value.Type())
+	line := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		line = append(line, field.value(value))
+	}
+	return line, nil
+}
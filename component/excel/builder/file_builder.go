@@ -4,19 +4,30 @@ import (
 	"fmt"
 	"github.com/xuri/excelize/v2"
 	"io"
-	"net/url"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/actorbuf/iota/generator/uuid"
 )
 
+// XlsxContentType xlsx 文件的 MIME 类型
+const XlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
 type File struct {
 	f         *excelize.File
 	fileName  string
 	sheets    []*sheet
 	headStyle *excelize.Style
 	fileStyle []*Style
+	styleRefs map[string]*excelize.Style // 可以被 excel_style 引用的样式
+	template  *Template                  // 模板导出
+	autoWidth *AutoWidth                 // 自动列宽，sheet没有设置时使用
+	exported  bool
+
+	workbookProtection *WorkbookProtection // 工作簿保护
+	password           string              // 打开文件的密码
+	unlockedStyles     map[int]int         // 取消锁定的样式，key为原样式
 }
 
 func NewFile() *File {
@@ -51,17 +62,35 @@ func (file *File) SetHeadStyle(style *excelize.Style) *File {
 	return file
 }
 
+// AddStyleRef 添加可以被结构体 excel_style 引用的样式，所有sheet共用
+func (file *File) AddStyleRef(name string, style *excelize.Style) *File {
+	if file.styleRefs == nil {
+		file.styleRefs = make(map[string]*excelize.Style)
+	}
+	file.styleRefs[name] = style
+	return file
+}
+
 // SetExcel 植入excel文件，如果没有植入，会自动创建一个新的
 func (file *File) SetExcel(f *excelize.File) *File {
 	file.f = f
 	return file
 }
 
+// SetTemplate 按模板导出：导出时先填充模板，再导出添加的sheet
+func (file *File) SetTemplate(t *Template) *File {
+	file.template = t
+	return file
+}
+
 // exportInit 导出前初始化
 func (file *File) exportInit() {
 	if file.headStyle == nil {
 		file.SetHeadStyle(&DefaultHeadStyle)
 	}
+	if file.f == nil && file.template != nil {
+		file.SetExcel(file.template.Excel())
+	}
 	if file.f == nil {
 		file.SetExcel(excelize.NewFile())
 	}
@@ -70,6 +99,11 @@ func (file *File) exportInit() {
 // Export 导出
 func (file *File) Export() (*excelize.File, error) {
 	file.exportInit() // 统一初始化的地方
+	if file.template != nil {
+		if _, err := file.template.Execute(); err != nil {
+			return nil, err
+		}
+	}
 	for i := range file.sheets {
 		file.sheets[i].SetFile(file) // 植入file
 		err := file.sheets[i].Export()
@@ -77,16 +111,19 @@ func (file *File) Export() (*excelize.File, error) {
 			return nil, err
 		}
 	}
+	if err := file.upWorkbookProtection(); err != nil {
+		return nil, err
+	}
+	file.exported = true
 	return file.f, nil
 }
 
 // ExportFile 导出到文件
 func (file *File) ExportFile(w io.Writer) error {
-	f, err := file.Export()
-	if err != nil {
+	if _, err := file.Export(); err != nil {
 		return err
 	}
-	return f.Write(w)
+	return file.write(w)
 }
 
 // ExportWeb 导出给web
@@ -94,18 +131,63 @@ func (file *File) ExportWeb(webContext ExportWebInterface) error {
 	if file.fileName == "" {
 		file.fileName = fmt.Sprintf("excel_%s.xlsx", time.Now().Format("20060102150405"))
 	}
-	if file.f == nil {
+	if !file.exported {
 		_, err := file.Export()
 		if err != nil {
 			return err
 		}
 	}
+	setWebHeader(webContext, file.fileName, XlsxContentType)
+	return file.write(webContext.GetWriter())
+}
+
+// ExportWebReader 把已经生成的文件输出给web，如异步导出完成后的下载
+func ExportWebReader(webContext ExportWebInterface, fileName, contentType string, r io.Reader) error {
+	setWebHeader(webContext, fileName, contentType)
+	_, err := io.Copy(webContext.GetWriter(), r)
+	return err
+}
+
+// setWebHeader 设置下载的头信息
+func setWebHeader(webContext ExportWebInterface, fileName, contentType string) {
 	webContext.Header("content-description", "File Transfer")
-	webContext.Header("content-type", "application/octet-stream")
-	webContext.Header("content-disposition", "attachment; filename="+url.QueryEscape(filepath.Base(file.fileName)))
+	webContext.Header("content-type", contentType)
+	webContext.Header("content-disposition", ContentDisposition(fileName))
 	webContext.Header("content-transfer-encoding", "binary")
 	webContext.AddHeader("Access-Control-Expose-Headers", "content-disposition")
 	webContext.Header("pragma", "public")
 	webContext.Header("etag", uuid.TimeUUID().String())
-	return file.f.Write(webContext.GetWriter())
+}
+
+// ContentDisposition 下载的 content-disposition：filename 为 ASCII 的文件名，非 ASCII 字符替换为 _，
+// filename* 为按 RFC 5987 编码的 UTF-8 文件名，支持的浏览器优先使用 filename*，中文文件名不会显示为 %E4%B8%AD 这样的编码
+func ContentDisposition(fileName string) string {
+	fileName = filepath.Base(fileName)
+	var fallback, encoded strings.Builder
+	for _, r := range fileName {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(fileName) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	if fallback.String() == fileName {
+		return fmt.Sprintf(`attachment; filename="%s"`, fileName)
+	}
+	return fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback.String(), encoded.String())
+}
+
+// isAttrChar RFC 5987 中不需要编码的字符
+func isAttrChar(b byte) bool {
+	if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
 }
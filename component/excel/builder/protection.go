@@ -0,0 +1,261 @@
+package builder
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// 工作表保护后仍然允许的操作
+const (
+	ProtectFormatCells      = "format_cells"
+	ProtectFormatColumns    = "format_columns"
+	ProtectFormatRows       = "format_rows"
+	ProtectInsertColumns    = "insert_columns"
+	ProtectInsertRows       = "insert_rows"
+	ProtectInsertHyperlinks = "insert_hyperlinks"
+	ProtectDeleteColumns    = "delete_columns"
+	ProtectDeleteRows       = "delete_rows"
+	ProtectSort             = "sort"
+	ProtectAutoFilter       = "auto_filter"
+	ProtectPivotTables      = "pivot_tables"
+	ProtectEditObjects      = "edit_objects"
+	ProtectEditScenarios    = "edit_scenarios"
+)
+
+// SheetProtection 工作表保护：保护后单元格默认锁定不能编辑，Unlocked 区域仍然可以编辑；
+// 密码为空时不需要密码就可以取消保护
+type SheetProtection struct {
+	Password       string
+	Allow          []string    // 允许的操作，如 ProtectSort、ProtectAutoFilter
+	Unlocked       []*CellArea // 保护后仍然可以编辑的区域，流式导出时只对写入的单元格生效
+	NoSelectLocked bool        // 不允许选中锁定的单元格
+}
+
+// NewSheetProtection 新建工作表保护，默认只读：只允许选中单元格
+func NewSheetProtection(password string) *SheetProtection {
+	return &SheetProtection{Password: password}
+}
+
+// SetAllow 添加允许的操作
+func (protection *SheetProtection) SetAllow(actions ...string) *SheetProtection {
+	protection.Allow = append(protection.Allow, actions...)
+	return protection
+}
+
+// AddUnlockedByNum 添加可以编辑的区域通过数字行列；如：(hColumn = 3, hLine = 2, vCollum = 3, vLine = 100) 对应"C2:C100"
+func (protection *SheetProtection) AddUnlockedByNum(hColumn, hLine, vCollum, vLine int) *SheetProtection {
+	protection.Unlocked = append(protection.Unlocked, &CellArea{
+		HCell: &Cell{CellNum: &CellNum{Column: hColumn, Line: hLine}},
+		VCell: &Cell{CellNum: &CellNum{Column: vCollum, Line: vLine}},
+	})
+	return protection
+}
+
+// AddUnlockedByChar 添加可以编辑的区域通过字母；如：(hCell = "C2", vCell = "C100") 对应"C2:C100"
+func (protection *SheetProtection) AddUnlockedByChar(hCell, vCell string) *SheetProtection {
+	protection.Unlocked = append(protection.Unlocked, &CellArea{
+		HCell: &Cell{CellChar: &CellChar{Cell: hCell}},
+		VCell: &Cell{CellChar: &CellChar{Cell: vCell}},
+	})
+	return protection
+}
+
+// SetNoSelectLocked 设置不允许选中锁定的单元格
+func (protection *SheetProtection) SetNoSelectLocked(noSelect bool) *SheetProtection {
+	protection.NoSelectLocked = noSelect
+	return protection
+}
+
+// format 转为 excelize 的设置；excelize 直接写入 sheetProtection 的属性，属性为 true 表示该操作被禁止
+func (protection *SheetProtection) format() *excelize.FormatSheetProtection {
+	allow := make(map[string]bool, len(protection.Allow))
+	for _, action := range protection.Allow {
+		allow[action] = true
+	}
+	return &excelize.FormatSheetProtection{
+		Password:            protection.Password,
+		FormatCells:         !allow[ProtectFormatCells],
+		FormatColumns:       !allow[ProtectFormatColumns],
+		FormatRows:          !allow[ProtectFormatRows],
+		InsertColumns:       !allow[ProtectInsertColumns],
+		InsertRows:          !allow[ProtectInsertRows],
+		InsertHyperlinks:    !allow[ProtectInsertHyperlinks],
+		DeleteColumns:       !allow[ProtectDeleteColumns],
+		DeleteRows:          !allow[ProtectDeleteRows],
+		Sort:                !allow[ProtectSort],
+		AutoFilter:          !allow[ProtectAutoFilter],
+		PivotTables:         !allow[ProtectPivotTables],
+		EditObjects:         !allow[ProtectEditObjects],
+		EditScenarios:       !allow[ProtectEditScenarios],
+		SelectLockedCells:   protection.NoSelectLocked,
+		SelectUnlockedCells: false,
+	}
+}
+
+// WorkbookProtection 工作簿保护：锁定结构后不能添加、删除、重命名、隐藏工作表
+type WorkbookProtection struct {
+	Password      string
+	LockStructure bool // 锁定结构
+	LockWindows   bool // 锁定窗口
+}
+
+// NewWorkbookProtection 新建工作簿保护，默认锁定结构
+func NewWorkbookProtection(password string) *WorkbookProtection {
+	return &WorkbookProtection{Password: password, LockStructure: true}
+}
+
+// SetLockWindows 设置锁定窗口
+func (protection *WorkbookProtection) SetLockWindows(lock bool) *WorkbookProtection {
+	protection.LockWindows = lock
+	return protection
+}
+
+// SetSheetProtection 设置工作表保护
+func SetSheetProtection(protection *SheetProtection) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.protection = protection
+	}
+}
+
+// SetProtection 设置工作表保护
+func (sheet *sheet) SetProtection(protection *SheetProtection) *sheet {
+	sheet.protection = protection
+	return sheet
+}
+
+// SetWorkbookProtection 设置工作簿保护
+func (file *File) SetWorkbookProtection(protection *WorkbookProtection) *File {
+	file.workbookProtection = protection
+	return file
+}
+
+// SetPassword 设置打开文件的密码，导出的 xlsx 会被加密（csv不支持）；
+// 加密需要先在内存中生成整个文件
+func (file *File) SetPassword(password string) *File {
+	file.password = password
+	return file
+}
+
+// write 输出 xlsx，设置了密码时加密
+func (file *File) write(w io.Writer) error {
+	if file.password == "" {
+		return file.f.Write(w)
+	}
+	buf, err := file.f.WriteToBuffer()
+	if err != nil {
+		return err
+	}
+	data, err := util.EncryptXlsx(buf.Bytes(), file.password)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// unlockedStyle 在 styleID 的基础上取消锁定的样式
+func (file *File) unlockedStyle(styleID int) (int, error) {
+	if id, has := file.unlockedStyles[styleID]; has {
+		return id, nil
+	}
+	if file.unlockedStyles == nil {
+		file.unlockedStyles = make(map[int]int)
+	}
+	unlockedID, err := file.f.NewStyle(&excelize.Style{Protection: &excelize.Protection{Locked: false}})
+	if err != nil {
+		return 0, err
+	}
+	id := unlockedID
+	if xfs := file.f.Styles.CellXfs; styleID > 0 && styleID < len(xfs.Xf) {
+		xf := xfs.Xf[styleID]
+		xf.Protection = xfs.Xf[unlockedID].Protection
+		xf.ApplyProtection = xfs.Xf[unlockedID].ApplyProtection
+		xfs.Xf = append(xfs.Xf, xf)
+		xfs.Count = len(xfs.Xf)
+		id = len(xfs.Xf) - 1
+	}
+	file.unlockedStyles[styleID] = id
+	return id, nil
+}
+
+// unlockedAreas 可以编辑的区域
+func (sheet *sheet) unlockedAreas() ([]*streamStyle, error) {
+	if sheet.protection == nil {
+		return nil, nil
+	}
+	areas := make([]*streamStyle, 0, len(sheet.protection.Unlocked))
+	for _, area := range sheet.protection.Unlocked {
+		hCol, hLine, vCol, vLine, err := areaBounds(area.GetHCell(), area.GetVCell())
+		if err != nil {
+			return nil, err
+		}
+		areas = append(areas, &streamStyle{hCol: hCol, hLine: hLine, vCol: vCol, vLine: vLine})
+	}
+	return areas, nil
+}
+
+// upProtection 工作表保护，在数据写入之后处理；流式导出时可编辑区域的样式在写入时设置，保护需要在 Flush 之前处理
+func (sheet *sheet) upProtection() error {
+	if sheet.protection == nil {
+		return nil
+	}
+	f := sheet.file.f
+	if sheet.streamData == nil {
+		areas, err := sheet.unlockedAreas()
+		if err != nil {
+			return err
+		}
+		for _, area := range areas {
+			for line := area.hLine; line <= area.vLine; line++ {
+				for col := area.hCol; col <= area.vCol; col++ {
+					axis, err := excelize.CoordinatesToCellName(col, line)
+					if err != nil {
+						return err
+					}
+					styleID, err := f.GetCellStyle(sheet.sheetName, axis)
+					if err != nil {
+						return err
+					}
+					if styleID, err = sheet.file.unlockedStyle(styleID); err != nil {
+						return err
+					}
+					if err = f.SetCellStyle(sheet.sheetName, axis, axis, styleID); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return f.ProtectSheet(sheet.sheetName, sheet.protection.format())
+}
+
+// upWorkbookProtection 工作簿保护，excelize v2.5.0 没有提供设置的方法，通过反射设置
+func (file *File) upWorkbookProtection() error {
+	protection := file.workbookProtection
+	if protection == nil || file.f.WorkBook == nil {
+		return nil
+	}
+	field := reflect.ValueOf(file.f.WorkBook).Elem().FieldByName("WorkbookProtection")
+	if !field.IsValid() {
+		return nil
+	}
+	value := reflect.New(field.Type().Elem())
+	elem := value.Elem()
+	elem.FieldByName("LockStructure").SetBool(protection.LockStructure)
+	elem.FieldByName("LockWindows").SetBool(protection.LockWindows)
+	if protection.Password != "" {
+		algorithm, hash, salt, spinCount, err := util.ProtectionHash(protection.Password)
+		if err != nil {
+			return err
+		}
+		elem.FieldByName("WorkbookAlgorithmName").SetString(algorithm)
+		elem.FieldByName("WorkbookHashValue").SetString(hash)
+		elem.FieldByName("WorkbookSaltValue").SetString(salt)
+		elem.FieldByName("WorkbookSpinCount").SetInt(int64(spinCount))
+	}
+	field.Set(value)
+	return nil
+}
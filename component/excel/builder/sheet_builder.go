@@ -11,20 +11,36 @@ import (
 type sheet struct {
 	file         *File // excel文件
 	sheetName    string
-	heads        [][]interface{}       // 头信息
-	lines        map[int][]interface{} // 行信息
-	maxLine      int                   // 最大行
-	maxChar      int                   // 最大列
-	lineMap      map[int]struct{}      // 行是否被数据填充过，防止数据覆盖
-	isStyleCover bool                  // 是否样式覆盖
-	styleMap     map[string]struct{}   // cell是不是已经被修饰过，如果是，不再修饰 (TODO)
-	DataBuilder  DataBuilder           // 数据构建来源
-	headStyle    *excelize.Style       // 头部样式
-	bodyStyle    []*Style              // 整体样式
-	mergeCell    []*MergeCell          // 合并单元格
-	colWidth     []*ColWidth           // 设置单元格宽度
-	colHeight    []*ColHeight          // 设置单元格高度
-	numKeep      bool                  // 是否保持为
+	heads        [][]interface{}            // 头信息
+	lines        map[int][]interface{}      // 行信息
+	maxLine      int                        // 最大行
+	maxChar      int                        // 最大列
+	lineMap      map[int]struct{}           // 行是否被数据填充过，防止数据覆盖
+	isStyleCover bool                       // 是否样式覆盖
+	styleMap     map[string]struct{}        // cell是不是已经被修饰过，如果是，不再修饰 (TODO)
+	DataBuilder  DataBuilder                // 数据构建来源
+	streamData   StreamDataBuilder          // 流式数据来源，设置后优先于 DataBuilder
+	headStyle    *excelize.Style            // 头部样式
+	bodyStyle    []*Style                   // 整体样式
+	mergeCell    []*MergeCell               // 合并单元格
+	colWidth     []*ColWidth                // 设置单元格宽度
+	colHeight    []*ColHeight               // 设置单元格高度
+	numKeep      bool                       // 是否保持为
+	numNative    bool                       // 是否保持数字类型
+	colFormat    []*ColFormat               // 列的数字格式
+	styleRefs    map[string]*excelize.Style // 可以被 excel_style 引用的样式
+	validations  []*DataValidation          // 下拉列表
+	comments     []*Comment                 // 批注
+	hyperlinks   []*Hyperlink               // 超链接
+	images       []*Image                   // 图片
+	charts       []*Chart                   // 图表
+	protection   *SheetProtection           // 工作表保护
+	freezeColumn int                        // 冻结的列数
+	freezeLine   int                        // 冻结的行数
+	freezeHead   bool                       // 冻结表头行
+	autoFilter   *CellArea                  // 筛选区域
+	headFilter   bool                       // 在表头设置筛选
+	autoWidth    *AutoWidth                 // 自动列宽
 }
 
 // NewSheet 新建一个Sheet
@@ -73,6 +89,13 @@ func SetDataBuilder(dataBuilder DataBuilder) SheetOptionFunc {
 	}
 }
 
+// SetStreamDataBuilder 设置流式数据来源，通过 StreamWriter 逐行写入
+func SetStreamDataBuilder(dataBuilder StreamDataBuilder) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.streamData = dataBuilder
+	}
+}
+
 // SetFile 设置文件
 func SetFile(file *File) SheetOptionFunc {
 	return func(sheet *sheet) {
@@ -92,6 +115,13 @@ func (sheet *sheet) SetDataBuilder(dataBuilder DataBuilder) *sheet {
 	return sheet
 }
 
+// SetStreamDataBuilder 设置流式数据来源，设置后优先于 DataBuilder，通过 StreamWriter 逐行写入；
+// 流式写入时单元格样式在写入时设置，样式区域需要提前确定
+func (sheet *sheet) SetStreamDataBuilder(dataBuilder StreamDataBuilder) *sheet {
+	sheet.streamData = dataBuilder
+	return sheet
+}
+
 // SetFile 设置文件
 func (sheet *sheet) SetFile(file *File) *sheet {
 	sheet.file = file
@@ -128,12 +158,122 @@ func (sheet *sheet) AddColWidth(colWidth *ColWidth) *sheet {
 	return sheet
 }
 
-// SetNumKeep 设置是否保持数字
+// SetNumKeep 设置是否保持数字（数字转为文本写入，不会显示为科学计数法，但不能求和）
 func (sheet *sheet) SetNumKeep(keep bool) *sheet {
 	sheet.numKeep = keep
 	return sheet
 }
 
+// SetNumNative 设置是否保持数字类型，优先于 SetNumKeep：数字按数字写入，可以求和，
+// 超过15位有效数字的整数（如长ID）与十进制数转为文本；十进制数（如 shopspring/decimal）按文本转换，不会产生浮点误差
+func (sheet *sheet) SetNumNative(native bool) *sheet {
+	sheet.numNative = native
+	return sheet
+}
+
+// AddColFormat 设置列的数字格式，优先于结构体 excel_format 的格式
+func (sheet *sheet) AddColFormat(colFormat *ColFormat) *sheet {
+	sheet.colFormat = append(sheet.colFormat, colFormat)
+	return sheet
+}
+
+// cellValue 写入单元格的值：numNative 时保持数字类型，numKeep 时数字转为文本
+func (sheet *sheet) cellValue(value interface{}) interface{} {
+	if sheet.numNative {
+		if is, v := util.NumToNative(value); is {
+			return v
+		}
+		return value
+	}
+	if sheet.numKeep {
+		if is, s := util.NumToString(value); is {
+			return s
+		}
+	}
+	return value
+}
+
+// colStyles 每列表头以下的样式，下标为列号减1：列配置的样式与数字格式，AddColFormat 的格式优先
+func (sheet *sheet) colStyles() ([]*excelize.Style, error) {
+	columns := sheet.columns()
+	styles := make([]*excelize.Style, len(columns))
+	for i, column := range columns {
+		styles[i] = columnStyle(column, sheet.styleRef)
+	}
+	for _, colFormat := range sheet.colFormat {
+		min, err := excelize.ColumnNameToNumber(colFormat.GetHCell())
+		if err != nil {
+			return nil, err
+		}
+		max, err := excelize.ColumnNameToNumber(colFormat.GetVCell())
+		if err != nil {
+			return nil, err
+		}
+		if min > max {
+			min, max = max, min
+		}
+		for col := min; col <= max; col++ {
+			for len(styles) < col {
+				styles = append(styles, nil)
+			}
+			style := excelize.Style{}
+			if styles[col-1] != nil {
+				style = *styles[col-1]
+			}
+			format := colFormat.Format
+			style.CustomNumFmt = &format
+			styles[col-1] = &style
+		}
+	}
+	return styles, nil
+}
+
+// AddStyleRef 添加可以被结构体 excel_style 引用的样式，优先于 File 中同名的样式
+func (sheet *sheet) AddStyleRef(name string, style *excelize.Style) *sheet {
+	if sheet.styleRefs == nil {
+		sheet.styleRefs = make(map[string]*excelize.Style)
+	}
+	sheet.styleRefs[name] = style
+	return sheet
+}
+
+// styleRef 获取引用的样式
+func (sheet *sheet) styleRef(name string) *excelize.Style {
+	if style, has := sheet.styleRefs[name]; has {
+		return style
+	}
+	if sheet.file != nil {
+		return sheet.file.styleRefs[name]
+	}
+	return nil
+}
+
+// columns 数据来源提供的列配置
+func (sheet *sheet) columns() []*Column {
+	var dataBuilder interface{} = sheet.DataBuilder
+	if sheet.streamData != nil {
+		dataBuilder = sheet.streamData
+	}
+	if columnBuilder, ok := dataBuilder.(ColumnDataBuilder); ok {
+		return columnBuilder.GetColumns()
+	}
+	return nil
+}
+
+// mergeCells 需要合并的单元格，包括数据来源生成的（如多级表头）
+func (sheet *sheet) mergeCells() []*MergeCell {
+	var dataBuilder interface{} = sheet.DataBuilder
+	if sheet.streamData != nil {
+		dataBuilder = sheet.streamData
+	}
+	mergeBuilder, ok := dataBuilder.(MergeDataBuilder)
+	if !ok {
+		return sheet.mergeCell
+	}
+	merges := append([]*MergeCell{}, mergeBuilder.GetMergeCells()...)
+	return append(merges, sheet.mergeCell...)
+}
+
 // AddColHeight 设置单元格高
 func (sheet *sheet) AddColHeight(colHeight *ColHeight) *sheet {
 	sheet.colHeight = append(sheet.colHeight, colHeight)
@@ -187,27 +327,27 @@ func (sheet *sheet) exportInit() error {
 	}
 	sheet.lineMap = make(map[int]struct{})
 	// 保持数字不转为科学技术法
-	if sheet.numKeep {
+	if sheet.numKeep || sheet.numNative {
 		sheet.keepNum()
 	}
 
 	return nil
 }
 
-// keepNum 保持为数字（把数字转为string类型，输出的时候自然就是保持原数据了）
+// keepNum 保持为数字（numKeep 时把数字转为string类型，输出的时候自然就是保持原数据了；numNative 时保持数字类型）
 func (sheet *sheet) keepNum() {
 	for i := range sheet.lines {
 		for lineIndex := range sheet.lines[i] {
-			is, s := util.NumToString(sheet.lines[i][lineIndex])
-			if is {
-				sheet.lines[i][lineIndex] = s
-			}
+			sheet.lines[i][lineIndex] = sheet.cellValue(sheet.lines[i][lineIndex])
 		}
 	}
 }
 
 // Export 导出
 func (sheet *sheet) Export() error {
+	if sheet.streamData != nil {
+		return sheet.exportStream()
+	}
 	// 先进行初始化
 	err := sheet.exportInit()
 	if err != nil {
@@ -263,6 +403,14 @@ func (sheet *sheet) upStyle() error {
 	if err := sheet.upHeadStyle(); err != nil {
 		return err
 	}
+	// 自动列宽，优先级最低
+	if err := sheet.upAutoWidth(); err != nil {
+		return err
+	}
+	// 按列配置修饰，优先级比身体样式与宽度低
+	if err := sheet.upColumns(); err != nil {
+		return err
+	}
 	// 格式化身体
 	if err := sheet.upBodyStyle(); err != nil {
 		return err
@@ -279,7 +427,12 @@ func (sheet *sheet) upStyle() error {
 	if err := sheet.upMergeCell(); err != nil {
 		return err
 	}
-	return nil
+	// 下拉列表、批注、超链接、图片、图表、筛选与保护
+	if err := sheet.upFeature(); err != nil {
+		return err
+	}
+	// 冻结窗格
+	return sheet.upFreezePanes()
 }
 
 // setStyle 样式设置
@@ -300,6 +453,33 @@ func (sheet *sheet) upHeadStyle() error {
 	return sheet.setStyle(style)
 }
 
+// upColumns 按列配置设置宽度、数字格式与样式
+func (sheet *sheet) upColumns() error {
+	for i, column := range sheet.columns() {
+		if column.Width > 0 {
+			if err := setColWidth(sheet.file.f, sheet.sheetName, NewColWidthByNum(i+1, i+1, column.Width)); err != nil {
+				return err
+			}
+		}
+	}
+	if sheet.maxLine <= len(sheet.heads) {
+		return nil
+	}
+	styles, err := sheet.colStyles()
+	if err != nil {
+		return err
+	}
+	for i, style := range styles {
+		if style == nil {
+			continue
+		}
+		if err = sheet.setStyle(NewStyleByNum(i+1, len(sheet.heads)+1, i+1, sheet.maxLine, style)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // upBodyStyle 修饰行信息
 func (sheet *sheet) upBodyStyle() error {
 	for _, style := range sheet.bodyStyle {
@@ -335,7 +515,7 @@ func (sheet *sheet) upColHeight() error {
 
 // upMergeCell 修饰合并
 func (sheet *sheet) upMergeCell() error {
-	for _, merge := range sheet.mergeCell {
+	for _, merge := range sheet.mergeCells() {
 		err := mergeCell(sheet.file.f, sheet.sheetName, merge)
 		if err != nil {
 			return err
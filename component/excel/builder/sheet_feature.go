@@ -0,0 +1,369 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// DataValidation 数据验证（下拉列表），导入模板中让用户只能选择有效的枚举值
+type DataValidation struct {
+	CellArea    *CellArea
+	List        []string // 下拉选项
+	Sqref       string   // 使用本sheet的单元格区域作为下拉选项，如 $E$1:$E$3，设置后 List 不生效
+	AllowBlank  bool     // 是否允许为空，默认允许
+	ErrorTitle  string   // 输入无效值时的提示
+	ErrorMsg    string
+	PromptTitle string // 选中单元格时的提示
+	PromptMsg   string
+}
+
+// NewDataValidationByNum 新建下拉列表通过数字行列；如：(hColumn = 1, hLine = 2, vCollum = 1, vLine = 100) 对应"A2:A100"
+func NewDataValidationByNum(hColumn, hLine, vCollum, vLine int, list []string) *DataValidation {
+	return &DataValidation{
+		CellArea: &CellArea{
+			HCell: &Cell{CellNum: &CellNum{Column: hColumn, Line: hLine}},
+			VCell: &Cell{CellNum: &CellNum{Column: vCollum, Line: vLine}},
+		},
+		List:       list,
+		AllowBlank: true,
+	}
+}
+
+// NewDataValidationByChar 新建下拉列表通过字母；如：(hCell = "A2", vCell = "A100") 对应"A2:A100"
+func NewDataValidationByChar(hCell, vCell string, list []string) *DataValidation {
+	return &DataValidation{
+		CellArea: &CellArea{
+			HCell: &Cell{CellChar: &CellChar{Cell: hCell}},
+			VCell: &Cell{CellChar: &CellChar{Cell: vCell}},
+		},
+		List:       list,
+		AllowBlank: true,
+	}
+}
+
+// SetSqref 使用本sheet的单元格区域作为下拉选项，选项较多时使用（直接写入的选项总长度不能超过255）
+func (validation *DataValidation) SetSqref(sqref string) *DataValidation {
+	validation.Sqref = sqref
+	return validation
+}
+
+// SetAllowBlank 设置是否允许为空
+func (validation *DataValidation) SetAllowBlank(allowBlank bool) *DataValidation {
+	validation.AllowBlank = allowBlank
+	return validation
+}
+
+// SetError 设置输入无效值时的提示
+func (validation *DataValidation) SetError(title, msg string) *DataValidation {
+	validation.ErrorTitle = title
+	validation.ErrorMsg = msg
+	return validation
+}
+
+// SetPrompt 设置选中单元格时的提示
+func (validation *DataValidation) SetPrompt(title, msg string) *DataValidation {
+	validation.PromptTitle = title
+	validation.PromptMsg = msg
+	return validation
+}
+
+// addDataValidation 添加数据验证
+func addDataValidation(f *excelize.File, sheetName string, validation *DataValidation) error {
+	dv := excelize.NewDataValidation(validation.AllowBlank)
+	dv.SetSqref(validation.CellArea.GetHCell() + ":" + validation.CellArea.GetVCell())
+	var err error
+	if validation.Sqref != "" {
+		err = dv.SetSqrefDropList(validation.Sqref, true)
+	} else {
+		err = dv.SetDropList(validation.List)
+	}
+	if err != nil {
+		return err
+	}
+	if validation.ErrorTitle != "" || validation.ErrorMsg != "" {
+		dv.SetError(excelize.DataValidationErrorStyleStop, validation.ErrorTitle, validation.ErrorMsg)
+	}
+	if validation.PromptTitle != "" || validation.PromptMsg != "" {
+		dv.SetInput(validation.PromptTitle, validation.PromptMsg)
+	}
+	return f.AddDataValidation(sheetName, dv)
+}
+
+// Comment 单元格批注
+type Comment struct {
+	Cell   *Cell
+	Author string
+	Text   string
+}
+
+// NewCommentByNum 新建批注通过数字行列；如：(column = 1, line = 1) 对应"A1"
+func NewCommentByNum(column, line int, author, text string) *Comment {
+	return &Comment{Cell: &Cell{CellNum: &CellNum{Column: column, Line: line}}, Author: author, Text: text}
+}
+
+// NewCommentByChar 新建批注通过字母；如：(cell = "A1")
+func NewCommentByChar(cell, author, text string) *Comment {
+	return &Comment{Cell: &Cell{CellChar: &CellChar{Cell: cell}}, Author: author, Text: text}
+}
+
+// addComment 添加批注
+func addComment(f *excelize.File, sheetName string, comment *Comment) error {
+	format, err := json.Marshal(map[string]string{"author": comment.Author, "text": comment.Text})
+	if err != nil {
+		return err
+	}
+	return f.AddComment(sheetName, comment.Cell.GetCell(), string(format))
+}
+
+// Hyperlink 超链接，Link 为网址（http://、https://、mailto:）时为外部链接，否则为工作簿内的位置，如 Sheet2!A1；
+// 流式导出时单元格的内容只来自数据，Display 不会写入单元格
+type Hyperlink struct {
+	Cell    *Cell
+	Link    string
+	Display string // 单元格显示的文本，为空时不修改单元格
+	Tooltip string // 鼠标悬停时的提示
+}
+
+// NewHyperlinkByNum 新建超链接通过数字行列；如：(column = 1, line = 1) 对应"A1"
+func NewHyperlinkByNum(column, line int, link, display string) *Hyperlink {
+	return &Hyperlink{Cell: &Cell{CellNum: &CellNum{Column: column, Line: line}}, Link: link, Display: display}
+}
+
+// NewHyperlinkByChar 新建超链接通过字母；如：(cell = "A1")
+func NewHyperlinkByChar(cell, link, display string) *Hyperlink {
+	return &Hyperlink{Cell: &Cell{CellChar: &CellChar{Cell: cell}}, Link: link, Display: display}
+}
+
+// SetTooltip 设置鼠标悬停时的提示
+func (hyperlink *Hyperlink) SetTooltip(tooltip string) *Hyperlink {
+	hyperlink.Tooltip = tooltip
+	return hyperlink
+}
+
+// isExternal 是否外部链接
+func (hyperlink *Hyperlink) isExternal() bool {
+	return strings.Contains(hyperlink.Link, "://") || strings.HasPrefix(hyperlink.Link, "mailto:")
+}
+
+// addHyperlink 添加超链接，设置了显示文本且 setValue 时同时写入单元格
+func addHyperlink(f *excelize.File, sheetName string, hyperlink *Hyperlink, setValue bool) error {
+	axis := hyperlink.Cell.GetCell()
+	linkType := "Location"
+	if hyperlink.isExternal() {
+		linkType = "External"
+	}
+	opts := excelize.HyperlinkOpts{}
+	if hyperlink.Display != "" {
+		opts.Display = &hyperlink.Display
+		if setValue {
+			if err := f.SetCellStr(sheetName, axis, hyperlink.Display); err != nil {
+				return err
+			}
+		}
+	}
+	if hyperlink.Tooltip != "" {
+		opts.Tooltip = &hyperlink.Tooltip
+	}
+	return f.SetCellHyperLink(sheetName, axis, strings.TrimPrefix(hyperlink.Link, "#"), linkType, opts)
+}
+
+// Image 图片，Path 为本地文件路径；通过 SetData 设置图片内容时 Path 作为图片的名称
+type Image struct {
+	Cell      *Cell
+	Path      string
+	Data      []byte
+	Extension string // 图片内容的扩展名，如 .png
+	Format    string // excelize 的图片格式，如 {"x_scale": 0.5, "y_scale": 0.5, "positioning": "oneCell"}
+}
+
+// NewImageByNum 新建图片通过数字行列；如：(column = 1, line = 1) 对应"A1"
+func NewImageByNum(column, line int, path string) *Image {
+	return &Image{Cell: &Cell{CellNum: &CellNum{Column: column, Line: line}}, Path: path}
+}
+
+// NewImageByChar 新建图片通过字母；如：(cell = "A1")
+func NewImageByChar(cell, path string) *Image {
+	return &Image{Cell: &Cell{CellChar: &CellChar{Cell: cell}}, Path: path}
+}
+
+// SetData 设置图片内容，extension 如 .png、.jpg
+func (image *Image) SetData(extension string, data []byte) *Image {
+	image.Extension = extension
+	image.Data = data
+	return image
+}
+
+// SetFormat 设置图片格式（缩放、偏移、定位方式等）
+func (image *Image) SetFormat(format string) *Image {
+	image.Format = format
+	return image
+}
+
+// addImage 添加图片
+func addImage(f *excelize.File, sheetName string, image *Image) error {
+	if image.Data != nil {
+		return f.AddPictureFromBytes(sheetName, image.Cell.GetCell(), image.Format, image.Path, image.Extension, image.Data)
+	}
+	return f.AddPicture(sheetName, image.Cell.GetCell(), image.Path, image.Format)
+}
+
+// SetFreezePanes 冻结窗格，冻结左边 column 列与上面 line 行
+func SetFreezePanes(column, line int) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.SetFreezePanes(column, line)
+	}
+}
+
+// SetFreezeHead 冻结表头行
+func SetFreezeHead() SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.SetFreezeHead()
+	}
+}
+
+// SetAutoFilter 设置筛选的区域
+func SetAutoFilter(area *CellArea) SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.SetAutoFilter(area)
+	}
+}
+
+// SetHeadFilter 在表头的最后一行设置筛选，区域到数据的最后一行
+func SetHeadFilter() SheetOptionFunc {
+	return func(sheet *sheet) {
+		sheet.SetHeadFilter()
+	}
+}
+
+// SetFreezePanes 冻结窗格，冻结左边 column 列与上面 line 行
+func (sheet *sheet) SetFreezePanes(column, line int) *sheet {
+	sheet.freezeColumn = column
+	sheet.freezeLine = line
+	sheet.freezeHead = false
+	return sheet
+}
+
+// SetFreezeHead 冻结表头行
+func (sheet *sheet) SetFreezeHead() *sheet {
+	sheet.freezeHead = true
+	return sheet
+}
+
+// SetAutoFilter 设置筛选的区域
+func (sheet *sheet) SetAutoFilter(area *CellArea) *sheet {
+	sheet.autoFilter = area
+	return sheet
+}
+
+// SetHeadFilter 在表头的最后一行设置筛选，区域到数据的最后一行
+func (sheet *sheet) SetHeadFilter() *sheet {
+	sheet.headFilter = true
+	return sheet
+}
+
+// AddDataValidation 添加下拉列表
+func (sheet *sheet) AddDataValidation(validation *DataValidation) *sheet {
+	sheet.validations = append(sheet.validations, validation)
+	return sheet
+}
+
+// AddComment 添加批注
+func (sheet *sheet) AddComment(comment *Comment) *sheet {
+	sheet.comments = append(sheet.comments, comment)
+	return sheet
+}
+
+// AddHyperlink 添加超链接
+func (sheet *sheet) AddHyperlink(hyperlink *Hyperlink) *sheet {
+	sheet.hyperlinks = append(sheet.hyperlinks, hyperlink)
+	return sheet
+}
+
+// AddImage 添加图片
+func (sheet *sheet) AddImage(image *Image) *sheet {
+	sheet.images = append(sheet.images, image)
+	return sheet
+}
+
+// upFeature 设置下拉列表、批注、超链接、图片、图表、筛选与工作表保护，在数据写入之后处理；
+// 流式导出时需要在 Flush 之前处理，单元格已经写入 StreamWriter，不再写入超链接的显示文本
+func (sheet *sheet) upFeature() error {
+	f := sheet.file.f
+	for _, validation := range sheet.validations {
+		if err := addDataValidation(f, sheet.sheetName, validation); err != nil {
+			return err
+		}
+	}
+	for _, comment := range sheet.comments {
+		if err := addComment(f, sheet.sheetName, comment); err != nil {
+			return err
+		}
+	}
+	for _, hyperlink := range sheet.hyperlinks {
+		if err := addHyperlink(f, sheet.sheetName, hyperlink, sheet.streamData == nil); err != nil {
+			return err
+		}
+	}
+	for _, image := range sheet.images {
+		if err := addImage(f, sheet.sheetName, image); err != nil {
+			return err
+		}
+	}
+	if err := sheet.upChart(); err != nil {
+		return err
+	}
+	if err := sheet.upAutoFilter(); err != nil {
+		return err
+	}
+	return sheet.upProtection()
+}
+
+// upFreezePanes 冻结窗格，流式导出时需要在创建 StreamWriter 之前处理
+func (sheet *sheet) upFreezePanes() error {
+	column, line := sheet.freezeColumn, sheet.freezeLine
+	if sheet.freezeHead {
+		column, line = 0, len(sheet.heads)
+	}
+	if column <= 0 && line <= 0 {
+		return nil
+	}
+	topLeftCell, err := excelize.CoordinatesToCellName(column+1, line+1)
+	if err != nil {
+		return err
+	}
+	activePane := "bottomRight"
+	if column == 0 {
+		activePane = "bottomLeft"
+	} else if line == 0 {
+		activePane = "topRight"
+	}
+	panes := fmt.Sprintf(`{"freeze":true,"split":false,"x_split":%d,"y_split":%d,"top_left_cell":"%s","active_pane":"%s","panes":[{"sqref":"%s","active_cell":"%s","pane":"%s"}]}`,
+		column, line, topLeftCell, activePane, topLeftCell, topLeftCell, activePane)
+	return sheet.file.f.SetPanes(sheet.sheetName, panes)
+}
+
+// upAutoFilter 筛选
+func (sheet *sheet) upAutoFilter() error {
+	if sheet.autoFilter != nil {
+		return sheet.file.f.AutoFilter(sheet.sheetName, sheet.autoFilter.GetHCell(), sheet.autoFilter.GetVCell(), "")
+	}
+	if !sheet.headFilter || len(sheet.heads) == 0 {
+		return nil
+	}
+	maxLine := sheet.maxLine
+	if maxLine < len(sheet.heads) {
+		maxLine = len(sheet.heads)
+	}
+	hCell, err := excelize.CoordinatesToCellName(1, len(sheet.heads))
+	if err != nil {
+		return err
+	}
+	vCell, err := excelize.CoordinatesToCellName(sheet.maxChar, maxLine)
+	if err != nil {
+		return err
+	}
+	return sheet.file.f.AutoFilter(sheet.sheetName, hCell, vCell, "")
+}
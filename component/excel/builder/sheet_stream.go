@@ -0,0 +1,266 @@
+package builder
+
+import (
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// streamStyle 流式写入时的样式区域
+type streamStyle struct {
+	hCol, hLine, vCol, vLine int
+	styleID                  int
+}
+
+// contains 单元格是否在区域内
+func (style *streamStyle) contains(col, line int) bool {
+	return col >= style.hCol && col <= style.vCol && line >= style.hLine && line <= style.vLine
+}
+
+// newStreamStyle 创建样式并解析区域
+func newStreamStyle(f *excelize.File, style *Style) (*streamStyle, error) {
+	styleID, err := f.NewStyle(style.Style)
+	if err != nil {
+		return nil, err
+	}
+	hCol, hLine, vCol, vLine, err := areaBounds(style.GetHCell(), style.GetVCell())
+	if err != nil {
+		return nil, err
+	}
+	return &streamStyle{hCol: hCol, hLine: hLine, vCol: vCol, vLine: vLine, styleID: styleID}, nil
+}
+
+// areaBounds 区域的行列范围，只设置了一个单元格时按单个单元格处理
+func areaBounds(hCell, vCell string) (hCol, hLine, vCol, vLine int, err error) {
+	if hCell == "" {
+		hCell = vCell
+	}
+	if vCell == "" {
+		vCell = hCell
+	}
+	if hCol, hLine, err = excelize.CellNameToCoordinates(hCell); err != nil {
+		return
+	}
+	if vCol, vLine, err = excelize.CellNameToCoordinates(vCell); err != nil {
+		return
+	}
+	if hCol > vCol {
+		hCol, vCol = vCol, hCol
+	}
+	if hLine > vLine {
+		hLine, vLine = vLine, hLine
+	}
+	return
+}
+
+// exportStream 通过 StreamWriter 逐行写入：头部样式与身体样式在写入单元格时设置，
+// 宽度在写入前设置，高度通过行属性设置，合并在写入后设置；
+// 冻结窗格在创建 StreamWriter 之前设置，下拉列表、批注、超链接、图片、图表、筛选与保护在 Flush 之前设置
+func (sheet *sheet) exportStream() (err error) {
+	if closer, ok := sheet.streamData.(io.Closer); ok {
+		defer func() {
+			if closeErr := closer.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+	}
+	if sheet.sheetName == "" {
+		sheet.SetSheetName("Sheet1")
+	}
+	if sheet.file == nil {
+		sheet.SetFile(NewFile().SetExcel(excelize.NewFile()))
+	}
+	f := sheet.file.f
+
+	sheetIndex := f.NewSheet(sheet.sheetName) // 创建工作簿
+	f.SetActiveSheet(sheetIndex)              // 设置激活的工作簿
+	sheet.heads = sheet.streamData.GetHeads()
+	if err = sheet.upFreezePanes(); err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter(sheet.sheetName)
+	if err != nil {
+		return err
+	}
+
+	// 自动列宽按表头与采样的行计算，采样的行先缓存
+	samples := make([][]interface{}, 0)
+	eof := false
+	widths := make(map[int]float64)
+	if autoWidth := sheet.getAutoWidth(); autoWidth != nil {
+		meter, err := sheet.newWidthMeter()
+		if err != nil {
+			return err
+		}
+		for i, head := range sheet.heads {
+			meter.measure(i+1, head, true)
+		}
+		for len(samples) < autoWidth.Sample {
+			line, err := sheet.streamData.Next()
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+			meter.measure(len(sheet.heads)+len(samples)+1, line, false)
+			samples = append(samples, line)
+		}
+		for i, w := range meter.widths(autoWidth) {
+			widths[i+1] = w
+		}
+	}
+
+	// 宽度需要在写入行之前设置，优先级：AddColWidth > 列配置 > 自动列宽；同一列只能设置一次
+	for i, column := range sheet.columns() {
+		if column.Width > 0 {
+			widths[i+1] = column.Width
+		}
+	}
+	colStyles, err := sheet.colStyles()
+	if err != nil {
+		return err
+	}
+	colStyleIDs := make([]int, len(colStyles))
+	for i, style := range colStyles {
+		if style == nil {
+			continue
+		}
+		if colStyleIDs[i], err = f.NewStyle(style); err != nil {
+			return err
+		}
+	}
+	for _, colWidth := range sheet.colWidth {
+		min, err := excelize.ColumnNameToNumber(colWidth.GetHCell())
+		if err != nil {
+			return err
+		}
+		max, err := excelize.ColumnNameToNumber(colWidth.GetVCell())
+		if err != nil {
+			return err
+		}
+		for col := min; col <= max; col++ {
+			widths[col] = colWidth.Width
+		}
+	}
+	cols := make([]int, 0, len(widths))
+	for col := range widths {
+		cols = append(cols, col)
+	}
+	sort.Ints(cols)
+	for _, col := range cols {
+		if err = sw.SetColWidth(col, col, widths[col]); err != nil {
+			return err
+		}
+	}
+	heights := make(map[int]float64, len(sheet.colHeight))
+	for _, colHeight := range sheet.colHeight {
+		heights[colHeight.Line] = colHeight.Height
+	}
+
+	// 头部样式
+	headStyleID := 0
+	headStyle := sheet.headStyle
+	if headStyle == nil && sheet.file != nil {
+		headStyle = sheet.file.headStyle
+	}
+	if headStyle != nil {
+		if headStyleID, err = f.NewStyle(headStyle); err != nil {
+			return err
+		}
+	}
+	// 身体样式，后添加的优先
+	bodyStyles := make([]*streamStyle, 0, len(sheet.bodyStyle))
+	for _, style := range sheet.bodyStyle {
+		bodyStyle, err := newStreamStyle(f, style)
+		if err != nil {
+			return err
+		}
+		bodyStyles = append(bodyStyles, bodyStyle)
+	}
+
+	// 工作表保护中可以编辑的区域
+	unlocked, err := sheet.unlockedAreas()
+	if err != nil {
+		return err
+	}
+
+	lineI := 0
+	writeLine := func(line []interface{}, isHead bool) error {
+		lineI++
+		cells := make([]interface{}, len(line))
+		for i := range line {
+			value := sheet.cellValue(line[i])
+			cellStyleID := headStyleID
+			if !isHead {
+				cellStyleID = 0
+				if i < len(colStyleIDs) {
+					cellStyleID = colStyleIDs[i]
+				}
+			}
+			for j := len(bodyStyles) - 1; j >= 0; j-- {
+				if bodyStyles[j].contains(i+1, lineI) {
+					cellStyleID = bodyStyles[j].styleID
+					break
+				}
+			}
+			for _, area := range unlocked {
+				if area.contains(i+1, lineI) {
+					unlockedID, err := sheet.file.unlockedStyle(cellStyleID)
+					if err != nil {
+						return err
+					}
+					cellStyleID = unlockedID
+					break
+				}
+			}
+			cells[i] = excelize.Cell{StyleID: cellStyleID, Value: value}
+		}
+		opts := make([]excelize.RowOpts, 0, 1)
+		if height, has := heights[lineI]; has {
+			opts = append(opts, excelize.RowOpts{Height: height})
+		}
+		sheet.incMaxChar(len(line))
+		sheet.incMaxLine(lineI)
+		return sw.SetRow(util.ToLine(1)+strconv.Itoa(lineI), cells, opts...)
+	}
+
+	// 写入头信息
+	for _, head := range sheet.heads {
+		if err = writeLine(head, true); err != nil {
+			return err
+		}
+	}
+	// 逐行写入
+	for _, line := range samples {
+		if err = writeLine(line, false); err != nil {
+			return err
+		}
+	}
+	for !eof {
+		line, err := sheet.streamData.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = writeLine(line, false); err != nil {
+			return err
+		}
+	}
+
+	for _, merge := range sheet.mergeCells() {
+		if err = sw.MergeCell(merge.GetHCell(), merge.GetVCell()); err != nil {
+			return err
+		}
+	}
+	if err = sheet.upFeature(); err != nil {
+		return err
+	}
+	return sw.Flush()
+}
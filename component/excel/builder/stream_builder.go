@@ -0,0 +1,81 @@
+package builder
+
+import (
+	"io"
+	"reflect"
+)
+
+// StreamDataBuilder 迭代方式的数据来源，行数据逐行读取写入，不会全部加载到内存，适合大数据量导出；
+// 实现了 io.Closer 时导出结束后会调用 Close
+type StreamDataBuilder interface {
+	GetHeads() [][]interface{}    // 获取头部数据
+	Next() ([]interface{}, error) // 获取下一行数据，没有数据时返回 io.EOF
+}
+
+var _ StreamDataBuilder = new(FuncDataBuilder)
+var _ StreamDataBuilder = new(CursorDataBuilder)
+var _ ColumnDataBuilder = new(FuncDataBuilder)
+var _ ColumnDataBuilder = new(CursorDataBuilder)
+var _ MergeDataBuilder = new(FuncDataBuilder)
+var _ MergeDataBuilder = new(CursorDataBuilder)
+
+// FuncDataBuilder 通过方法逐行获取数据
+type FuncDataBuilder struct {
+	heads     [][]interface{}
+	next      func() ([]interface{}, error)
+	dataType  reflect.Type // 结构体类型，用于生成列配置
+	mergeCell []*MergeCell // 多级表头的合并
+}
+
+// NewFuncDataBuilder next 返回下一行数据，没有数据时返回 io.EOF
+func NewFuncDataBuilder(next func() ([]interface{}, error)) *FuncDataBuilder {
+	return &FuncDataBuilder{next: next}
+}
+
+// AddHead 添加头信息
+func (dataBuilder *FuncDataBuilder) AddHead(head []interface{}) *FuncDataBuilder {
+	dataBuilder.heads = append(dataBuilder.heads, head)
+	return dataBuilder
+}
+
+// AddHeadByStruct 通过结构体添加头信息
+func (dataBuilder *FuncDataBuilder) AddHeadByStruct(s interface{}) *FuncDataBuilder {
+	if t := structType(s); t != nil {
+		dataBuilder.dataType = t
+	}
+	var merges []*MergeCell
+	dataBuilder.heads, merges = appendStructHead(dataBuilder.heads, s)
+	dataBuilder.mergeCell = append(dataBuilder.mergeCell, merges...)
+	return dataBuilder
+}
+
+// AddHeads 替换头信息，同时去掉按结构体生成的表头合并
+func (dataBuilder *FuncDataBuilder) AddHeads(heads [][]interface{}) *FuncDataBuilder {
+	dataBuilder.heads = heads
+	dataBuilder.mergeCell = nil
+	return dataBuilder
+}
+
+func (dataBuilder *FuncDataBuilder) GetHeads() [][]interface{} {
+	return dataBuilder.heads
+}
+
+// GetColumns 按 AddHeadByStruct 的结构体获取列配置
+func (dataBuilder *FuncDataBuilder) GetColumns() []*Column {
+	if dataBuilder.dataType == nil {
+		return nil
+	}
+	return structColumns(dataBuilder.dataType)
+}
+
+// GetMergeCells 多级表头需要合并的单元格
+func (dataBuilder *FuncDataBuilder) GetMergeCells() []*MergeCell {
+	return dataBuilder.mergeCell
+}
+
+func (dataBuilder *FuncDataBuilder) Next() ([]interface{}, error) {
+	if dataBuilder.next == nil {
+		return nil, io.EOF
+	}
+	return dataBuilder.next()
+}
@@ -0,0 +1,366 @@
+package builder
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// ExcelOrderTag 列的顺序，从小到大排列，没有设置的列按字段顺序排在后面，如 excel_order:"1"
+	ExcelOrderTag = "excel_order"
+	// ExcelWidthTag 列宽，如 excel_width:"20"
+	ExcelWidthTag = "excel_width"
+	// ExcelDefaultTag 零值时输出的内容，如 excel_default:"-"
+	ExcelDefaultTag = "excel_default"
+	// ExcelStyleTag 引用通过 AddStyleRef 添加的样式，如 excel_style:"money"
+	ExcelStyleTag = "excel_style"
+	// ExcelSepTag 基础类型切片的分隔符，默认为 DefaultSep，如 excel_sep:"|"
+	ExcelSepTag = "excel_sep"
+)
+
+// DefaultSep 基础类型切片默认的分隔符
+const DefaultSep = ","
+
+// DefaultTimeFormat 时间字段没有设置 excel_format 时使用的格式
+const DefaultTimeFormat = "yyyy-mm-dd hh:mm:ss"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Column 列的配置，由结构体的tag生成
+type Column struct {
+	Head     string  // 表头
+	Width    float64 // 列宽，0为不设置
+	Format   string  // Excel 数字格式，如 yyyy-mm-dd、0.00、#,##0
+	StyleRef string  // 引用的样式名
+}
+
+// ColumnDataBuilder 提供列配置的数据来源，导出时按列设置宽度、数字格式与样式
+type ColumnDataBuilder interface {
+	GetColumns() []*Column
+}
+
+// MergeDataBuilder 提供合并单元格的数据来源，如多级表头，导出时自动合并
+type MergeDataBuilder interface {
+	GetMergeCells() []*MergeCell
+}
+
+// structField 结构体字段的excel配置
+type structField struct {
+	index        []int
+	order        int
+	column       *Column
+	heads        []string          // 各级表头，最后一个为本列的表头
+	groups       []int             // 各级父表头的编号，用于区分同名的分组
+	enum         map[string]string // 值 -> 展示文本
+	defaultValue string
+	sep          string // 基础类型的切片用分隔符连接
+}
+
+// structFieldsCache 结构体类型 -> []*structField
+var structFieldsCache sync.Map
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// parseStructFields 解析结构体中需要excel处理的字段：嵌套的结构体展开为多列，
+// 设置了 excel_head 的嵌套结构体作为上一级表头；同一级按 excel_order 排序
+func parseStructFields(t reflect.Type) []*structField {
+	if fields, has := structFieldsCache.Load(t); has {
+		return fields.([]*structField)
+	}
+	groupID := 0
+	fields := parseStructLevel(t, nil, nil, nil, &groupID)
+	structFieldsCache.Store(t, fields)
+	return fields
+}
+
+// parseStructLevel 解析一级结构体
+func parseStructLevel(t reflect.Type, index []int, heads []string, groups []int, groupID *int) []*structField {
+	type entry struct {
+		order  int
+		fields []*structField
+	}
+	entries := make([]*entry, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag
+		fieldType := indirectType(field.Type)
+		// 去掉不需要excel处理的字段；未导出的字段只展开嵌入的结构体
+		if isExclude(tag) || (field.PkgPath != "" && !(field.Anonymous && isNestedStruct(fieldType))) {
+			continue
+		}
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+		head := tag.Get(ExcelHeadTag)
+		order := -1
+		if o, err := strconv.Atoi(tag.Get(ExcelOrderTag)); err == nil {
+			order = o
+		}
+
+		if isNestedStruct(fieldType) {
+			childHeads, childGroups := heads, groups
+			if head != "" {
+				*groupID++
+				childHeads = append(append(make([]string, 0, len(heads)+1), heads...), head)
+				childGroups = append(append(make([]int, 0, len(groups)+1), groups...), *groupID)
+			}
+			children := parseStructLevel(fieldType, fieldIndex, childHeads, childGroups, groupID)
+			entries = append(entries, &entry{order: order, fields: children})
+			continue
+		}
+
+		f := &structField{
+			index:        fieldIndex,
+			order:        order,
+			heads:        append(append(make([]string, 0, len(heads)+1), heads...), head),
+			groups:       groups,
+			defaultValue: tag.Get(ExcelDefaultTag),
+			column: &Column{
+				Head:     head,
+				Format:   tag.Get(ExcelFormatTag),
+				StyleRef: tag.Get(ExcelStyleTag),
+			},
+		}
+		if width, err := strconv.ParseFloat(tag.Get(ExcelWidthTag), 64); err == nil {
+			f.column.Width = width
+		}
+		if f.column.Format == "" && fieldType == timeType {
+			f.column.Format = DefaultTimeFormat
+		}
+		f.enum = parseEnum(tag.Get(ExcelMapTag))
+		if isPrimitiveSlice(fieldType) {
+			f.sep = tag.Get(ExcelSepTag)
+			if f.sep == "" {
+				f.sep = DefaultSep
+			}
+		}
+		entries = append(entries, &entry{order: order, fields: []*structField{f}})
+	}
+	// 有 order 的在前并按 order 排序，没有的保持字段顺序
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].order < 0 || entries[j].order < 0 {
+			return entries[i].order >= 0 && entries[j].order < 0
+		}
+		return entries[i].order < entries[j].order
+	})
+	fields := make([]*structField, 0, len(entries))
+	for _, e := range entries {
+		fields = append(fields, e.fields...)
+	}
+	return fields
+}
+
+// isNestedStruct 需要展开的嵌套结构体，time.Time 与实现了 fmt.Stringer 的结构体作为一个单元格
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType &&
+		!t.Implements(stringerType) && !reflect.PtrTo(t).Implements(stringerType)
+}
+
+// isPrimitiveSlice 基础类型的切片（[]byte 除外）
+func isPrimitiveSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return false
+	}
+	elem := indirectType(t.Elem())
+	switch elem.Kind() {
+	case reflect.Uint8:
+		return elem.Name() != "uint8" && elem.Name() != "byte"
+	case reflect.Struct:
+		return elem == timeType || !isNestedStruct(elem)
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return false
+	}
+	return true
+}
+
+// structHeads 结构体对应的表头，有嵌套分组时为多行；offset 为表头开始前已有的行数，
+// 返回分组表头横向合并与没有分组的表头纵向合并的单元格
+func structHeads(t reflect.Type, offset int) ([][]interface{}, []*MergeCell) {
+	fields := parseStructFields(t)
+	depth := 0
+	for _, field := range fields {
+		if len(field.heads) > depth {
+			depth = len(field.heads)
+		}
+	}
+	if depth == 0 {
+		return nil, nil
+	}
+	heads := make([][]interface{}, depth)
+	for r := range heads {
+		heads[r] = make([]interface{}, len(fields))
+		for c := range heads[r] {
+			heads[r][c] = ""
+		}
+	}
+	merges := make([]*MergeCell, 0)
+	for c, field := range fields {
+		for r, head := range field.heads {
+			// 分组表头只写在分组的第一列
+			if r < len(field.groups) && c > 0 && r < len(fields[c-1].groups) && fields[c-1].groups[r] == field.groups[r] {
+				continue
+			}
+			heads[r][c] = head
+		}
+		if level := len(field.heads); level < depth {
+			merges = append(merges, NewMergeCellByNum(c+1, offset+level, c+1, offset+depth))
+		}
+	}
+	// 同一分组的相邻列横向合并
+	for r := 0; r < depth-1; r++ {
+		for c := 0; c < len(fields); {
+			end := c
+			if r < len(fields[c].groups) {
+				for end+1 < len(fields) && r < len(fields[end+1].groups) &&
+					fields[end+1].groups[r] == fields[c].groups[r] {
+					end++
+				}
+			}
+			if end > c {
+				merges = append(merges, NewMergeCellByNum(c+1, offset+r+1, end+1, offset+r+1))
+			}
+			c = end + 1
+		}
+	}
+	return heads, merges
+}
+
+// parseEnum 解析 excel_map，格式为 值:文本，多个用逗号分隔
+func parseEnum(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	enum := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		enum[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return enum
+}
+
+// indirectType 指针的元素类型
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structType 获取数据对应的结构体类型，不是结构体时返回 nil
+func structType(s interface{}) reflect.Type {
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return nil
+	}
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// structColumns 结构体对应的列配置
+func structColumns(t reflect.Type) []*Column {
+	fields := parseStructFields(t)
+	columns := make([]*Column, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, field.column)
+	}
+	return columns
+}
+
+// value 字段的输出值：指针取值，枚举转为文本，切片用分隔符连接，零值使用默认值；root 为整个结构体
+func (field *structField) value(root reflect.Value) interface{} {
+	v := root
+	for _, i := range field.index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return field.zeroValue()
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return field.zeroValue()
+		}
+		v = v.Elem()
+	}
+	if field.sep != "" && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) {
+		if v.Len() == 0 {
+			return field.zeroValue()
+		}
+		parts := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			parts = append(parts, field.elemText(v.Index(i)))
+		}
+		return strings.Join(parts, field.sep)
+	}
+	if field.enum != nil {
+		if label, has := field.enum[fmt.Sprint(v.Interface())]; has {
+			return label
+		}
+	}
+	if v.IsZero() && field.defaultValue != "" {
+		return field.defaultValue
+	}
+	if v.Type() == timeType && v.IsZero() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// elemText 切片元素的文本
+func (field *structField) elemText(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	text := fmt.Sprint(v.Interface())
+	if t, ok := v.Interface().(time.Time); ok {
+		text = t.Format(CsvTimeLayout)
+	}
+	if label, has := field.enum[text]; has {
+		return label
+	}
+	return text
+}
+
+func (field *structField) zeroValue() interface{} {
+	if field.defaultValue != "" {
+		return field.defaultValue
+	}
+	return nil
+}
+
+// columnStyle 列的样式：引用的样式加上数字格式，都没有时返回 nil
+func columnStyle(column *Column, styleRef func(name string) *excelize.Style) *excelize.Style {
+	var style excelize.Style
+	has := false
+	if column.StyleRef != "" && styleRef != nil {
+		if ref := styleRef(column.StyleRef); ref != nil {
+			style = *ref
+			has = true
+		}
+	}
+	if column.Format != "" {
+		format := column.Format
+		style.CustomNumFmt = &format
+		has = true
+	}
+	if !has {
+		return nil
+	}
+	return &style
+}
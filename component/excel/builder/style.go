@@ -211,6 +211,59 @@ func setColWidth(f *excelize.File, sheetName string, colWidth *ColWidth) error {
 	return f.SetColWidth(sheetName, colWidth.GetHCell(), colWidth.GetVCell(), colWidth.Width)
 }
 
+// 常用的数字格式
+const (
+	NumFmtThousands         = "#,##0"    // 千分位
+	NumFmtThousandsDecimal2 = "#,##0.00" // 千分位，两位小数
+	NumFmtDecimal2          = "0.00"     // 两位小数
+	NumFmtPercent           = "0%"       // 百分比
+	NumFmtPercentDecimal2   = "0.00%"    // 百分比，两位小数
+	NumFmtText              = "@"        // 文本
+)
+
+// ColFormat 列的数字格式，作用于表头以下的行
+type ColFormat struct {
+	StartColumn     int    // 开始第几列（从1开始算）
+	StartColumnChar string // 开始第几列（从"A"开始算）
+	EndColumn       int    // 结束第几列（从1开始算）
+	EndColumnChar   string // 结束第几列（从"A"开始算）
+	Format          string // Excel 数字格式，如 NumFmtThousandsDecimal2
+}
+
+func (colFormat *ColFormat) GetHCell() string {
+	if colFormat.StartColumn > 0 {
+		return util.ToLine(colFormat.StartColumn)
+	}
+
+	return colFormat.StartColumnChar
+}
+
+func (colFormat *ColFormat) GetVCell() string {
+	if colFormat.EndColumn > 0 {
+		return util.ToLine(colFormat.EndColumn)
+	}
+
+	return colFormat.EndColumnChar
+}
+
+// NewColFormatByNum 新建列的数字格式通过数字行列；如：(startCol = 1, endCol = 3) 对应"A~C"
+func NewColFormatByNum(startCol, endCol int, format string) *ColFormat {
+	return &ColFormat{
+		StartColumn: startCol,
+		EndColumn:   endCol,
+		Format:      format,
+	}
+}
+
+// NewColFormatByChar 新建列的数字格式通过字母；如：(startCol = "A", endCol = "C") 对应"A~C"
+func NewColFormatByChar(startCol, endCol string, format string) *ColFormat {
+	return &ColFormat{
+		StartColumnChar: startCol,
+		EndColumnChar:   endCol,
+		Format:          format,
+	}
+}
+
 // ColHeight 单元格高度
 type ColHeight struct {
 	Line   int
@@ -0,0 +1,560 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	// templateRangeRe 重复行区域的开始标记，如 {{range .Items}}
+	templateRangeRe = regexp.MustCompile(`\{\{-?\s*range\s+(\.[\w.]*)\s*-?\}\}`)
+	// templateEndRe 重复行区域的结束标记
+	templateEndRe = regexp.MustCompile(`\{\{-?\s*end\s*-?\}\}`)
+	// templateFieldRe 整个单元格只有一个字段占位符，如 {{.Amount}}，数字、bool 与时间保持原类型
+	templateFieldRe = regexp.MustCompile(`^\{\{-?\s*(\.[\w.]*)\s*-?\}\}$`)
+	// formulaRefRe 公式中的单元格引用或区域，如 A1、$B$2、C3:D4
+	formulaRefRe = regexp.MustCompile(`(\$?)([A-Z]{1,3})(\$?)(\d+)(?::(\$?)([A-Z]{1,3})(\$?)(\d+))?`)
+)
+
+// Template 模板导出：单元格中的 {{.Title}} 等占位符按 text/template 填充；
+// 以 {{range .Items}} 开始、{{end}} 结束的行区域按 Items 的每个元素重复，区域内 . 为当前元素，
+// 复制样式、行高与区域内的合并单元格，公式中的相对引用按行偏移，区域下方的行、合并单元格与引用同步下移，
+// 引用整个区域的公式（如 SUM(C3:C3)）扩展到所有重复的行。
+// 注意：excelize 不会移动区域下方的图片，图片请放在区域上方
+type Template struct {
+	f      *excelize.File
+	data   interface{}
+	funcs  template.FuncMap
+	sheets []string
+	cache  map[string]*template.Template
+}
+
+// templateRange 重复行区域
+type templateRange struct {
+	start, end int
+	path       string
+}
+
+// templateCell 重复行区域中的单元格
+type templateCell struct {
+	row, col int
+	text     string
+	formula  string
+	style    int
+	cellType excelize.CellType
+}
+
+// formulaCell 区域外的公式单元格，行区域重复后更新位置与引用
+type formulaCell struct {
+	row, col int
+	formula  string
+	changed  bool
+}
+
+// NewTemplate 使用已经打开的工作簿作为模板
+func NewTemplate(f *excelize.File) *Template {
+	return &Template{f: f, funcs: template.FuncMap{}, cache: make(map[string]*template.Template)}
+}
+
+// OpenTemplate 打开本地模板文件
+func OpenTemplate(fileName string, opts ...excelize.Options) (*Template, error) {
+	f, err := excelize.OpenFile(fileName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(f), nil
+}
+
+// OpenTemplateReader 读取模板
+func OpenTemplateReader(r io.Reader, opts ...excelize.Options) (*Template, error) {
+	f, err := excelize.OpenReader(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewTemplate(f), nil
+}
+
+// SetData 设置填充的数据，结构体或者 map[string]interface{}
+func (t *Template) SetData(data interface{}) *Template {
+	t.data = data
+	return t
+}
+
+// AddFunc 添加模板中可以使用的方法，如 {{money .Amount}}
+func (t *Template) AddFunc(name string, fn interface{}) *Template {
+	t.funcs[name] = fn
+	return t
+}
+
+// SetSheets 只处理指定的sheet，默认处理所有sheet
+func (t *Template) SetSheets(sheets ...string) *Template {
+	t.sheets = sheets
+	return t
+}
+
+// Excel 模板对应的工作簿
+func (t *Template) Excel() *excelize.File {
+	return t.f
+}
+
+// Execute 填充模板
+func (t *Template) Execute() (*excelize.File, error) {
+	sheets := t.sheets
+	if len(sheets) == 0 {
+		sheets = t.f.GetSheetList()
+	}
+	for _, sheetName := range sheets {
+		if err := t.executeSheet(sheetName); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheetName, err)
+		}
+	}
+	t.fullCalcOnLoad()
+	return t.f, nil
+}
+
+// executeSheet 先从下往上展开重复行区域，再填充其余的占位符
+func (t *Template) executeSheet(sheetName string) error {
+	rows, err := t.f.GetRows(sheetName)
+	if err != nil {
+		return err
+	}
+	ranges, err := findTemplateRanges(rows)
+	if err != nil {
+		return err
+	}
+	formulas, err := t.sheetFormulas(sheetName, rows)
+	if err != nil {
+		return err
+	}
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if formulas, err = t.executeRange(sheetName, rows, ranges[i], formulas); err != nil {
+			return err
+		}
+	}
+	for _, cell := range formulas {
+		if !cell.changed {
+			continue
+		}
+		axis, err := excelize.CoordinatesToCellName(cell.col, cell.row)
+		if err != nil {
+			return err
+		}
+		if err = t.f.SetCellFormula(sheetName, axis, cell.formula); err != nil {
+			return err
+		}
+	}
+
+	// 区域外的占位符
+	if rows, err = t.f.GetRows(sheetName); err != nil {
+		return err
+	}
+	for r := range rows {
+		for c := range rows[r] {
+			if !strings.Contains(rows[r][c], "{{") {
+				continue
+			}
+			axis, err := excelize.CoordinatesToCellName(c+1, r+1)
+			if err != nil {
+				return err
+			}
+			if err = t.setCell(sheetName, axis, rows[r][c], t.data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findTemplateRanges 查找重复行区域，不支持嵌套
+func findTemplateRanges(rows [][]string) ([]*templateRange, error) {
+	ranges := make([]*templateRange, 0)
+	var current *templateRange
+	for r := range rows {
+		for c := range rows[r] {
+			if match := templateRangeRe.FindStringSubmatch(rows[r][c]); match != nil {
+				if current != nil {
+					return nil, error2.TemplateRangeErr
+				}
+				current = &templateRange{start: r + 1, path: match[1]}
+			}
+		}
+		for c := range rows[r] {
+			if current != nil && templateEndRe.MatchString(rows[r][c]) {
+				current.end = r + 1
+				ranges = append(ranges, current)
+				current = nil
+			}
+		}
+	}
+	if current != nil {
+		return nil, error2.TemplateRangeErr
+	}
+	return ranges, nil
+}
+
+// sheetFormulas 模板中所有的公式
+func (t *Template) sheetFormulas(sheetName string, rows [][]string) ([]*formulaCell, error) {
+	formulas := make([]*formulaCell, 0)
+	for r := range rows {
+		for c := range rows[r] {
+			axis, err := excelize.CoordinatesToCellName(c+1, r+1)
+			if err != nil {
+				return nil, err
+			}
+			formula, err := t.f.GetCellFormula(sheetName, axis)
+			if err != nil {
+				return nil, err
+			}
+			if formula != "" {
+				formulas = append(formulas, &formulaCell{row: r + 1, col: c + 1, formula: formula})
+			}
+		}
+	}
+	return formulas, nil
+}
+
+// executeRange 展开一个重复行区域，返回区域外的公式与展开后区域内的公式，
+// 上方的区域展开时继续调整它们的位置与引用；复制出的公式由 executeSheet 统一写入
+func (t *Template) executeRange(sheetName string, rows [][]string, tr *templateRange, formulas []*formulaCell) ([]*formulaCell, error) {
+	items, err := lookupSlice(t.data, tr.path)
+	if err != nil {
+		return nil, err
+	}
+	height := tr.end - tr.start + 1
+	delta := (len(items) - 1) * height
+	if len(items) == 0 {
+		delta = -height
+	}
+
+	// 记录区域内的单元格、公式、行高与合并单元格
+	blockFormulas := make(map[[2]int]string)
+	outside := make([]*formulaCell, 0, len(formulas))
+	for _, cell := range formulas {
+		if cell.row >= tr.start && cell.row <= tr.end {
+			blockFormulas[[2]int{cell.row, cell.col}] = cell.formula
+			continue
+		}
+		outside = append(outside, cell)
+	}
+	cells := make([]*templateCell, 0)
+	heights := make(map[int]float64, height)
+	for r := tr.start; r <= tr.end; r++ {
+		if heights[r], err = t.f.GetRowHeight(sheetName, r); err != nil {
+			return nil, err
+		}
+		if r-1 >= len(rows) {
+			continue
+		}
+		for c := range rows[r-1] {
+			axis, err := excelize.CoordinatesToCellName(c+1, r)
+			if err != nil {
+				return nil, err
+			}
+			cell := &templateCell{row: r, col: c + 1, formula: blockFormulas[[2]int{r, c + 1}]}
+			cell.text = templateEndRe.ReplaceAllString(templateRangeRe.ReplaceAllString(rows[r-1][c], ""), "")
+			if cell.style, err = t.f.GetCellStyle(sheetName, axis); err != nil {
+				return nil, err
+			}
+			if cell.text != "" && cell.formula == "" && !strings.Contains(cell.text, "{{") {
+				// 常量保持原来的类型
+				if cell.cellType, err = t.f.GetCellType(sheetName, axis); err != nil {
+					return nil, err
+				}
+				if cell.text, err = t.f.GetCellValue(sheetName, axis, excelize.Options{RawCellValue: true}); err != nil {
+					return nil, err
+				}
+			}
+			cells = append(cells, cell)
+		}
+	}
+	mergeCells, err := t.f.GetMergeCells(sheetName)
+	if err != nil {
+		return nil, err
+	}
+	blockMerges := make([][4]int, 0)
+	for _, merge := range mergeCells {
+		hCol, hRow, err := excelize.CellNameToCoordinates(merge.GetStartAxis())
+		if err != nil {
+			return nil, err
+		}
+		vCol, vRow, err := excelize.CellNameToCoordinates(merge.GetEndAxis())
+		if err != nil {
+			return nil, err
+		}
+		if hRow >= tr.start && vRow <= tr.end {
+			blockMerges = append(blockMerges, [4]int{hCol, hRow, vCol, vRow})
+		}
+	}
+
+	// 插入或者删除行，区域下方的行与合并单元格由 excelize 下移
+	for i := 0; i < delta; i++ {
+		if err = t.f.InsertRow(sheetName, tr.end+1); err != nil {
+			return nil, err
+		}
+	}
+	for i := 0; i < -delta; i++ {
+		if err = t.f.RemoveRow(sheetName, tr.start); err != nil {
+			return nil, err
+		}
+	}
+	if delta != 0 {
+		for _, cell := range outside {
+			if cell.row > tr.end {
+				cell.row += delta
+				cell.changed = true
+			}
+			formula := shiftFormula(cell.formula, func(row, rowEnd int, isEnd bool) int {
+				return insertShift(row, rowEnd, isEnd, tr.start, tr.end, delta)
+			})
+			if formula != cell.formula {
+				cell.formula = formula
+				cell.changed = true
+			}
+		}
+	}
+
+	// 逐个元素填充
+	expanded := make([]*formulaCell, 0, len(blockFormulas)*len(items))
+	for k := range items {
+		offset := k * height
+		for r, h := range heights {
+			if k > 0 {
+				if err = t.f.SetRowHeight(sheetName, r+offset, h); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, cell := range cells {
+			axis, err := excelize.CoordinatesToCellName(cell.col, cell.row+offset)
+			if err != nil {
+				return nil, err
+			}
+			if k > 0 && cell.style != 0 {
+				if err = t.f.SetCellStyle(sheetName, axis, axis, cell.style); err != nil {
+					return nil, err
+				}
+			}
+			switch {
+			case cell.formula != "":
+				formula := cell.formula
+				if k > 0 {
+					formula = shiftFormula(cell.formula, func(row, _ int, _ bool) int { return row + offset })
+				}
+				expanded = append(expanded, &formulaCell{row: cell.row + offset, col: cell.col, formula: formula, changed: k > 0})
+			case strings.Contains(cell.text, "{{"):
+				err = t.setCell(sheetName, axis, cell.text, items[k])
+			default:
+				err = t.f.SetCellValue(sheetName, axis, typedValue(cell.text, cell.cellType))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if k == 0 {
+			continue
+		}
+		for _, merge := range blockMerges {
+			hCell, _ := excelize.CoordinatesToCellName(merge[0], merge[1]+offset)
+			vCell, _ := excelize.CoordinatesToCellName(merge[2], merge[3]+offset)
+			if err = t.f.MergeCell(sheetName, hCell, vCell); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return append(outside, expanded...), nil
+}
+
+// insertShift 区域外公式的引用：区域下方的行按 delta 移动，引用到区域最后一行的区域扩展到所有重复的行
+func insertShift(row, rowStart int, isEnd bool, start, end, delta int) int {
+	if row > end {
+		return row + delta
+	}
+	if isEnd && row >= start && rowStart <= start {
+		if delta < 0 {
+			return start - 1
+		}
+		return row + delta
+	}
+	return row
+}
+
+// shiftFormula 调整公式中的行号，绝对引用（$1）不变；字符串与跨sheet的引用不处理；
+// fn 的参数为行号、区域的开始行（单元格引用时为自身）与是否为区域的结束行；
+// 调整后结束行小于开始行（区域内的行全部删除）时整个区域替换为 0
+func shiftFormula(formula string, fn func(row, rowStart int, isEnd bool) int) string {
+	var builder strings.Builder
+	inString := false
+	last := 0
+	for i := 0; i < len(formula); i++ {
+		if formula[i] == '"' {
+			inString = !inString
+			continue
+		}
+		if inString {
+			continue
+		}
+		loc := formulaRefRe.FindStringSubmatchIndex(formula[i:])
+		if loc == nil || loc[0] != 0 {
+			continue
+		}
+		start, end := i, i+loc[1]
+		// 前面是字母、数字、下划线、点或者 !（跨sheet）时不是引用，后面是 ( 时为函数名
+		if start > 0 && isRefPrefix(formula[start-1]) || end < len(formula) && (formula[end] == '(' || isWordChar(formula[end])) {
+			for i+1 < len(formula) && isWordChar(formula[i+1]) {
+				i++
+			}
+			continue
+		}
+		match := formula[start:end]
+		sub := formulaRefRe.FindStringSubmatch(match)
+		row1, _ := strconv.Atoi(sub[4])
+		newRow1 := row1
+		if sub[3] == "" {
+			newRow1 = fn(row1, row1, false)
+		}
+		ref := sub[1] + sub[2] + sub[3] + strconv.Itoa(newRow1)
+		if sub[6] != "" {
+			row2, _ := strconv.Atoi(sub[8])
+			newRow2 := row2
+			if sub[7] == "" {
+				newRow2 = fn(row2, row1, true)
+			}
+			ref += ":" + sub[5] + sub[6] + sub[7] + strconv.Itoa(newRow2)
+			if newRow2 < newRow1 {
+				ref = "0"
+			}
+		}
+		builder.WriteString(formula[last:start])
+		builder.WriteString(ref)
+		last = end
+		i = end - 1
+	}
+	builder.WriteString(formula[last:])
+	return builder.String()
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isRefPrefix(c byte) bool {
+	return isWordChar(c) || c == '.' || c == '!' || c == '$'
+}
+
+// setCell 填充单元格的占位符，单元格只有一个字段且为数字、bool 或时间时保持原类型
+func (t *Template) setCell(sheetName, axis, text string, data interface{}) error {
+	if match := templateFieldRe.FindStringSubmatch(text); match != nil {
+		if value, ok := lookupPath(data, match[1]); ok {
+			switch v := value.Interface().(type) {
+			case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool, time.Time:
+				return t.f.SetCellValue(sheetName, axis, v)
+			}
+		}
+	}
+	tpl, has := t.cache[text]
+	if !has {
+		var err error
+		if tpl, err = template.New(axis).Funcs(t.funcs).Option("missingkey=zero").Parse(text); err != nil {
+			return err
+		}
+		t.cache[text] = tpl
+	}
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, data); err != nil {
+		return err
+	}
+	return t.f.SetCellStr(sheetName, axis, strings.ReplaceAll(buf.String(), "<no value>", ""))
+}
+
+// typedValue 模板中的常量按原来的类型写入
+func typedValue(text string, cellType excelize.CellType) interface{} {
+	switch cellType {
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f
+		}
+	case excelize.CellTypeBool:
+		return text == "1" || strings.EqualFold(text, "true")
+	}
+	return text
+}
+
+// lookupPath 按 .A.B 取值，支持结构体字段与 string 为键的 map
+func lookupPath(data interface{}, path string) (reflect.Value, bool) {
+	v := reflect.ValueOf(data)
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		if name == "" {
+			continue
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, false
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return reflect.Value{}, false
+		}
+		if !v.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, v.IsValid() && v.CanInterface()
+}
+
+// lookupSlice 重复行区域的数据，没有时为空
+func lookupSlice(data interface{}, path string) ([]interface{}, error) {
+	v, ok := lookupPath(data, path)
+	if !ok {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("range %s is not slice", path)
+	}
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// fullCalcOnLoad 打开时重新计算公式，模板中复制与调整过的公式没有缓存的结果
+func (t *Template) fullCalcOnLoad() {
+	if t.f.WorkBook == nil {
+		return
+	}
+	calcPr := reflect.ValueOf(t.f.WorkBook).Elem().FieldByName("CalcPr")
+	if !calcPr.IsValid() {
+		return
+	}
+	if calcPr.IsNil() {
+		calcPr.Set(reflect.New(calcPr.Type().Elem()))
+	}
+	if field := calcPr.Elem().FieldByName("FullCalcOnLoad"); field.IsValid() && field.CanSet() {
+		field.SetBool(true)
+	}
+}
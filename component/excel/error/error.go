@@ -24,3 +24,36 @@ var StructBuilderDataErr = errors.New("input data must be strut")
 
 // StructBuilderFieldErr 结构体创建时对应的结构体不能转为string（当前支持类型看：fieldToString() ）
 var StructBuilderFieldErr = errors.New("input field not to string")
+
+// SheetNotFoundErr 导入时指定的sheet不存在
+var SheetNotFoundErr = errors.New("sheet not found")
+
+// StructImportTargetErr 按结构体导入时接收结果的参数必须为结构体切片的指针
+var StructImportTargetErr = errors.New("import target must be pointer to slice of struct")
+
+// StructImportFieldErr 按结构体导入时字段类型不支持转换
+var StructImportFieldErr = errors.New("import field type not supported")
+
+// MissingColumnErr 按结构体导入时表头中没有 validate:"required" 字段对应的列
+var MissingColumnErr = errors.New("column not found")
+
+// EnumValueErr 按结构体导入时单元格的值不在 excel_map 中
+var EnumValueErr = errors.New("value not in enum")
+
+// TemplateRangeErr 模板中 {{range}} 与 {{end}} 不匹配或者嵌套
+var TemplateRangeErr = errors.New("template range not closed or nested")
+
+// ChartColumnErr 图表的系列或分类引用的列名在表头中不存在
+var ChartColumnErr = errors.New("chart column not found")
+
+// JobNotFoundErr 导出任务不存在或者已经过期
+var JobNotFoundErr = errors.New("export job not found")
+
+// JobNotDoneErr 导出任务还没有完成，不能下载
+var JobNotDoneErr = errors.New("export job not done")
+
+// JobManagerClosedErr 导出任务管理器已经停止，不能提交任务
+var JobManagerClosedErr = errors.New("export job manager closed")
+
+// JobForbiddenErr 不是任务的提交者，不能查看或下载
+var JobForbiddenErr = errors.New("export job forbidden")
@@ -0,0 +1,89 @@
+package export_job
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/actorbuf/iota/component/excel/export_web_driver"
+	"github.com/gin-gonic/gin"
+)
+
+// JobIDParam 路由中任务ID的参数名，如 /export/jobs/:id
+const JobIDParam = "id"
+
+// UserFunc 获取当前请求的用户，用于校验任务的提交者；未登录时返回空字符串，
+// 此时只能访问没有提交者的任务
+type UserFunc func(c *gin.Context) string
+
+// JobStatus 查询任务状态的返回
+type JobStatus struct {
+	*Job
+	Percent float64 `json:"percent"`
+}
+
+// StatusHandler 查询任务状态，返回 JobStatus，任务不存在时返回404
+func (manager *Manager) StatusHandler(userFunc UserFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := manager.userJob(c, userFunc)
+		if err != nil {
+			abortJobError(c, err)
+			return
+		}
+		status := &JobStatus{Job: job, Percent: job.Percent()}
+		status.Key = ""
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// DownloadHandler 下载已完成任务的文件，任务没有完成时返回409
+func (manager *Manager) DownloadHandler(userFunc UserFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := manager.userJob(c, userFunc); err != nil {
+			abortJobError(c, err)
+			return
+		}
+		job, r, err := manager.Open(c.Request.Context(), c.Param(JobIDParam))
+		if err != nil {
+			abortJobError(c, err)
+			return
+		}
+		defer r.Close()
+		web := export_web_driver.NewExportGin(nil, c)
+		_ = builder.ExportWebReader(web, job.FileName, job.ContentType, r)
+	}
+}
+
+// userJob 获取任务并校验提交者
+func (manager *Manager) userJob(c *gin.Context, userFunc UserFunc) (*Job, error) {
+	job, err := manager.Get(c.Request.Context(), c.Param(JobIDParam))
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID == "" {
+		return job, nil
+	}
+	var userID string
+	if userFunc != nil {
+		userID = userFunc(c)
+	}
+	if userID != job.UserID {
+		return nil, error2.JobForbiddenErr
+	}
+	return job, nil
+}
+
+// abortJobError 按错误返回状态码
+func abortJobError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, error2.JobNotFoundErr):
+		status = http.StatusNotFound
+	case errors.Is(err, error2.JobForbiddenErr):
+		status = http.StatusForbidden
+	case errors.Is(err, error2.JobNotDoneErr):
+		status = http.StatusConflict
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+}
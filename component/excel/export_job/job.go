@@ -0,0 +1,108 @@
+package export_job
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+)
+
+// State 任务状态
+type State string
+
+const (
+	StatePending State = "pending" // 等待执行
+	StateRunning State = "running" // 执行中
+	StateDone    State = "done"    // 已完成，可以下载
+	StateFailed  State = "failed"  // 失败
+)
+
+// Job 导出任务的状态，保存在redis中
+type Job struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	State       State     `json:"state"`
+	Total       int64     `json:"total"` // 总行数，不知道时为0
+	Done        int64     `json:"done"`  // 已处理的行数
+	Error       string    `json:"error,omitempty"`
+	Key         string    `json:"key,omitempty"` // 存储中的文件标识
+	CreatedAt   time.Time `json:"created_at"`
+	StartAt     time.Time `json:"start_at,omitempty"`
+	EndAt       time.Time `json:"end_at,omitempty"`
+}
+
+// Percent 进度百分比，完成时为100，没有总数时为0
+func (job *Job) Percent() float64 {
+	if job.State == StateDone {
+		return 100
+	}
+	if job.Total <= 0 {
+		return 0
+	}
+	percent := float64(job.Done) * 100 / float64(job.Total)
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+// Finished 是否已经结束（完成或失败）
+func (job *Job) Finished() bool {
+	return job.State == StateDone || job.State == StateFailed
+}
+
+// Progress 任务进度，由 BuildFunc 在读取数据时更新，定时保存到redis
+type Progress struct {
+	total int64
+	done  int64
+}
+
+// SetTotal 设置总行数
+func (progress *Progress) SetTotal(total int64) {
+	atomic.StoreInt64(&progress.total, total)
+}
+
+// Add 增加已处理的行数
+func (progress *Progress) Add(n int64) {
+	atomic.AddInt64(&progress.done, n)
+}
+
+// SetDone 设置已处理的行数
+func (progress *Progress) SetDone(done int64) {
+	atomic.StoreInt64(&progress.done, done)
+}
+
+// get 当前的进度
+func (progress *Progress) get() (total, done int64) {
+	return atomic.LoadInt64(&progress.total), atomic.LoadInt64(&progress.done)
+}
+
+// BuildFunc 在worker中构建导出的文件，ctx 在任务管理器停止时取消；
+// 数据量大时建议使用 StreamDataBuilder，在 Next 中调用 progress.Add
+type BuildFunc func(ctx context.Context, progress *Progress) (*builder.File, error)
+
+// JobOptionFunc 提交任务的修饰方法
+type JobOptionFunc func(options *jobOptions)
+
+type jobOptions struct {
+	csv     bool
+	csvOpts []builder.CsvOptionFunc
+}
+
+// SetJobCsv 以csv（或tsv）导出
+func SetJobCsv(opts ...builder.CsvOptionFunc) JobOptionFunc {
+	return func(options *jobOptions) {
+		options.csv = true
+		options.csvOpts = opts
+	}
+}
+
+// task 等待执行的任务
+type task struct {
+	job     *Job
+	build   BuildFunc
+	options *jobOptions
+}
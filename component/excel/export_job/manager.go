@@ -0,0 +1,379 @@
+package export_job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/go-redis/redis/v8"
+)
+
+// Manager 异步导出任务管理：任务在worker池中执行，同一用户同时执行的任务数有限制，
+// 超出的任务排队等待；状态与进度保存在redis中，完成的文件保存到 Storage
+type Manager struct {
+	redis            redis.UniversalClient
+	storage          Storage
+	prefix           string        // key前缀
+	ttl              time.Duration // 任务状态保留的时间
+	workers          int           // worker数量
+	userLimit        int           // 每个用户同时执行的任务数
+	progressInterval time.Duration // 进度保存到redis的间隔
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	pending []*task
+	running map[string]int // 用户 -> 执行中的任务数
+	started bool
+	closed  bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewManager 初始化任务管理，storage 为 nil 时保存到系统临时目录下的 iota_excel_export
+func NewManager(redis redis.UniversalClient, storage Storage) *Manager {
+	if storage == nil {
+		storage = NewLocalStorage(filepath.Join(os.TempDir(), "iota_excel_export"))
+	}
+	manager := &Manager{
+		redis:            redis,
+		storage:          storage,
+		prefix:           "iota:excel:job:",
+		ttl:              24 * time.Hour,
+		workers:          4,
+		userLimit:        1,
+		progressInterval: time.Second,
+		running:          make(map[string]int),
+	}
+	manager.cond = sync.NewCond(&manager.mu)
+	return manager
+}
+
+// SetPrefix 设置key前缀，初始为 "iota:excel:job:"
+func (manager *Manager) SetPrefix(prefix string) *Manager {
+	manager.prefix = prefix
+	return manager
+}
+
+// SetTTL 设置任务状态保留的时间，初始为24小时；文件需要在存储中另外清理
+func (manager *Manager) SetTTL(ttl time.Duration) *Manager {
+	manager.ttl = ttl
+	return manager
+}
+
+// SetWorkers 设置worker数量，初始为4，需要在 Start 之前设置
+func (manager *Manager) SetWorkers(workers int) *Manager {
+	manager.workers = workers
+	return manager
+}
+
+// SetUserLimit 设置每个用户同时执行的任务数，初始为1
+func (manager *Manager) SetUserLimit(limit int) *Manager {
+	manager.userLimit = limit
+	return manager
+}
+
+// SetProgressInterval 设置进度保存到redis的间隔，初始为1秒
+func (manager *Manager) SetProgressInterval(interval time.Duration) *Manager {
+	manager.progressInterval = interval
+	return manager
+}
+
+// Start 启动worker
+func (manager *Manager) Start(ctx context.Context) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.started {
+		return
+	}
+	manager.started = true
+	manager.ctx, manager.cancel = context.WithCancel(ctx)
+	for i := 0; i < manager.workers; i++ {
+		manager.wg.Add(1)
+		go manager.work()
+	}
+}
+
+// Stop 停止接收任务，取消执行中的任务并等待worker退出，排队中的任务标记为失败
+func (manager *Manager) Stop() {
+	manager.mu.Lock()
+	if manager.closed {
+		manager.mu.Unlock()
+		return
+	}
+	manager.closed = true
+	pending := manager.pending
+	manager.pending = nil
+	if manager.cancel != nil {
+		manager.cancel()
+	}
+	manager.cond.Broadcast()
+	manager.mu.Unlock()
+	manager.wg.Wait()
+
+	for _, t := range pending {
+		manager.fail(context.Background(), t.job, error2.JobManagerClosedErr)
+	}
+}
+
+// newJobID 随机生成任务ID；任务ID用于查询与下载，不能使用可猜测的时间UUID
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Submit 提交任务，返回等待执行的任务状态
+func (manager *Manager) Submit(ctx context.Context, userID, fileName string, build BuildFunc, opts ...JobOptionFunc) (*Job, error) {
+	options := new(jobOptions)
+	for i := range opts {
+		opts[i](options)
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:          id,
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: builder.XlsxContentType,
+		State:       StatePending,
+		Key:         id + ".xlsx",
+		CreatedAt:   time.Now(),
+	}
+	if options.csv {
+		job.ContentType = builder.CsvContentType
+		job.Key = id + ".csv"
+		if strings.EqualFold(filepath.Ext(fileName), ".tsv") {
+			job.ContentType = builder.TsvContentType
+			job.Key = id + ".tsv"
+		}
+	}
+	if job.FileName == "" {
+		job.FileName = fmt.Sprintf("excel_%s%s", time.Now().Format("20060102150405"), filepath.Ext(job.Key))
+	}
+
+	manager.mu.Lock()
+	if manager.closed {
+		manager.mu.Unlock()
+		return nil, error2.JobManagerClosedErr
+	}
+	manager.mu.Unlock()
+	if err := manager.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.closed {
+		return nil, error2.JobManagerClosedErr
+	}
+	manager.pending = append(manager.pending, &task{job: job, build: build, options: options})
+	manager.cond.Signal()
+	// 任务在worker中会被修改，返回副本
+	submitted := *job
+	return &submitted, nil
+}
+
+// Get 获取任务状态
+func (manager *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	data, err := manager.redis.Get(ctx, manager.prefix+id).Bytes()
+	if err == redis.Nil {
+		return nil, error2.JobNotFoundErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	job := new(Job)
+	if err = json.Unmarshal(data, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Open 读取已完成任务的文件
+func (manager *Manager) Open(ctx context.Context, id string) (*Job, io.ReadCloser, error) {
+	job, err := manager.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.State != StateDone {
+		return job, nil, error2.JobNotDoneErr
+	}
+	r, err := manager.storage.Open(ctx, job.Key)
+	if err != nil {
+		return job, nil, err
+	}
+	return job, r, nil
+}
+
+// Delete 删除任务状态与文件
+func (manager *Manager) Delete(ctx context.Context, id string) error {
+	job, err := manager.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.State == StateDone {
+		if err = manager.storage.Delete(ctx, job.Key); err != nil {
+			return err
+		}
+	}
+	return manager.redis.Del(ctx, manager.prefix+id).Err()
+}
+
+// save 保存任务状态
+func (manager *Manager) save(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return manager.redis.Set(ctx, manager.prefix+job.ID, data, manager.ttl).Err()
+}
+
+// fail 标记任务失败
+func (manager *Manager) fail(ctx context.Context, job *Job, err error) {
+	job.State = StateFailed
+	job.Error = err.Error()
+	job.EndAt = time.Now()
+	_ = manager.save(ctx, job)
+}
+
+// next 取出下一个可以执行的任务：按提交顺序，跳过已达到并发限制的用户；停止时返回nil
+func (manager *Manager) next() *task {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	for {
+		if manager.closed {
+			return nil
+		}
+		for i, t := range manager.pending {
+			if manager.userLimit > 0 && manager.running[t.job.UserID] >= manager.userLimit {
+				continue
+			}
+			manager.pending = append(manager.pending[:i], manager.pending[i+1:]...)
+			manager.running[t.job.UserID]++
+			return t
+		}
+		manager.cond.Wait()
+	}
+}
+
+// done 任务执行结束，唤醒等待同一用户的worker
+func (manager *Manager) done(t *task) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	if manager.running[t.job.UserID]--; manager.running[t.job.UserID] <= 0 {
+		delete(manager.running, t.job.UserID)
+	}
+	manager.cond.Broadcast()
+}
+
+// work worker循环
+func (manager *Manager) work() {
+	defer manager.wg.Done()
+	for {
+		t := manager.next()
+		if t == nil {
+			return
+		}
+		manager.run(t)
+		manager.done(t)
+	}
+}
+
+// run 执行任务：构建文件并保存到存储，执行过程中定时保存进度
+func (manager *Manager) run(t *task) {
+	ctx := manager.ctx
+	job := t.job
+	job.State = StateRunning
+	job.StartAt = time.Now()
+	_ = manager.save(ctx, job)
+
+	progress := new(Progress)
+	stop := make(chan struct{})
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		ticker := time.NewTicker(manager.progressInterval)
+		defer ticker.Stop()
+		var lastTotal, lastDone int64
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				total, done := progress.get()
+				if total == lastTotal && done == lastDone {
+					continue
+				}
+				lastTotal, lastDone = total, done
+				snapshot := *job
+				snapshot.Total, snapshot.Done = total, done
+				_ = manager.save(ctx, &snapshot)
+			}
+		}
+	}()
+
+	err := manager.export(ctx, t, progress)
+	close(stop)
+	<-reported
+
+	job.Total, job.Done = progress.get()
+	job.EndAt = time.Now()
+	if err != nil {
+		// 停止时 ctx 已经取消，使用新的 ctx 保存失败状态
+		manager.fail(context.Background(), job, err)
+		return
+	}
+	job.State = StateDone
+	_ = manager.save(context.Background(), job)
+}
+
+// export 构建文件并保存，构建时的 panic 作为错误返回
+func (manager *Manager) export(ctx context.Context, t *task, progress *Progress) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("export job panic: %v", r)
+		}
+	}()
+	file, err := t.build(ctx, progress)
+	if err != nil {
+		return err
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	// 边生成边保存，不需要把整个文件放在内存中
+	pr, pw := io.Pipe()
+	go func() {
+		var err error
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("export job panic: %v", r)
+			}
+			_ = pw.CloseWithError(err)
+		}()
+		if t.options.csv {
+			err = file.ExportCsv(pw, t.options.csvOpts...)
+		} else {
+			err = file.ExportFile(pw)
+		}
+	}()
+	err = manager.storage.Save(ctx, t.job.Key, pr)
+	_ = pr.CloseWithError(err)
+	return err
+}
@@ -0,0 +1,68 @@
+package export_job
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Storage 导出文件的存储，可以实现为对象存储等
+type Storage interface {
+	// Save 保存文件，key 为任务生成的文件标识
+	Save(ctx context.Context, key string, r io.Reader) error
+	// Open 读取文件
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+var _ Storage = new(LocalStorage)
+
+// LocalStorage 保存到本地目录，多实例部署时需要共享目录或者使用其他存储
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage 初始化本地存储，目录不存在时自动创建
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{dir: dir}
+}
+
+// path 文件路径，去掉 key 中的目录防止访问目录外的文件
+func (storage *LocalStorage) path(key string) string {
+	return filepath.Join(storage.dir, filepath.Base(key))
+}
+
+func (storage *LocalStorage) Save(ctx context.Context, key string, r io.Reader) error {
+	if err := os.MkdirAll(storage.dir, 0755); err != nil {
+		return err
+	}
+	// 先写入临时文件，完成后再改名，避免读到不完整的文件
+	tmp, err := os.CreateTemp(storage.dir, filepath.Base(key)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), storage.path(key))
+}
+
+func (storage *LocalStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(storage.path(key))
+}
+
+func (storage *LocalStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(storage.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -33,6 +33,11 @@ func (gin *ExportGin) Export() error {
 	return gin.File.ExportWeb(gin)
 }
 
+// ExportCsv 以csv（或tsv）导出到gin
+func (gin *ExportGin) ExportCsv(opts ...builder.CsvOptionFunc) error {
+	return gin.File.ExportWebCsv(gin, opts...)
+}
+
 // NewExportGin 创建gin导出器
 func NewExportGin(file *builder.File, ctx *gin.Context) *ExportGin {
 	return &ExportGin{Context: ctx, File: file}
@@ -0,0 +1,54 @@
+package export_web_driver
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+)
+
+// ExportHttp 输出到 net/http 的 ResponseWriter
+type ExportHttp struct {
+	Writer http.ResponseWriter
+	File   *builder.File
+}
+
+// GetWriter 获取http的writer
+func (h *ExportHttp) GetWriter() io.Writer {
+	return h.Writer
+}
+
+// Header 设置头信息
+func (h *ExportHttp) Header(key, value string) {
+	if value == "" {
+		h.Writer.Header().Del(key)
+		return
+	}
+	h.Writer.Header().Set(key, value)
+}
+
+// AddHeader 加入导出的特定头信息
+func (h *ExportHttp) AddHeader(key, value string) {
+	nameValues := h.Writer.Header().Values(key)
+	// 如果是"*"设置为当前值，如果不是，则增加
+	if len(nameValues) == 1 && nameValues[0] == "*" {
+		h.Writer.Header().Set(key, value)
+	} else {
+		h.Writer.Header().Add(key, value)
+	}
+}
+
+// Export 导出到http
+func (h *ExportHttp) Export() error {
+	return h.File.ExportWeb(h)
+}
+
+// ExportCsv 以csv（或tsv）导出到http
+func (h *ExportHttp) ExportCsv(opts ...builder.CsvOptionFunc) error {
+	return h.File.ExportWebCsv(h, opts...)
+}
+
+// NewExportHttp 创建 net/http 导出器
+func NewExportHttp(file *builder.File, w http.ResponseWriter) *ExportHttp {
+	return &ExportHttp{Writer: w, File: file}
+}
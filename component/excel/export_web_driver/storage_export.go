@@ -0,0 +1,103 @@
+package export_web_driver
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+)
+
+// ObjectStorage 对象存储（OSS、COS、S3等）的上传接口，由使用方按所用的SDK实现；
+// headers 为导出时设置的头信息（key为小写），可以取 content-type、content-disposition 作为对象的元数据
+type ObjectStorage interface {
+	PutObject(ctx context.Context, key string, r io.Reader, headers map[string]string) error
+}
+
+// ExportStorage 输出到对象存储，边导出边上传，不在内存中保留整个文件
+type ExportStorage struct {
+	ctx     context.Context
+	storage ObjectStorage
+	key     string
+	headers map[string]string
+	File    *builder.File
+
+	pw     *io.PipeWriter
+	result chan error
+}
+
+// GetWriter 开始上传，返回上传的writer；在头信息设置完成后调用
+func (s *ExportStorage) GetWriter() io.Writer {
+	if s.pw != nil {
+		return s.pw
+	}
+	pr, pw := io.Pipe()
+	result := make(chan error, 1)
+	s.pw, s.result = pw, result
+	headers := make(map[string]string, len(s.headers))
+	for key, value := range s.headers {
+		headers[key] = value
+	}
+	go func() {
+		err := s.storage.PutObject(s.ctx, s.key, pr, headers)
+		// 上传提前结束时，让写入方返回错误而不是阻塞
+		_ = pr.CloseWithError(err)
+		result <- err
+	}()
+	return pw
+}
+
+// Header 设置头信息
+func (s *ExportStorage) Header(key, value string) {
+	key = strings.ToLower(key)
+	if value == "" {
+		delete(s.headers, key)
+		return
+	}
+	s.headers[key] = value
+}
+
+// AddHeader 加入头信息，多个值以", "连接
+func (s *ExportStorage) AddHeader(key, value string) {
+	key = strings.ToLower(key)
+	if old, has := s.headers[key]; has && old != "" && old != "*" {
+		value = old + ", " + value
+	}
+	s.headers[key] = value
+}
+
+// Headers 导出时设置的头信息
+func (s *ExportStorage) Headers() map[string]string {
+	return s.headers
+}
+
+// Export 导出并上传到对象存储
+func (s *ExportStorage) Export() error {
+	return s.finish(s.File.ExportWeb(s))
+}
+
+// ExportCsv 以csv（或tsv）导出并上传到对象存储
+func (s *ExportStorage) ExportCsv(opts ...builder.CsvOptionFunc) error {
+	return s.finish(s.File.ExportWebCsv(s, opts...))
+}
+
+// finish 结束写入并等待上传完成
+func (s *ExportStorage) finish(err error) error {
+	if s.pw == nil {
+		return err
+	}
+	pw, result := s.pw, s.result
+	s.pw, s.result = nil, nil
+	if err != nil {
+		_ = pw.CloseWithError(err)
+		<-result
+		return err
+	}
+	_ = pw.Close()
+	return <-result
+}
+
+// NewExportStorage 创建对象存储导出器，key 为对象的存储路径
+func NewExportStorage(ctx context.Context, file *builder.File, storage ObjectStorage, key string) *ExportStorage {
+	return &ExportStorage{ctx: ctx, storage: storage, key: key, headers: make(map[string]string), File: file}
+}
@@ -1,76 +1,311 @@
 package excel
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"errors"
 	"io"
-	"io/ioutil"
-	"mime/multipart"
-	"net/http"
+	"strconv"
+	"time"
+
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/go-playground/validator/v10"
+	"github.com/xuri/excelize/v2"
 )
 
+// ImportReturn 远程 PHP 导入服务的返回
+// Deprecated: 导入已改为本地解析，保留类型兼容旧代码
 type ImportReturn struct {
 	Code int        `json:"code"`
 	Msg  string     `json:"msg"`
 	Data [][]string `json:"data"`
 }
 
+// Deprecated: 同 ImportReturn
 const ImportSuccessCode = 1
 
-func ToPhpExcel(ctx context.Context, file io.Reader) ([][]string, error) {
-	url := "https://robotcrmapi.teammvp.art/server/excel"
-	method := "POST"
-	var returnData [][]string
+const (
+	DefaultImportDateLayout     = "2006-01-02"
+	DefaultImportDateTimeLayout = "2006-01-02 15:04:05"
+)
 
-	payload := &bytes.Buffer{}
-	writer := multipart.NewWriter(payload)
-	part1, err := writer.CreateFormFile("excel_data", "excel_data_file.xlsx")
-	if err != nil {
-		return returnData, err
+// importOptions 导入配置
+type importOptions struct {
+	sheetName      string
+	sheetIndex     int
+	dateLayout     string
+	dateTimeLayout string
+	mergeFill      bool
+	password       string
+
+	// 按结构体导入
+	headRow     int
+	location    *time.Location
+	validate    *validator.Validate
+	validateSet bool
+
+	// csv导入
+	csvComma rune
+	csvGBK   *bool
+}
+
+// ImportOptionFunc 导入修饰方法
+type ImportOptionFunc func(options *importOptions)
+
+// SetImportSheetName 按名称读取sheet，优先于 SetImportSheetIndex
+func SetImportSheetName(sheetName string) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.sheetName = sheetName
 	}
-	_, err = io.Copy(part1, file)
-	if err != nil {
-		return returnData, err
+}
+
+// SetImportSheetIndex 按顺序读取sheet（从0开始），默认读取第一个sheet
+func SetImportSheetIndex(sheetIndex int) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.sheetIndex = sheetIndex
 	}
-	err = writer.Close()
-	if err != nil {
-		return returnData, err
+}
+
+// SetImportDateLayout 日期单元格的输出格式，dateLayout 用于没有时间部分的日期
+func SetImportDateLayout(dateLayout, dateTimeLayout string) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.dateLayout = dateLayout
+		options.dateTimeLayout = dateTimeLayout
 	}
+}
 
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, payload)
+// SetImportMergeFill 是否把合并单元格的值填充到合并区域的每个单元格，默认填充
+func SetImportMergeFill(mergeFill bool) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.mergeFill = mergeFill
+	}
+}
+
+// SetImportPassword 打开有密码的文件
+func SetImportPassword(password string) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.password = password
+	}
+}
+
+func newImportOptions(opts []ImportOptionFunc) *importOptions {
+	options := &importOptions{
+		dateLayout:     DefaultImportDateLayout,
+		dateTimeLayout: DefaultImportDateTimeLayout,
+		mergeFill:      true,
+		csvComma:       ',',
+	}
+	for i := range opts {
+		opts[i](options)
+	}
+	return options
+}
+
+// excelizeOptions 打开文件的设置
+func (options *importOptions) excelizeOptions() []excelize.Options {
+	if options.password == "" {
+		return nil
+	}
+	return []excelize.Options{{Password: options.password}}
+}
+
+// ToPhpExcel 读取第一个sheet，兼容原远程 PHP 导入服务的返回
+func ToPhpExcel(ctx context.Context, file io.Reader) ([][]string, error) {
+	return Import(ctx, file)
+}
 
+// Import 读取 xlsx 的一个sheet为 [][]string：
+// 公式取缓存的计算结果，日期按 SetImportDateLayout 格式化，合并单元格填充，
+// 去掉末尾的空行与空列，每行长度一致
+func Import(ctx context.Context, file io.Reader, opts ...ImportOptionFunc) ([][]string, error) {
+	f, err := excelize.OpenReader(file, newImportOptions(opts).excelizeOptions()...)
 	if err != nil {
-		return returnData, err
+		return nil, err
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	res, err := client.Do(req)
+	defer func() {
+		_ = f.Close()
+	}()
+	return ImportExcelize(ctx, f, opts...)
+}
+
+// ImportFile 读取本地 xlsx 文件，同 Import
+func ImportFile(ctx context.Context, fileName string, opts ...ImportOptionFunc) ([][]string, error) {
+	f, err := excelize.OpenFile(fileName, newImportOptions(opts).excelizeOptions()...)
 	if err != nil {
-		return returnData, err
+		return nil, err
 	}
 	defer func() {
-		_ = res.Body.Close()
+		_ = f.Close()
 	}()
+	return ImportExcelize(ctx, f, opts...)
+}
 
-	if res.StatusCode != 200 {
-		return returnData, errors.New("request excel serve error")
+// ImportExcelize 读取已经打开的 excelize.File，同 Import
+func ImportExcelize(ctx context.Context, f *excelize.File, opts ...ImportOptionFunc) ([][]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	options := newImportOptions(opts)
+	sheetName, err := importSheetName(f, options)
+	if err != nil {
+		return nil, err
 	}
 
-	var importReturn ImportReturn
-	body, err := ioutil.ReadAll(res.Body)
+	rows, err := f.GetRows(sheetName)
 	if err != nil {
-		return returnData, err
+		return nil, err
+	}
+	rawRows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, err
+	}
+	if err = importDates(f, sheetName, rows, rawRows, options); err != nil {
+		return nil, err
+	}
+	if options.mergeFill {
+		if rows, err = importMergeFill(f, sheetName, rows); err != nil {
+			return nil, err
+		}
+	}
+	return importTrim(rows), nil
+}
+
+// importSheetName 获取需要读取的sheet名称
+func importSheetName(f *excelize.File, options *importOptions) (string, error) {
+	if options.sheetName != "" {
+		if f.GetSheetIndex(options.sheetName) == -1 {
+			return "", error2.SheetNotFoundErr
+		}
+		return options.sheetName, nil
+	}
+	sheets := f.GetSheetList()
+	if options.sheetIndex < 0 || options.sheetIndex >= len(sheets) {
+		return "", error2.SheetNotFoundErr
 	}
-	err = json.Unmarshal(body, &importReturn)
+	return sheets[options.sheetIndex], nil
+}
+
+// importDates 日期格式的数字单元格按配置的格式输出
+func importDates(f *excelize.File, sheetName string, rows, rawRows [][]string, options *importOptions) error {
+	date1904 := f.WorkBook != nil && f.WorkBook.WorkbookPr != nil && f.WorkBook.WorkbookPr.Date1904
+	for i := range rows {
+		if i >= len(rawRows) {
+			break
+		}
+		for j := range rows[i] {
+			if j >= len(rawRows[i]) || rows[i][j] == rawRows[i][j] {
+				continue
+			}
+			serial, err := strconv.ParseFloat(rawRows[i][j], 64)
+			if err != nil {
+				continue
+			}
+			axis, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return err
+			}
+			style, err := f.GetCellStyle(sheetName, axis)
+			if err != nil {
+				return err
+			}
+			if !isDateStyle(f, style) {
+				continue
+			}
+			t, err := excelize.ExcelDateToTime(serial, date1904)
+			if err != nil {
+				continue
+			}
+			if serial == float64(int64(serial)) {
+				rows[i][j] = t.Format(options.dateLayout)
+			} else {
+				rows[i][j] = t.Format(options.dateTimeLayout)
+			}
+		}
+	}
+	return nil
+}
+
+// isDateStyle 样式的数字格式是否为日期或时间
+func isDateStyle(f *excelize.File, style int) bool {
+	if f.Styles == nil || f.Styles.CellXfs == nil || style <= 0 || style >= len(f.Styles.CellXfs.Xf) {
+		return false
+	}
+	numFmtID := f.Styles.CellXfs.Xf[style].NumFmtID
+	if numFmtID == nil {
+		return false
+	}
+	switch id := *numFmtID; {
+	case id >= 14 && id <= 22, id >= 27 && id <= 36, id >= 45 && id <= 47, id >= 50 && id <= 58:
+		return true
+	case id < 164:
+		return false
+	}
+	if f.Styles.NumFmts == nil {
+		return false
+	}
+	for _, numFmt := range f.Styles.NumFmts.NumFmt {
+		if numFmt.NumFmtID == *numFmtID {
+			return util.IsExcelDateFormat(numFmt.FormatCode)
+		}
+	}
+	return false
+}
+
+// importMergeFill 合并区域内的单元格使用左上角单元格的值
+func importMergeFill(f *excelize.File, sheetName string, rows [][]string) ([][]string, error) {
+	mergeCells, err := f.GetMergeCells(sheetName)
 	if err != nil {
-		return returnData, err
+		return nil, err
 	}
-	if importReturn.Code != ImportSuccessCode {
-		return returnData, errors.New("import err " + importReturn.Msg)
+	for _, mergeCell := range mergeCells {
+		startCol, startRow, err := excelize.CellNameToCoordinates(mergeCell.GetStartAxis())
+		if err != nil {
+			return nil, err
+		}
+		endCol, endRow, err := excelize.CellNameToCoordinates(mergeCell.GetEndAxis())
+		if err != nil {
+			return nil, err
+		}
+		value := ""
+		if startRow-1 < len(rows) && startCol-1 < len(rows[startRow-1]) {
+			value = rows[startRow-1][startCol-1]
+		}
+		if value == "" {
+			continue
+		}
+		for len(rows) < endRow {
+			rows = append(rows, make([]string, 0))
+		}
+		for r := startRow - 1; r < endRow; r++ {
+			for len(rows[r]) < endCol {
+				rows[r] = append(rows[r], "")
+			}
+			for c := startCol - 1; c < endCol; c++ {
+				rows[r][c] = value
+			}
+		}
 	}
-	returnData = importReturn.Data
+	return rows, nil
+}
 
-	return returnData, nil
+// importTrim 去掉末尾的空行与空列，并把每行补齐到相同长度
+func importTrim(rows [][]string) [][]string {
+	width, height := 0, 0
+	for i := range rows {
+		for j := len(rows[i]) - 1; j >= 0; j-- {
+			if rows[i][j] != "" {
+				if j+1 > width {
+					width = j + 1
+				}
+				height = i + 1
+				break
+			}
+		}
+	}
+	result := make([][]string, height)
+	for i := 0; i < height; i++ {
+		line := make([]string, width)
+		copy(line, rows[i])
+		result[i] = line
+	}
+	return result
 }
@@ -0,0 +1,98 @@
+package excel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// csvDetectSize 自动识别编码时读取的字节数
+const csvDetectSize = 64 * 1024
+
+// SetImportCsvComma 导入csv时的分隔符，默认为逗号，tsv 使用 '\t'
+func SetImportCsvComma(comma rune) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.csvComma = comma
+	}
+}
+
+// SetImportCsvGBK 导入csv时按 GBK 解码；默认根据内容自动识别 UTF-8 与 GBK
+func SetImportCsvGBK(gbk bool) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.csvGBK = &gbk
+	}
+}
+
+// ImportCsv 读取csv（或tsv）为 [][]string：去掉 UTF-8 BOM，GBK 编码自动转为 UTF-8，
+// 去掉末尾的空行与空列，每行长度一致，与 Import 的结果一致
+func ImportCsv(ctx context.Context, file io.Reader, opts ...ImportOptionFunc) ([][]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	options := newImportOptions(opts)
+	buf := bufio.NewReaderSize(file, csvDetectSize)
+	head, err := buf.Peek(csvDetectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	var reader io.Reader = buf
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		_, _ = buf.Discard(len(utf8BOM))
+	case options.csvGBK != nil && *options.csvGBK, options.csvGBK == nil && !isUTF8Prefix(head):
+		reader = simplifiedchinese.GBK.NewDecoder().Reader(buf)
+	}
+
+	r := csv.NewReader(reader)
+	r.Comma = options.csvComma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return importTrim(rows), nil
+}
+
+// ImportCsvFile 读取本地csv文件，同 ImportCsv
+func ImportCsvFile(ctx context.Context, fileName string, opts ...ImportOptionFunc) ([][]string, error) {
+	fd, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = fd.Close()
+	}()
+	return ImportCsv(ctx, fd, opts...)
+}
+
+// ImportStructCsv 读取csv并转换为结构体，同 ImportStruct
+func ImportStructCsv(ctx context.Context, file io.Reader, out interface{}, opts ...ImportOptionFunc) (*StructImportResult, error) {
+	rows, err := ImportCsv(ctx, file, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ImportStructRows(ctx, rows, out, opts...)
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// isUTF8Prefix 内容是否为合法的 UTF-8，忽略末尾被截断的字符
+func isUTF8Prefix(b []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(b) > 0; i++ {
+		if utf8.Valid(b) {
+			return true
+		}
+		if len(b) < csvDetectSize {
+			return false
+		}
+		b = b[:len(b)-1]
+	}
+	return utf8.Valid(b)
+}
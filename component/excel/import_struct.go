@@ -0,0 +1,626 @@
+package excel
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel/builder"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/go-playground/validator/v10"
+	"github.com/xuri/excelize/v2"
+)
+
+// ErrorColumnHead 错误工作簿中追加的错误信息列的表头
+const ErrorColumnHead = "错误信息"
+
+// importTimeLayouts 时间字段没有 excel_format 或者不匹配时依次尝试的格式
+var importTimeLayouts = []string{
+	time.RFC3339,
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"2006/1/2 15:04:05",
+	"2006/1/2 15:04",
+	"2006/1/2",
+	"2006-1-2 15:04:05",
+	"2006-1-2",
+	"2006.01.02",
+	"2006年01月02日 15:04:05",
+	"2006年01月02日",
+	"2006年1月2日",
+	"20060102",
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+	_validate     *validator.Validate
+	_validateOnce sync.Once
+)
+
+// defaultValidate 默认的校验器
+func defaultValidate() *validator.Validate {
+	_validateOnce.Do(func() {
+		_validate = validator.New()
+	})
+	return _validate
+}
+
+// SetImportHeadRow 按结构体导入时表头所在的行（从1开始），默认第1行，数据从下一行开始；
+// 多级表头按最后一级匹配列，需要设置为表头的层数，如 StructDataBuilder 导出的两级表头为 SetImportHeadRow(2)
+func SetImportHeadRow(headRow int) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.headRow = headRow
+	}
+}
+
+// SetImportLocation 按结构体导入时解析时间使用的时区，默认 time.Local
+func SetImportLocation(location *time.Location) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.location = location
+	}
+}
+
+// SetImportValidator 按结构体导入时使用的校验器，默认 validator.New()；传 nil 不校验
+func SetImportValidator(validate *validator.Validate) ImportOptionFunc {
+	return func(options *importOptions) {
+		options.validate = validate
+		options.validateSet = true
+	}
+}
+
+// CellError 按结构体导入时的单元格错误
+type CellError struct {
+	Row   int    // 行号，从1开始
+	Col   int    // 列号，从1开始；表头中没有对应的列时为0
+	Head  string // 表头
+	Field string // 结构体字段，嵌套结构体以 . 连接
+	Value string // 单元格原始内容
+	Err   error  // 转换错误、validator.FieldError 或者 error2.MissingColumnErr（必填字段没有对应的列）
+}
+
+// Axis 单元格坐标，如 B3；没有对应的列时为空
+func (e *CellError) Axis() string {
+	if e.Col <= 0 || e.Row <= 0 {
+		return ""
+	}
+	axis, _ := excelize.CoordinatesToCellName(e.Col, e.Row)
+	return axis
+}
+
+// Message 简短的错误说明，用于错误工作簿
+func (e *CellError) Message() string {
+	var fieldErr validator.FieldError
+	if errors.As(e.Err, &fieldErr) {
+		if fieldErr.Param() != "" {
+			return fmt.Sprintf("%s: validate failed on %s=%s", e.Head, fieldErr.Tag(), fieldErr.Param())
+		}
+		return fmt.Sprintf("%s: validate failed on %s", e.Head, fieldErr.Tag())
+	}
+	return fmt.Sprintf("%s: %s", e.Head, e.Err.Error())
+}
+
+func (e *CellError) Error() string {
+	if axis := e.Axis(); axis != "" {
+		return fmt.Sprintf("%s %s", axis, e.Message())
+	}
+	return fmt.Sprintf("row %d %s", e.Row, e.Message())
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
+
+// StructImportResult 按结构体导入的结果，解析成功的行写入传入的切片
+type StructImportResult struct {
+	Total   int          // 数据行数，不含表头与空行
+	Success int          // 解析并校验成功的行数
+	Errors  []*CellError // 按行号排列的错误
+
+	rows    [][]string
+	headRow int
+}
+
+// HasError 是否有错误
+func (r *StructImportResult) HasError() bool {
+	return len(r.Errors) > 0
+}
+
+// RowErrors 按行号分组的错误
+func (r *StructImportResult) RowErrors() map[int][]*CellError {
+	m := make(map[int][]*CellError)
+	for _, e := range r.Errors {
+		m[e.Row] = append(m[e.Row], e)
+	}
+	return m
+}
+
+// ErrorFile 生成标注了错误的工作簿：保留表头与原始内容，错误单元格标红并添加批注，
+// 末尾追加错误信息列；onlyErrorRows 为 true 时只保留有错误的数据行，便于修改后重新导入
+func (r *StructImportResult) ErrorFile(onlyErrorRows bool) (*excelize.File, error) {
+	const sheetName = "Sheet1"
+	f := excelize.NewFile()
+	errStyle, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#FFC7CE"}},
+		Font: &excelize.Font{Color: "#9C0006"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	width := 0
+	for i := range r.rows {
+		if len(r.rows[i]) > width {
+			width = len(r.rows[i])
+		}
+	}
+	rowErrors := r.RowErrors()
+	outRow := 0
+	for i := range r.rows {
+		row := i + 1
+		errs := rowErrors[row]
+		if row > r.headRow && onlyErrorRows && len(errs) == 0 {
+			continue
+		}
+		outRow++
+		line := make([]interface{}, width+1)
+		for j := range r.rows[i] {
+			line[j] = r.rows[i][j]
+		}
+		if row == r.headRow {
+			line[width] = ErrorColumnHead
+			errs = nil
+		}
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			messages = append(messages, e.Message())
+		}
+		if len(messages) > 0 {
+			line[width] = strings.Join(messages, "\n")
+		}
+		axis, err := excelize.CoordinatesToCellName(1, outRow)
+		if err != nil {
+			return nil, err
+		}
+		if err = f.SetSheetRow(sheetName, axis, &line); err != nil {
+			return nil, err
+		}
+		for _, e := range errs {
+			if e.Col <= 0 {
+				continue
+			}
+			cell, err := excelize.CoordinatesToCellName(e.Col, outRow)
+			if err != nil {
+				return nil, err
+			}
+			if err = f.SetCellStyle(sheetName, cell, cell, errStyle); err != nil {
+				return nil, err
+			}
+			comment, err := json.Marshal(map[string]string{"author": "excel", "text": e.Message()})
+			if err != nil {
+				return nil, err
+			}
+			if err = f.AddComment(sheetName, cell, string(comment)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return f, nil
+}
+
+// ImportStruct 读取 xlsx 的一个sheet并按 excel_head 把每行转换为结构体，out 为结构体切片的指针，
+// 如 *[]User 或 *[]*User；转换或校验失败的行不写入 out，错误在结果中返回。
+// 支持 string、整数、浮点数、bool、time.Time、encoding.TextUnmarshaler 及其指针，空单元格保持零值，
+// excel_map 把展示文本转换为枚举值，excel_default 的内容视为零值，excel_format 为 Excel 日期格式时
+// 转为对应的解析格式、带千分位时去掉逗号，validate tag 由 validator 校验
+func ImportStruct(ctx context.Context, file io.Reader, out interface{}, opts ...ImportOptionFunc) (*StructImportResult, error) {
+	rows, err := Import(ctx, file, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ImportStructRows(ctx, rows, out, opts...)
+}
+
+// ImportStructFile 读取本地 xlsx 文件，同 ImportStruct
+func ImportStructFile(ctx context.Context, fileName string, out interface{}, opts ...ImportOptionFunc) (*StructImportResult, error) {
+	rows, err := ImportFile(ctx, fileName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ImportStructRows(ctx, rows, out, opts...)
+}
+
+// ImportStructRows 把 Import 读取的内容转换为结构体，同 ImportStruct
+func ImportStructRows(ctx context.Context, rows [][]string, out interface{}, opts ...ImportOptionFunc) (*StructImportResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	options := newImportOptions(opts)
+	if !options.validateSet {
+		options.validate = defaultValidate()
+	}
+	if options.location == nil {
+		options.location = time.Local
+	}
+	if options.headRow <= 0 {
+		options.headRow = 1
+	}
+
+	outValue := reflect.ValueOf(out)
+	if outValue.Kind() != reflect.Ptr || outValue.IsNil() || outValue.Elem().Kind() != reflect.Slice {
+		return nil, error2.StructImportTargetErr
+	}
+	sliceValue := outValue.Elem()
+	elemType := sliceValue.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, error2.StructImportTargetErr
+	}
+	fields, err := importStructFields(structType, nil, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StructImportResult{rows: rows, headRow: options.headRow}
+	var heads []string
+	if options.headRow <= len(rows) {
+		heads = rows[options.headRow-1]
+	}
+	for _, field := range fields {
+		for j := range heads {
+			if strings.TrimSpace(heads[j]) == field.head {
+				field.col = j + 1
+				break
+			}
+		}
+		if field.col == 0 && field.required {
+			result.Errors = append(result.Errors, &CellError{
+				Row: options.headRow, Head: field.head, Field: field.name, Err: error2.MissingColumnErr,
+			})
+		}
+	}
+
+	for i := options.headRow; i < len(rows); i++ {
+		if isEmptyRow(rows[i]) {
+			continue
+		}
+		result.Total++
+		item := reflect.New(structType)
+		var rowErrors []*CellError
+		for _, field := range fields {
+			if field.col == 0 || field.col > len(rows[i]) {
+				continue
+			}
+			value := rows[i][field.col-1]
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+			if err := setFieldValue(fieldByIndex(item.Elem(), field.index), strings.TrimSpace(value), field, options); err != nil {
+				rowErrors = append(rowErrors, &CellError{
+					Row: i + 1, Col: field.col, Head: field.head, Field: field.name, Value: value, Err: err,
+				})
+			}
+		}
+		if len(rowErrors) == 0 && options.validate != nil {
+			rowErrors = validateRow(options.validate, item.Interface(), fields, rows[i], i+1)
+		}
+		if len(rowErrors) > 0 {
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+		result.Success++
+		if elemType.Kind() == reflect.Ptr {
+			sliceValue.Set(reflect.Append(sliceValue, item))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, item.Elem()))
+		}
+	}
+	return result, nil
+}
+
+// importField 结构体字段与列的对应关系
+type importField struct {
+	index  []int
+	name   string // 字段名，嵌套结构体以 . 连接，与 validator 的 StructNamespace 去掉类型名后一致
+	head   string
+	layout []string
+	enum   map[string]string // 展示文本 -> 值
+	values map[string]bool   // 合法的值
+	col    int
+
+	defaultValue string // excel_default，导入时视为零值
+	thousands    bool   // 数字格式带千分位，导入时去掉逗号
+	sep          string // 切片的分隔符
+	required     bool   // validate tag 中有 required，表头中没有对应的列时报错
+}
+
+// importStructFields 获取有 excel_head 的字段，嵌套的结构体展开
+func importStructFields(t reflect.Type, index []int, prefix string) ([]*importField, error) {
+	fields := make([]*importField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append(make([]int, 0, len(index)+1), index...), i)
+		exclude := field.Tag.Get(builder.ExcelExclude)
+		if exclude != "" && strings.Contains(exclude, builder.ExcelExcludeTrue) {
+			continue
+		}
+		head := strings.TrimSpace(field.Tag.Get(builder.ExcelHeadTag))
+		// 嵌套的结构体展开，按最后一级表头匹配列
+		if fieldType := indirectType(field.Type); isImportNested(fieldType) && (field.PkgPath == "" || field.Anonymous) {
+			nested, err := importStructFields(fieldType, fieldIndex, prefix+field.Name+".")
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+		if head == "" || field.PkgPath != "" {
+			continue
+		}
+		f := &importField{
+			index:        fieldIndex,
+			name:         prefix + field.Name,
+			head:         head,
+			defaultValue: field.Tag.Get(builder.ExcelDefaultTag),
+		}
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if strings.TrimSpace(rule) == "required" {
+				f.required = true
+			}
+		}
+		if format := field.Tag.Get(builder.ExcelFormatTag); format != "" {
+			// Excel 的日期格式转为 Go 的格式，也可以直接使用 Go 的格式
+			for _, layout := range strings.Split(format, "|") {
+				if util.IsExcelDateFormat(layout) {
+					layout = util.ExcelFormatToLayout(layout)
+				}
+				f.layout = append(f.layout, layout)
+			}
+			f.thousands = !util.IsExcelDateFormat(format) && strings.Contains(format, ",")
+		}
+		if fieldType := indirectType(field.Type); fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() != reflect.Uint8 {
+			f.sep = field.Tag.Get(builder.ExcelSepTag)
+			if f.sep == "" {
+				f.sep = builder.DefaultSep
+			}
+		}
+		if enum := field.Tag.Get(builder.ExcelMapTag); enum != "" {
+			f.enum = make(map[string]string)
+			f.values = make(map[string]bool)
+			for _, pair := range strings.Split(enum, ",") {
+				kv := strings.SplitN(pair, ":", 2)
+				if len(kv) != 2 {
+					return nil, fmt.Errorf("%s: invalid %s %q", field.Name, builder.ExcelMapTag, enum)
+				}
+				value, label := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+				f.enum[label] = value
+				f.values[value] = true
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// setFieldValue 把单元格内容转换后写入字段
+func setFieldValue(field reflect.Value, value string, meta *importField, options *importOptions) error {
+	if meta.defaultValue != "" && value == meta.defaultValue {
+		value = ""
+	}
+	if meta.sep != "" && value != "" {
+		return setSliceValue(field, value, meta, options)
+	}
+	if meta.enum != nil && value != "" {
+		if v, has := meta.enum[value]; has {
+			value = v
+		} else if !meta.values[value] {
+			return error2.EnumValueErr
+		}
+	}
+	if value == "" {
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := setFieldValue(elem.Elem(), value, &importField{layout: meta.layout, thousands: meta.thousands}, options); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Type() == timeType {
+		t, err := parseImportTime(value, meta.layout, options)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	if meta.thousands {
+		value = strings.ReplaceAll(value, ",", "")
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			f, fErr := strconv.ParseFloat(value, 64)
+			if fErr != nil || f != math.Trunc(f) || field.OverflowInt(int64(f)) {
+				return err
+			}
+			n = int64(f)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			f, fErr := strconv.ParseFloat(value, 64)
+			if fErr != nil || f < 0 || f != math.Trunc(f) || field.OverflowUint(uint64(f)) {
+				return err
+			}
+			n = uint64(f)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(value, "%") {
+			f /= 100
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := parseImportBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return error2.StructImportFieldErr
+	}
+	return nil
+}
+
+// setSliceValue 按分隔符拆分后逐个转换，枚举按元素转换
+func setSliceValue(field reflect.Value, value string, meta *importField, options *importOptions) error {
+	sliceType := field.Type()
+	for sliceType.Kind() == reflect.Ptr {
+		sliceType = sliceType.Elem()
+	}
+	parts := strings.Split(value, meta.sep)
+	slice := reflect.MakeSlice(sliceType, 0, len(parts))
+	elemMeta := &importField{layout: meta.layout, enum: meta.enum, values: meta.values, thousands: meta.thousands}
+	for _, part := range parts {
+		elem := reflect.New(sliceType.Elem()).Elem()
+		if err := setFieldValue(elem, strings.TrimSpace(part), elemMeta, options); err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, elem)
+	}
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	field.Set(slice)
+	return nil
+}
+
+// isImportNested 需要展开的嵌套结构体，time.Time 与实现了 encoding.TextUnmarshaler 的除外
+func isImportNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// indirectType 指针的元素类型
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldByIndex 获取嵌套字段，路径上为 nil 的结构体指针会被创建
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+// parseImportTime 依次尝试 excel_format、导入的日期格式与常用格式，都不匹配时按 Excel 日期序列号解析
+func parseImportTime(value string, layouts []string, options *importOptions) (time.Time, error) {
+	tryLayouts := make([]string, 0, len(layouts)+len(importTimeLayouts)+2)
+	tryLayouts = append(tryLayouts, layouts...)
+	tryLayouts = append(tryLayouts, options.dateTimeLayout, options.dateLayout)
+	tryLayouts = append(tryLayouts, importTimeLayouts...)
+	for _, layout := range tryLayouts {
+		if t, err := time.ParseInLocation(layout, value, options.location); err == nil {
+			return t, nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(value, 64); err == nil && serial > 0 {
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), options.location), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// parseImportBool 支持 true/false、1/0、是/否、yes/no 等写法
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "true", "t", "yes", "y", "on", "是", "√":
+		return true, nil
+	case "0", "false", "f", "no", "n", "off", "否", "×":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool %q", value)
+}
+
+// validateRow 校验一行，校验错误对应到列
+func validateRow(validate *validator.Validate, item interface{}, fields []*importField, line []string, row int) []*CellError {
+	err := validate.Struct(item)
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []*CellError{{Row: row, Err: err}}
+	}
+	rowErrors := make([]*CellError, 0, len(validationErrors))
+	for _, fieldErr := range validationErrors {
+		name := fieldErr.StructNamespace()
+		if i := strings.Index(name, "."); i >= 0 {
+			name = name[i+1:]
+		}
+		cellError := &CellError{Row: row, Head: fieldErr.Field(), Field: name, Err: fieldErr}
+		for _, field := range fields {
+			if field.name == name {
+				cellError.Col, cellError.Head = field.col, field.head
+				if field.col > 0 && field.col <= len(line) {
+					cellError.Value = line[field.col-1]
+				}
+				break
+			}
+		}
+		rowErrors = append(rowErrors, cellError)
+	}
+	return rowErrors
+}
+
+// isEmptyRow 整行都为空
+func isEmptyRow(line []string) bool {
+	for i := range line {
+		if strings.TrimSpace(line[i]) != "" {
+			return false
+		}
+	}
+	return true
+}
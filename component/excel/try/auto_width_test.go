@@ -0,0 +1,117 @@
+package excel_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+type autoWidthRow struct {
+	Name    string    `excel_head:"姓名"`
+	Amount  float64   `excel_head:"金额" excel_format:"#,##0.00"`
+	Rate    float64   `excel_head:"比例" excel_format:"0.0%"`
+	Created time.Time `excel_head:"创建时间" excel_format:"yyyy-mm-dd"`
+	Remark  string    `excel_head:"备注"`
+	Fixed   string    `excel_head:"固定" excel_width:"30"`
+}
+
+func TestValueWidth(t *testing.T) {
+	cases := []struct {
+		value  interface{}
+		format string
+		want   int
+	}{
+		{"abc", "", 3},
+		{"中文ab", "", 6},
+		{"第一行\nab", "", 6},
+		{1234567.891, "#,##0.00", 12},
+		{-1234.5, "#,##0", 6},
+		{0.256, "0.0%", 5},
+		{12.5, `"¥"#,##0.00`, 6},
+		{12.5, `0.00"元"`, 7},
+		{1234567890123456789, "", 11},
+		{time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC), "yyyy-mm-dd hh:mm:ss", 19},
+		{time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC), "yyyy年mm月dd日", 14},
+		{true, "", 4},
+		{nil, "", 0},
+	}
+	for _, c := range cases {
+		if got := util.ValueWidth(c.value, c.format); got != c.want {
+			t.Errorf("ValueWidth(%v, %q) = %d, want %d", c.value, c.format, got, c.want)
+		}
+	}
+}
+
+func colWidths(t *testing.T, buf *bytes.Buffer, sheetName string, cols ...string) []float64 {
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	widths := make([]float64, 0, len(cols))
+	for _, col := range cols {
+		w, err := f.GetColWidth(sheetName, col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		widths = append(widths, w)
+	}
+	return widths
+}
+
+func TestAutoWidth(t *testing.T) {
+	lines := []interface{}{
+		autoWidthRow{Name: "张三丰", Amount: 1234567.891, Rate: 0.256, Created: time.Now(), Remark: strings.Repeat("备注", 50)},
+		autoWidthRow{Name: "李四", Amount: 1},
+	}
+	dataBuilder := new(builder2.StructDataBuilder).AddStructAndHead(lines)
+	sheet := builder2.NewSheet(builder2.SetDataBuilder(dataBuilder)).
+		AddColWidth(builder2.NewColWidthByChar("A", "A", 5))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().SetAutoWidth(builder2.NewAutoWidth().SetBounds(10, 40)).AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	// A 为 AddColWidth 设置的宽度，B 为 12+2，C 按最小宽度，D 为日期格式 10+2，E 限制在最大宽度，F 为 excel_width
+	want := []float64{5, 14, 10, 12, 40, 30}
+	got := colWidths(t, buf, "Sheet1", "A", "B", "C", "D", "E", "F")
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("widths: %v, want %v", got, want)
+		}
+	}
+}
+
+func TestStreamAutoWidth(t *testing.T) {
+	values := []string{"短", "中文的内容", strings.Repeat("长", 30)}
+	i := 0
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= len(values) {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, values[i-1]}, nil
+	}).AddHead([]interface{}{"编号", "名称"})
+	sheet := builder2.NewSheet(builder2.SetStreamDataBuilder(dataBuilder),
+		builder2.SetAutoWidth(builder2.NewAutoWidth().SetSample(2)))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// 只采样前两行：名称列为 10+2，第三行不参与计算但正常写入
+	if got := colWidths(t, bytes.NewBuffer(data), "Sheet1", "A", "B"); got[0] != builder2.DefaultAutoWidthMin || got[1] != 12 {
+		t.Fatalf("widths: %v", got)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "B4"); value != values[2] {
+		t.Fatalf("value: %s", value)
+	}
+}
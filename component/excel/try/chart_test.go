@@ -0,0 +1,78 @@
+package excel_test
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"io"
+	"strings"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+)
+
+func chartDataBuilder() *builder2.ArrDataBuilder {
+	return new(builder2.ArrDataBuilder).
+		AddHead([]interface{}{"日期", "销量", "金额"}).
+		AddLine(2, []interface{}{"2022-03-01", 10, 100.5}).
+		AddLine(3, []interface{}{"2022-03-02", 12, 130}).
+		AddLine(4, []interface{}{"2022-03-03", 8, 90})
+}
+
+func TestChart(t *testing.T) {
+	combo := builder2.NewChartByChar(builder2.ChartCol, "E2").SetTitle("销售趋势").
+		SetCategories("日期").SetLegend(builder2.ChartLegendTop).
+		SetYAxis(builder2.NewChartAxis().SetGridlines(true, false).SetRange(0, 200)).
+		AddSeries(builder2.NewChartSeries("销量"), builder2.NewChartSeries("金额").SetType(builder2.ChartLine))
+	data := builder2.NewSheet(builder2.SetSheetName("数据"), builder2.SetDataBuilder(chartDataBuilder()), builder2.AddChart(combo))
+	pie := builder2.NewChartByNum(builder2.ChartPie, 1, 1).SetShowPercent(true).
+		SetCategoryArea(builder2.NewMergeCellByChar("A2", "A4").CellArea).SetCategorySheet("数据").
+		AddSeries(builder2.NewChartSeries("金额").SetSheet("数据"))
+	summary := builder2.NewSheet(builder2.SetSheetName("汇总")).AddChart(pie)
+
+	i := 0
+	stream := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= 5 {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, i * i}, nil
+	}).AddHead([]interface{}{"序号", "平方"})
+	line := builder2.NewChartByChar(builder2.ChartLine, "D2").SetCategories("序号").SetLegend(builder2.ChartLegendNone).
+		AddSeries(builder2.NewChartSeries("平方"))
+	streamSheet := builder2.NewSheet(builder2.SetSheetName("明细"), builder2.SetStreamDataBuilder(stream)).AddChart(line)
+
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(data).AddSheet(summary).AddSheet(streamSheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	chart1 := html.UnescapeString(sheetXML(t, buf.Bytes(), "xl/charts/chart1.xml"))
+	for _, want := range []string{"<barChart>", "<lineChart>", "'数据'!$B$2:$B$4", "'数据'!$C$2:$C$4",
+		"'数据'!$A$2:$A$4", "'数据'!$B$1", "销售趋势", `<max val="200">`, `<legendPos val="t">`} {
+		if !strings.Contains(chart1, want) {
+			t.Errorf("chart1 missing %s", want)
+		}
+	}
+	chart2 := html.UnescapeString(sheetXML(t, buf.Bytes(), "xl/charts/chart2.xml"))
+	if !strings.Contains(chart2, "<pieChart>") || !strings.Contains(chart2, "'数据'!$C$2:$C$4") {
+		t.Errorf("chart2: %s", chart2)
+	}
+	chart3 := html.UnescapeString(sheetXML(t, buf.Bytes(), "xl/charts/chart3.xml"))
+	if !strings.Contains(chart3, "'明细'!$B$2:$B$6") || strings.Contains(chart3, "<legend>") {
+		t.Errorf("chart3: %s", chart3)
+	}
+	if sheet3 := sheetXML(t, buf.Bytes(), "xl/worksheets/sheet3.xml"); !strings.Contains(sheet3, "<drawing") {
+		t.Errorf("stream sheet without drawing: %s", sheet3)
+	}
+}
+
+func TestChartColumnNotFound(t *testing.T) {
+	chart := builder2.NewChartByChar(builder2.ChartLine, "E2").AddSeries(builder2.NewChartSeries("利润"))
+	sheet := builder2.NewSheet(builder2.SetDataBuilder(chartDataBuilder()), builder2.AddChart(chart))
+	err := builder2.NewFile().AddSheet(sheet).ExportFile(new(bytes.Buffer))
+	if !errors.Is(err, error2.ChartColumnErr) {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel"
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/actorbuf/iota/component/excel/export_web_driver"
+	"github.com/gin-gonic/gin"
+)
+
+func newCsvFile() *builder2.File {
+	dataBuilder := new(builder2.ArrDataBuilder).
+		AddHead([]interface{}{"编号", "姓名", "年龄"}).
+		AddLine(2, []interface{}{int64(1234567890123456789), "张三, 李四", 20}).
+		AddLine(4, []interface{}{2, "王五\"", 3.5})
+	return builder2.NewFile().AddSheet(builder2.NewSheet().SetDataBuilder(dataBuilder))
+}
+
+func TestExportCsv(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := newCsvFile().ExportCsv(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "\xEF\xBB\xBF编号,姓名,年龄\n1234567890123456789,\"张三, 李四\",20\n,,\n2,\"王五\"\"\",3.5\n"
+	if buf.String() != want {
+		t.Fatalf("csv: %q", buf.String())
+	}
+	rows, err := excel.ImportCsv(context.Background(), buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "编号" || rows[1][1] != "张三, 李四" || rows[3][1] != "王五\"" {
+		t.Fatalf("rows: %q", rows)
+	}
+
+	// GBK + tsv，自动识别编码
+	buf.Reset()
+	if err = newCsvFile().ExportCsv(buf, builder2.SetCsvGBK(true), builder2.SetCsvTSV()); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("编号")) {
+		t.Fatal("not gbk encoded")
+	}
+	var users []struct {
+		ID   int64   `excel_head:"编号"`
+		Name string  `excel_head:"姓名"`
+		Age  float64 `excel_head:"年龄"`
+	}
+	result, err := excel.ImportStructCsv(context.Background(), buf, &users, excel.SetImportCsvComma('\t'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(users) != 2 || users[0].ID != 1234567890123456789 || users[0].Name != "张三, 李四" || users[1].Age != 3.5 {
+		t.Fatalf("users: %+v %+v", users, result.Errors)
+	}
+}
+
+func TestExportCsvStreamWeb(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	i := 0
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= 2 {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC)}, nil
+	}).AddHead([]interface{}{"ID", "Time"})
+	file := builder2.NewFile().SetFileName("导出.tsv").
+		AddSheet(builder2.NewSheet().SetStreamDataBuilder(dataBuilder))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	if err := export_web_driver.NewExportGin(file, c).ExportCsv(builder2.SetCsvTSV(), builder2.SetCsvBOM(false)); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("content-type"); ct != "text/tab-separated-values; charset=utf-8" {
+		t.Fatalf("content-type: %s", ct)
+	}
+	if cd := w.Header().Get("content-disposition"); !strings.Contains(cd, ".tsv") {
+		t.Fatalf("content-disposition: %s", cd)
+	}
+	if w.Body.String() != "ID\tTime\n1\t2022-03-04 10:30:00\n2\t2022-03-04 10:30:00\n" {
+		t.Fatalf("body: %q", w.Body.String())
+	}
+}
@@ -0,0 +1,99 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/actorbuf/iota/component/excel/export_web_driver"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestContentDisposition(t *testing.T) {
+	cases := map[string]string{
+		"report.xlsx":      `attachment; filename="report.xlsx"`,
+		"dir/订单 2022.xlsx": `attachment; filename="__ 2022.xlsx"; filename*=UTF-8''%E8%AE%A2%E5%8D%95%202022.xlsx`,
+		`a"b.csv`:          `attachment; filename="a_b.csv"; filename*=UTF-8''a%22b.csv`,
+	}
+	for fileName, want := range cases {
+		if cd := builder2.ContentDisposition(fileName); cd != want {
+			t.Errorf("ContentDisposition(%s) = %s, want %s", fileName, cd, want)
+		}
+	}
+}
+
+func driverFile() *builder2.File {
+	dataBuilder := new(builder2.ArrDataBuilder).AddHead([]interface{}{"名称"}).AddLine(2, []interface{}{"苹果"})
+	return builder2.NewFile().SetFileName("订单.xlsx").AddSheet(builder2.NewSheet(builder2.SetDataBuilder(dataBuilder)))
+}
+
+func checkDriverXlsx(t *testing.T, data []byte) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "A2"); value != "苹果" {
+		t.Fatalf("value: %s", value)
+	}
+}
+
+func TestExportHttp(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := export_web_driver.NewExportHttp(driverFile(), w).Export(); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("content-type"); ct != builder2.XlsxContentType {
+		t.Fatalf("content-type: %s", ct)
+	}
+	if cd := w.Header().Get("content-disposition"); cd != builder2.ContentDisposition("订单.xlsx") {
+		t.Fatalf("content-disposition: %s", cd)
+	}
+	checkDriverXlsx(t, w.Body.Bytes())
+}
+
+// memoryStorage 测试用的对象存储
+type memoryStorage struct {
+	objects map[string][]byte
+	headers map[string]map[string]string
+	err     error
+}
+
+func (s *memoryStorage) PutObject(ctx context.Context, key string, r io.Reader, headers map[string]string) error {
+	if s.err != nil {
+		return s.err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = data
+	s.headers[key] = headers
+	return nil
+}
+
+func TestExportStorage(t *testing.T) {
+	storage := &memoryStorage{objects: map[string][]byte{}, headers: map[string]map[string]string{}}
+	if err := export_web_driver.NewExportStorage(context.Background(), driverFile(), storage, "export/1.xlsx").Export(); err != nil {
+		t.Fatal(err)
+	}
+	if headers := storage.headers["export/1.xlsx"]; headers["content-type"] != builder2.XlsxContentType {
+		t.Fatalf("headers: %v", headers)
+	}
+	checkDriverXlsx(t, storage.objects["export/1.xlsx"])
+
+	if err := export_web_driver.NewExportStorage(context.Background(), driverFile(), storage, "export/1.csv").ExportCsv(); err != nil {
+		t.Fatal(err)
+	}
+	if data := string(storage.objects["export/1.csv"]); data != "\ufeff名称\n苹果\n" {
+		t.Fatalf("csv: %q", data)
+	}
+
+	storage.err = errors.New("upload failed")
+	if err := export_web_driver.NewExportStorage(context.Background(), driverFile(), storage, "export/2.xlsx").Export(); err == nil {
+		t.Fatal("want upload error")
+	}
+}
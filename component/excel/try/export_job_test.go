@@ -0,0 +1,210 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/actorbuf/iota/component/excel/export_job"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/xuri/excelize/v2"
+)
+
+func newJobManager(t *testing.T) *export_job.Manager {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	manager := export_job.NewManager(client, export_job.NewLocalStorage(t.TempDir())).
+		SetWorkers(2).SetProgressInterval(10 * time.Millisecond)
+	manager.Start(context.Background())
+	t.Cleanup(manager.Stop)
+	return manager
+}
+
+func waitJob(t *testing.T, manager *export_job.Manager, id string) *export_job.Job {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := manager.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s not finished", id)
+	return nil
+}
+
+func jobRouter(manager *export_job.Manager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userFunc := func(c *gin.Context) string { return c.GetHeader("X-User") }
+	router.GET("/jobs/:id", manager.StatusHandler(userFunc))
+	router.GET("/jobs/:id/download", manager.DownloadHandler(userFunc))
+	return router
+}
+
+func jobRequest(router *gin.Engine, path, user string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-User", user)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportJob(t *testing.T) {
+	manager := newJobManager(t)
+	const total = 100
+	job, err := manager.Submit(context.Background(), "u1", "订单.xlsx", func(ctx context.Context, progress *export_job.Progress) (*builder2.File, error) {
+		progress.SetTotal(total)
+		i := 0
+		dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+			if i >= total {
+				return nil, io.EOF
+			}
+			i++
+			progress.Add(1)
+			return []interface{}{i}, nil
+		}).AddHead([]interface{}{"编号"})
+		return builder2.NewFile().AddSheet(builder2.NewSheet(builder2.SetStreamDataBuilder(dataBuilder))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != export_job.StatePending {
+		t.Fatalf("state: %s", job.State)
+	}
+	job = waitJob(t, manager, job.ID)
+	if job.State != export_job.StateDone || job.Done != total || job.Percent() != 100 {
+		t.Fatalf("job: %+v", job)
+	}
+
+	router := jobRouter(manager)
+	w := jobRequest(router, "/jobs/"+job.ID, "u1")
+	status := new(export_job.JobStatus)
+	if err = json.Unmarshal(w.Body.Bytes(), status); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status: %d %s", w.Code, w.Body.String())
+	}
+	if status.State != export_job.StateDone || status.Percent != 100 || status.Key != "" {
+		t.Fatalf("status: %+v", status)
+	}
+
+	w = jobRequest(router, "/jobs/"+job.ID+"/download", "u1")
+	if w.Code != http.StatusOK || w.Header().Get("content-disposition") == "" {
+		t.Fatalf("download: %d %v", w.Code, w.Header())
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "A101"); value != "100" {
+		t.Fatalf("value: %s", value)
+	}
+
+	if w = jobRequest(router, "/jobs/"+job.ID+"/download", "u2"); w.Code != http.StatusForbidden {
+		t.Fatalf("other user: %d", w.Code)
+	}
+	if w = jobRequest(router, "/jobs/unknown", "u1"); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown: %d", w.Code)
+	}
+	if err = manager.Delete(context.Background(), job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w = jobRequest(router, "/jobs/"+job.ID, "u1"); w.Code != http.StatusNotFound {
+		t.Fatalf("deleted: %d", w.Code)
+	}
+}
+
+func TestExportJobFailed(t *testing.T) {
+	manager := newJobManager(t)
+	job, err := manager.Submit(context.Background(), "u1", "", func(ctx context.Context, progress *export_job.Progress) (*builder2.File, error) {
+		return nil, errors.New("query failed")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitJob(t, manager, job.ID)
+	if job.State != export_job.StateFailed || job.Error != "query failed" {
+		t.Fatalf("job: %+v", job)
+	}
+	if w := jobRequest(jobRouter(manager), "/jobs/"+job.ID+"/download", "u1"); w.Code != http.StatusConflict {
+		t.Fatalf("download failed job: %d", w.Code)
+	}
+
+	job, err = manager.Submit(context.Background(), "u1", "", func(ctx context.Context, progress *export_job.Progress) (*builder2.File, error) {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job = waitJob(t, manager, job.ID); job.State != export_job.StateFailed {
+		t.Fatalf("job: %+v", job)
+	}
+}
+
+func TestExportJobUserLimit(t *testing.T) {
+	manager := newJobManager(t)
+	var mu sync.Mutex
+	running := make(map[string]int)
+	var maxU1, otherUserRan int32
+	release := make(chan struct{})
+	build := func(user string) export_job.BuildFunc {
+		return func(ctx context.Context, progress *export_job.Progress) (*builder2.File, error) {
+			mu.Lock()
+			running[user]++
+			if user == "u1" && int32(running[user]) > atomic.LoadInt32(&maxU1) {
+				atomic.StoreInt32(&maxU1, int32(running[user]))
+			}
+			mu.Unlock()
+			if user == "u2" {
+				atomic.StoreInt32(&otherUserRan, 1)
+			} else {
+				<-release
+			}
+			mu.Lock()
+			running[user]--
+			mu.Unlock()
+			return builder2.NewFile(), nil
+		}
+	}
+	ids := make([]string, 0, 3)
+	for _, user := range []string{"u1", "u1", "u2"} {
+		job, err := manager.Submit(context.Background(), user, "", build(user))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	// u1 的第一个任务阻塞时，u2 的任务仍然可以执行
+	if job := waitJob(t, manager, ids[2]); job.State != export_job.StateDone || atomic.LoadInt32(&otherUserRan) != 1 {
+		t.Fatalf("other user job: %+v", job)
+	}
+	if job, _ := manager.Get(context.Background(), ids[1]); job.State != export_job.StatePending {
+		t.Fatalf("second job should wait: %s", job.State)
+	}
+	close(release)
+	for _, id := range ids[:2] {
+		if job := waitJob(t, manager, id); job.State != export_job.StateDone {
+			t.Fatalf("job: %+v", job)
+		}
+	}
+	if atomic.LoadInt32(&maxU1) != 1 {
+		t.Fatalf("u1 concurrency: %d", maxU1)
+	}
+}
@@ -0,0 +1,149 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/xuri/excelize/v2"
+)
+
+type importBase struct {
+	ID int64 `excel_head:"编号" validate:"required"`
+}
+
+type importUser struct {
+	importBase
+	Name     string     `excel_head:"姓名" validate:"required"`
+	Age      *int       `excel_head:"年龄" validate:"omitempty,gte=18"`
+	Score    float64    `excel_head:"分数"`
+	Vip      bool       `excel_head:"会员"`
+	Status   int        `excel_head:"状态" excel_map:"1:启用,0:禁用"`
+	Birthday time.Time  `excel_head:"生日" excel_format:"2006/01/02"`
+	LoginAt  *time.Time `excel_head:"登录时间"`
+	Remark   string     `excel_head:"备注" excel_exclude:"true"`
+	Email    string     `excel_head:"邮箱"`
+}
+
+func newImportStructFile(t *testing.T) *bytes.Buffer {
+	f := excelize.NewFile()
+	rows := [][]interface{}{
+		{"用户导入"},
+		{"编号", "姓名", "年龄", "分数", "会员", "状态", "生日", "登录时间", "备注"},
+		{1, "张三", 20, 98.5, "是", "启用", "1990/01/02", time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{},
+		{2, "李四", "", "", "否", "0", "1991-02-03", ""},
+		{3, "", 16, "abc", "x", "未知", "2000-13-01", ""},
+	}
+	for i := range rows {
+		axis, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", axis, &rows[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestImportStruct(t *testing.T) {
+	buf := newImportStructFile(t)
+	var users []*importUser
+	result, err := excel.ImportStruct(context.Background(), bytes.NewReader(buf.Bytes()), &users,
+		excel.SetImportHeadRow(2), excel.SetImportLocation(time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || result.Success != 2 || len(users) != 2 {
+		t.Fatalf("total %d success %d users %d", result.Total, result.Success, len(users))
+	}
+	u := users[0]
+	if u.ID != 1 || u.Name != "张三" || u.Age == nil || *u.Age != 20 || u.Score != 98.5 || !u.Vip || u.Status != 1 {
+		t.Fatalf("user: %+v", u)
+	}
+	if !u.Birthday.Equal(time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)) ||
+		u.LoginAt == nil || !u.LoginAt.Equal(time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC)) {
+		t.Fatalf("user: %+v", u)
+	}
+	if u = users[1]; u.Age != nil || u.Vip || u.Status != 0 || u.LoginAt != nil || u.Birthday.Year() != 1991 {
+		t.Fatalf("user: %+v", u)
+	}
+
+	// 缺少的邮箱列不是必填，不报错；第6行姓名、年龄校验不执行（转换已失败），分数、会员、状态、生日转换失败
+	errs := result.RowErrors()
+	if len(errs[2]) != 0 {
+		t.Fatalf("head errors: %v", errs[2])
+	}
+	axes := make([]string, 0)
+	for _, e := range errs[6] {
+		axes = append(axes, e.Axis())
+	}
+	if len(axes) != 4 || axes[0] != "D6" || axes[1] != "E6" || axes[2] != "F6" || axes[3] != "G6" {
+		t.Fatalf("row errors: %v", errs[6])
+	}
+	if !errors.Is(errs[6][2], error2.EnumValueErr) {
+		t.Fatalf("enum error: %v", errs[6][2])
+	}
+}
+
+func TestImportStructMissingColumn(t *testing.T) {
+	type contact struct {
+		ID    int64  `excel_head:"编号" validate:"required"`
+		Phone string `excel_head:"手机" validate:"required,len=11"`
+		Email string `excel_head:"邮箱" validate:"omitempty,email"`
+	}
+	rows := [][]string{{"编号"}, {"1"}}
+	var contacts []contact
+	result, err := excel.ImportStructRows(context.Background(), rows, &contacts, excel.SetImportValidator(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Head != "手机" || !errors.Is(result.Errors[0], error2.MissingColumnErr) {
+		t.Fatalf("errors: %v", result.Errors)
+	}
+}
+
+func TestImportStructValidate(t *testing.T) {
+	rows := [][]string{
+		{"编号", "姓名", "年龄"},
+		{"1", "", "16"},
+		{"2.0", "王五", "30"},
+	}
+	var users []importUser
+	result, err := excel.ImportStructRows(context.Background(), rows, &users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].ID != 2 {
+		t.Fatalf("users: %+v", users)
+	}
+	errs := result.RowErrors()[2]
+	if len(errs) != 2 || errs[0].Axis() != "B2" || errs[1].Axis() != "C2" || errs[1].Message() != "年龄: validate failed on gte=18" {
+		t.Fatalf("errors: %v", errs)
+	}
+
+	f, err := result.ErrorFile(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0][3] != excel.ErrorColumnHead || got[1][1] != "" || got[1][2] != "16" {
+		t.Fatalf("error file: %q", got)
+	}
+	if comments := f.GetComments()["Sheet1"]; len(comments) != 2 || comments[1].Ref != "C2" {
+		t.Fatalf("comments: %+v", comments)
+	}
+
+	if _, err = excel.ImportStructRows(context.Background(), rows, users); !errors.Is(err, error2.StructImportTargetErr) {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel"
+	error2 "github.com/actorbuf/iota/component/excel/error"
+	"github.com/xuri/excelize/v2"
+)
+
+func newImportFile(t *testing.T) *bytes.Buffer {
+	f := excelize.NewFile()
+	f.NewSheet("Data")
+	// 合并单元格
+	_ = f.SetCellValue("Data", "A1", "分组")
+	_ = f.MergeCell("Data", "A1", "A2")
+	_ = f.SetCellValue("Data", "B1", "a")
+	_ = f.SetCellValue("Data", "B2", "b")
+	// 公式取缓存值
+	_ = f.SetCellValue("Data", "C1", 1)
+	_ = f.SetCellValue("Data", "C2", 2)
+	_ = f.SetCellValue("Data", "C3", 3)
+	_ = f.SetCellFormula("Data", "C3", "=C1+C2")
+	// 日期
+	_ = f.SetCellValue("Data", "D1", time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC))
+	_ = f.SetCellValue("Data", "D2", time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC))
+	// 末尾只有样式的空单元格
+	style, _ := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	_ = f.SetCellStyle("Data", "F1", "F5", style)
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestImport(t *testing.T) {
+	buf := newImportFile(t)
+	rows, err := excel.Import(context.Background(), bytes.NewReader(buf.Bytes()), excel.SetImportSheetName("Data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"分组", "a", "1", "2022-03-04"},
+		{"分组", "b", "2", "2022-03-04 10:30:00"},
+		{"", "", "3", ""},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows: %q", rows)
+	}
+	for i := range want {
+		if len(rows[i]) != len(want[i]) {
+			t.Fatalf("rows: %q", rows)
+		}
+		for j := range want[i] {
+			if rows[i][j] != want[i][j] {
+				t.Fatalf("rows: %q", rows)
+			}
+		}
+	}
+
+	// 不填充合并单元格
+	rows, err = excel.Import(context.Background(), bytes.NewReader(buf.Bytes()),
+		excel.SetImportSheetIndex(1), excel.SetImportMergeFill(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[1][0] != "" {
+		t.Fatalf("rows: %q", rows)
+	}
+
+	// 兼容旧方法：读取第一个sheet（空）
+	rows, err = excel.ToPhpExcel(context.Background(), bytes.NewReader(buf.Bytes()))
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("rows %q err %v", rows, err)
+	}
+	if _, err = excel.Import(context.Background(), bytes.NewReader(buf.Bytes()), excel.SetImportSheetName("None")); err != error2.SheetNotFoundErr {
+		t.Fatalf("err: %v", err)
+	}
+}
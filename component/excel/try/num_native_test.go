@@ -0,0 +1,123 @@
+package excel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+// testDecimal 与 shopspring/decimal 的 Decimal 方法相同
+type testDecimal string
+
+func (d testDecimal) String() string { return string(d) }
+
+func (d testDecimal) Float64() (float64, bool) { return 0, false }
+
+var nativeLine = []interface{}{
+	int64(1234567890123456789), 123456, float32(0.1), testDecimal("12345.6789"),
+	testDecimal("1234567890.123456789"), json.Number("3.14"), 0.256, "文本",
+}
+
+// numFormatCode 单元格样式的数字格式
+func numFormatCode(f *excelize.File, sheetName, axis string) string {
+	styleID, _ := f.GetCellStyle(sheetName, axis)
+	if styleID == 0 || f.Styles == nil {
+		return ""
+	}
+	numFmtID := f.Styles.CellXfs.Xf[styleID].NumFmtID
+	if numFmtID == nil || f.Styles.NumFmts == nil {
+		return ""
+	}
+	for _, numFmt := range f.Styles.NumFmts.NumFmt {
+		if numFmt.NumFmtID == *numFmtID {
+			return numFmt.FormatCode
+		}
+	}
+	return ""
+}
+
+func checkNativeCells(t *testing.T, buf *bytes.Buffer) {
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		axis     string
+		value    string
+		cellType excelize.CellType
+	}{
+		{"A2", "1234567890123456789", excelize.CellTypeString},
+		{"B2", "123456", excelize.CellTypeUnset},
+		{"C2", "0.1", excelize.CellTypeUnset},
+		{"D2", "12345.6789", excelize.CellTypeUnset},
+		{"E2", "1234567890.123456789", excelize.CellTypeString},
+		{"F2", "3.14", excelize.CellTypeUnset},
+		{"G2", "0.256", excelize.CellTypeUnset},
+	}
+	for _, c := range cases {
+		value, _ := f.GetCellValue("Sheet1", c.axis, excelize.Options{RawCellValue: true})
+		cellType, _ := f.GetCellType("Sheet1", c.axis)
+		if value != c.value || cellType != c.cellType {
+			t.Errorf("%s: %q %v, want %q %v", c.axis, value, cellType, c.value, c.cellType)
+		}
+	}
+	for axis, want := range map[string]string{"B2": builder2.NumFmtThousands, "D2": builder2.NumFmtThousandsDecimal2, "G2": builder2.NumFmtPercentDecimal2, "B1": ""} {
+		if format := numFormatCode(f, "Sheet1", axis); format != want {
+			t.Errorf("%s format: %q, want %q", axis, format, want)
+		}
+	}
+}
+
+func TestNumNative(t *testing.T) {
+	dataBuilder := new(builder2.ArrDataBuilder).
+		AddHead([]interface{}{"ID", "数量", "单价", "金额", "精确金额", "比率", "占比", "备注"}).
+		AddLine(2, append([]interface{}{}, nativeLine...))
+	sheet := builder2.NewSheet(builder2.SetDataBuilder(dataBuilder)).SetNumNative(true).
+		AddColFormat(builder2.NewColFormatByChar("B", "B", builder2.NumFmtThousands)).
+		AddColFormat(builder2.NewColFormatByNum(4, 4, builder2.NumFmtThousandsDecimal2)).
+		AddColFormat(builder2.NewColFormatByChar("G", "G", builder2.NumFmtPercentDecimal2))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkNativeCells(t, buf)
+}
+
+func TestStreamNumNative(t *testing.T) {
+	done := false
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if done {
+			return nil, io.EOF
+		}
+		done = true
+		return nativeLine, nil
+	}).AddHead([]interface{}{"ID", "数量", "单价", "金额", "精确金额", "比率", "占比", "备注"})
+	sheet := builder2.NewSheet(builder2.SetStreamDataBuilder(dataBuilder)).SetNumNative(true).
+		AddColFormat(builder2.NewColFormatByChar("B", "B", builder2.NumFmtThousands)).
+		AddColFormat(builder2.NewColFormatByNum(4, 4, builder2.NumFmtThousandsDecimal2)).
+		AddColFormat(builder2.NewColFormatByChar("G", "G", builder2.NumFmtPercentDecimal2))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	checkNativeCells(t, buf)
+}
+
+func TestNumToString(t *testing.T) {
+	cases := map[interface{}]string{
+		float32(0.1):               "0.1",
+		float32(16777216.5):        "16777216",
+		0.1:                        "0.1",
+		int64(1234567890123456789): "1234567890123456789",
+	}
+	for value, want := range cases {
+		if is, s := util.NumToString(value); !is || s != want {
+			t.Errorf("NumToString(%v) = %s, want %s", value, s, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iota/component/excel"
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/xuri/excelize/v2"
+)
+
+// cellLocked 单元格是否锁定，没有设置保护样式时默认锁定
+func cellLocked(f *excelize.File, sheetName, axis string) bool {
+	styleID, _ := f.GetCellStyle(sheetName, axis)
+	if f.Styles == nil || styleID >= len(f.Styles.CellXfs.Xf) {
+		return true
+	}
+	protection := f.Styles.CellXfs.Xf[styleID].Protection
+	return protection == nil || protection.Locked == nil || *protection.Locked
+}
+
+func TestProtection(t *testing.T) {
+	dataBuilder := new(builder2.ArrDataBuilder).
+		AddHead([]interface{}{"姓名", "工资", "备注"}).
+		AddLine(2, []interface{}{"张三", 10000, ""}).
+		AddLine(3, []interface{}{"李四", 12000, ""})
+	sheet := builder2.NewSheet(builder2.SetDataBuilder(dataBuilder)).
+		AddBodyStyle(builder2.NewStyleByChar("C2", "C3", &excelize.Style{Font: &excelize.Font{Bold: true}})).
+		SetProtection(builder2.NewSheetProtection("123").SetAllow(builder2.ProtectSort).AddUnlockedByChar("C2", "C3"))
+	buf := new(bytes.Buffer)
+	err := builder2.NewFile().AddSheet(sheet).SetWorkbookProtection(builder2.NewWorkbookProtection("456")).
+		SetPassword("密码").ExportFile(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = excelize.OpenReader(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("open without password")
+	}
+	rows, err := excel.Import(context.Background(), bytes.NewReader(buf.Bytes()), excel.SetImportPassword("密码"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[2][1] != "12000" {
+		t.Fatalf("rows: %q", rows)
+	}
+
+	raw, err := excelize.Decrypt(buf.Bytes(), &excelize.Options{Password: "密码"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet1 := sheetXML(t, raw, "xl/worksheets/sheet1.xml")
+	if !strings.Contains(sheet1, `<sheetProtection password="CF7A" sheet="true"`) || !strings.Contains(sheet1, `sort="false"`) ||
+		!strings.Contains(sheet1, `formatCells="true"`) {
+		t.Errorf("sheet protection: %s", sheet1)
+	}
+	if workbook := sheetXML(t, raw, "xl/workbook.xml"); !strings.Contains(workbook, `lockStructure="true"`) ||
+		!strings.Contains(workbook, `workbookAlgorithmName="SHA-512"`) {
+		t.Errorf("workbook protection: %s", workbook)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()), excelize.Options{Password: "密码"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cellLocked(f, "Sheet1", "B2") || cellLocked(f, "Sheet1", "C2") || cellLocked(f, "Sheet1", "C3") {
+		t.Fatal("unlocked cells")
+	}
+	styleID, _ := f.GetCellStyle("Sheet1", "C2")
+	if fontID := f.Styles.CellXfs.Xf[styleID].FontID; fontID == nil || *fontID == 0 {
+		t.Fatal("unlocked cell lost its style")
+	}
+}
+
+func TestStreamProtection(t *testing.T) {
+	i := 0
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= 2 {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, ""}, nil
+	}).AddHead([]interface{}{"编号", "审核意见"})
+	sheet := builder2.NewSheet(builder2.SetStreamDataBuilder(dataBuilder),
+		builder2.SetSheetProtection(builder2.NewSheetProtection("").AddUnlockedByNum(2, 2, 2, 3)))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	if sheet1 := sheetXML(t, buf.Bytes(), "xl/worksheets/sheet1.xml"); !strings.Contains(sheet1, `<sheetProtection sheet="true"`) {
+		t.Errorf("sheet protection: %s", sheet1)
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cellLocked(f, "Sheet1", "A2") || cellLocked(f, "Sheet1", "B2") || cellLocked(f, "Sheet1", "B3") {
+		t.Fatal("unlocked cells")
+	}
+}
@@ -0,0 +1,120 @@
+package excel_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/xuri/excelize/v2"
+)
+
+// sheetXML 读取导出文件中sheet的xml
+func sheetXML(t *testing.T, data []byte, name string) string {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range r.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	t.Fatalf("%s not found", name)
+	return ""
+}
+
+func TestSheetFeature(t *testing.T) {
+	img := new(bytes.Buffer)
+	if err := png.Encode(img, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	dataBuilder := new(builder2.ArrDataBuilder).
+		AddHead([]interface{}{"姓名", "状态", "主页"}).
+		AddLine(2, []interface{}{"张三", "启用", ""}).
+		AddLine(3, []interface{}{"李四", "禁用", ""})
+	sheet := builder2.NewSheet(builder2.SetDataBuilder(dataBuilder), builder2.SetFreezeHead(), builder2.SetHeadFilter()).
+		AddDataValidation(builder2.NewDataValidationByNum(2, 2, 2, 100, []string{"启用", "禁用"}).
+			SetError("状态错误", "请选择启用或禁用").SetPrompt("状态", "请选择")).
+		AddComment(builder2.NewCommentByChar("B1", "系统", "只能选择启用或禁用")).
+		AddHyperlink(builder2.NewHyperlinkByNum(3, 2, "https://example.com", "主页").SetTooltip("打开主页")).
+		AddHyperlink(builder2.NewHyperlinkByChar("C3", "#Sheet1!A1", "返回")).
+		AddImage(builder2.NewImageByChar("E2", "logo").SetData(".png", img.Bytes()))
+
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	xml := sheetXML(t, data, "xl/worksheets/sheet1.xml")
+	for _, want := range []string{`ySplit="1"`, `state="frozen"`, `<autoFilter ref="$A$1:$C$3"`, `sqref="B2:B100"`, `type="list"`, `"启用,禁用"`} {
+		if !strings.Contains(xml, want) {
+			t.Fatalf("sheet xml missing %s: %s", want, xml)
+		}
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comments := f.GetComments()["Sheet1"]; len(comments) != 1 || comments[0].Ref != "B1" {
+		t.Fatalf("comments: %+v", comments)
+	}
+	if ok, link, _ := f.GetCellHyperLink("Sheet1", "C2"); !ok || link != "https://example.com" {
+		t.Fatalf("hyperlink: %v %s", ok, link)
+	}
+	if ok, link, _ := f.GetCellHyperLink("Sheet1", "C3"); !ok || link != "Sheet1!A1" {
+		t.Fatalf("location: %v %s", ok, link)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "C2"); value != "主页" {
+		t.Fatalf("display: %s", value)
+	}
+	if _, picture, _ := f.GetPicture("Sheet1", "E2"); !bytes.Equal(picture, img.Bytes()) {
+		t.Fatal("picture not found")
+	}
+}
+
+func TestStreamSheetFeature(t *testing.T) {
+	i := 0
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= 10 {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, "name"}, nil
+	}).AddHead([]interface{}{"ID", "Name"})
+	sheet := builder2.NewSheet(builder2.SetStreamDataBuilder(dataBuilder), builder2.SetFreezePanes(1, 1), builder2.SetHeadFilter()).
+		AddComment(builder2.NewCommentByNum(1, 1, "系统", "编号"))
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	xml := sheetXML(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	for _, want := range []string{`xSplit="1"`, `ySplit="1"`, `topLeftCell="B2"`, `<autoFilter ref="$A$1:$B$11"`} {
+		if !strings.Contains(xml, want) {
+			t.Fatalf("sheet xml missing %s: %s", want, xml)
+		}
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "B11"); value != "name" {
+		t.Fatalf("value: %s", value)
+	}
+}
@@ -0,0 +1,72 @@
+package excel_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/xuri/excelize/v2"
+)
+
+type closeCounter struct {
+	*builder2.FuncDataBuilder
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestExportStream(t *testing.T) {
+	const total = 1000
+	i := 0
+	dataBuilder := &closeCounter{FuncDataBuilder: builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= total {
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, fmt.Sprintf("name %d", i), int64(1234567890123456789)}, nil
+	}).AddHead([]interface{}{"ID", "Name", "Num"})}
+
+	red := &excelize.Style{Font: &excelize.Font{Color: "#FF0000"}}
+	sheet := builder2.NewSheet(builder2.SetSheetName("Data"), builder2.SetStreamDataBuilder(dataBuilder)).
+		AddBodyStyle(builder2.NewStyleByNum(2, 2, 2, total+1, red)).
+		AddColWidth(builder2.NewColWidthByNum(2, 2, 30)).
+		AddMergeCell(builder2.NewMergeCellByChar("D1", "E1")).
+		SetNumKeep(true)
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(sheet).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	if dataBuilder.closed != 1 {
+		t.Fatalf("closed %d", dataBuilder.closed)
+	}
+
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != total+1 || rows[0][1] != "Name" || rows[total][0] != strconv.Itoa(total) || rows[1][2] != "1234567890123456789" {
+		t.Fatalf("rows: %d %q %q", len(rows), rows[0], rows[total])
+	}
+	headStyle, _ := f.GetCellStyle("Data", "A1")
+	bodyStyle, _ := f.GetCellStyle("Data", "B3")
+	plainStyle, _ := f.GetCellStyle("Data", "A3")
+	if headStyle == 0 || bodyStyle == 0 || bodyStyle == headStyle || plainStyle != 0 {
+		t.Fatalf("styles: head %d body %d plain %d", headStyle, bodyStyle, plainStyle)
+	}
+	if width, _ := f.GetColWidth("Data", "B"); width != 30 {
+		t.Fatalf("width: %v", width)
+	}
+	if merges, _ := f.GetMergeCells("Data"); len(merges) != 1 {
+		t.Fatalf("merges: %v", merges)
+	}
+}
@@ -0,0 +1,119 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/actorbuf/iota/component/excel"
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/xuri/excelize/v2"
+)
+
+type nestedContact struct {
+	Phone string `excel_head:"电话"`
+	Email string `excel_head:"邮箱" excel_order:"1"`
+}
+
+type nestedUser struct {
+	Name    string         `excel_head:"姓名"`
+	Contact *nestedContact `excel_head:"联系方式"`
+	Tags    []string       `excel_head:"标签" excel_sep:"|"`
+	Levels  []int          `excel_head:"等级" excel_map:"1:普通,2:高级"`
+}
+
+func TestExportNestedStruct(t *testing.T) {
+	lines := []interface{}{
+		&nestedUser{Name: "张三", Contact: &nestedContact{Phone: "138", Email: "a@b.c"}, Tags: []string{"a", "b"}, Levels: []int{1, 2}},
+		&nestedUser{Name: "李四"},
+	}
+	dataBuilder := new(builder2.StructDataBuilder).AddHead([]interface{}{"用户列表"}).AddStructAndHead(lines).
+		AddLine(6, []interface{}{"合计", 2})
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(builder2.NewSheet().SetDataBuilder(dataBuilder)).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Sheet1", excelize.Options{RawCellValue: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"用户列表"},
+		{"姓名", "联系方式", "", "标签", "等级"},
+		{"", "邮箱", "电话"},
+		{"张三", "a@b.c", "138", "a|b", "普通,高级"},
+		{"李四"},
+		{"合计", "2"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("rows: %q", rows)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if j >= len(rows[i]) || rows[i][j] != want[i][j] {
+				t.Fatalf("rows: %q", rows)
+			}
+		}
+	}
+	merges, err := f.GetMergeCells("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotMerges := make(map[string]bool)
+	for _, merge := range merges {
+		gotMerges[merge.GetStartAxis()+":"+merge.GetEndAxis()] = true
+	}
+	for _, m := range []string{"A2:A3", "B2:C2", "D2:D3", "E2:E3"} {
+		if !gotMerges[m] {
+			t.Fatalf("merges: %v", gotMerges)
+		}
+	}
+
+	// 导入：表头第3行，按最后一级表头匹配，切片按分隔符拆分
+	var users []nestedUser
+	result, err := excel.ImportStruct(context.Background(), bytes.NewReader(buf.Bytes()), &users, excel.SetImportHeadRow(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) < 2 || users[0].Contact == nil || users[0].Contact.Phone != "138" || len(users[0].Tags) != 2 ||
+		users[0].Tags[1] != "b" || len(users[0].Levels) != 2 || users[0].Levels[1] != 2 || users[1].Contact != nil {
+		t.Fatalf("users: %+v %v", users, result.Errors)
+	}
+}
+
+type embedCode int
+
+type embedBase struct {
+	ID int64 `excel_head:"编号"`
+}
+
+type embedUser struct {
+	embedCode
+	embedBase
+	Name string `excel_head:"姓名"`
+}
+
+func TestExportUnexportedEmbedded(t *testing.T) {
+	lines := []interface{}{embedUser{embedCode: 1, embedBase: embedBase{ID: 7}, Name: "张三"}}
+	dataBuilder := new(builder2.StructDataBuilder).AddStructAndHead(lines)
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(builder2.NewSheet().SetDataBuilder(dataBuilder)).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 未导出的非结构体嵌入字段不输出，未导出的嵌入结构体展开
+	if len(rows) != 2 || len(rows[0]) != 2 || rows[0][0] != "编号" || rows[0][1] != "姓名" || rows[1][0] != "7" || rows[1][1] != "张三" {
+		t.Fatalf("rows: %q", rows)
+	}
+}
@@ -0,0 +1,123 @@
+package excel_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/excel"
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	util2 "github.com/actorbuf/iota/component/excel/util"
+	"github.com/xuri/excelize/v2"
+)
+
+type tagUser struct {
+	Name     string     `excel_head:"姓名" excel_order:"2" excel_width:"20"`
+	ID       int64      `excel_head:"编号" excel_order:"1"`
+	Status   int        `excel_head:"状态" excel_map:"1:启用,0:禁用"`
+	Amount   float64    `excel_head:"金额" excel_format:"#,##0.00" excel_style:"money"`
+	Birthday time.Time  `excel_head:"生日" excel_format:"yyyy/mm/dd"`
+	Remark   string     `excel_head:"备注" excel_default:"-"`
+	LoginAt  *time.Time `excel_head:"登录时间"`
+	Secret   string     `excel_exclude:"true"`
+}
+
+func tagUsers() []interface{} {
+	loginAt := time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC)
+	return []interface{}{
+		&tagUser{Name: "张三", ID: 1, Status: 1, Amount: 1234.5, Birthday: time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC), LoginAt: &loginAt},
+		&tagUser{Name: "李四", ID: 2, Remark: "vip"},
+	}
+}
+
+func TestExportStructTag(t *testing.T) {
+	sheet := builder2.NewSheet().SetDataBuilder(new(builder2.StructDataBuilder).AddStructAndHead(tagUsers()))
+	file := builder2.NewFile().AddSheet(sheet).
+		AddStyleRef("money", &excelize.Style{Font: &excelize.Font{Color: "#FF0000"}})
+	buf := new(bytes.Buffer)
+	if err := file.ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "编号" || rows[0][1] != "姓名" || rows[1][2] != "启用" || rows[2][2] != "禁用" || rows[1][5] != "-" {
+		t.Fatalf("rows: %q", rows)
+	}
+	if width, _ := f.GetColWidth("Sheet1", "B"); width != 20 {
+		t.Fatalf("width: %v", width)
+	}
+	if style, _ := f.GetCellStyle("Sheet1", "D2"); style == 0 || f.Styles.CellXfs.Xf[style].FontID == nil || *f.Styles.CellXfs.Xf[style].FontID == 0 {
+		t.Fatalf("money style not applied: %d", style)
+	}
+
+	// 导入时反向转换
+	var users []tagUser
+	result, err := excel.ImportStruct(context.Background(), bytes.NewReader(buf.Bytes()), &users, excel.SetImportLocation(time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.HasError() || len(users) != 2 {
+		t.Fatalf("errors: %v", result.Errors)
+	}
+	u := users[0]
+	if u.ID != 1 || u.Status != 1 || u.Amount != 1234.5 || u.Remark != "" || !u.Birthday.Equal(time.Date(1990, 1, 2, 0, 0, 0, 0, time.UTC)) ||
+		u.LoginAt == nil || !u.LoginAt.Equal(time.Date(2022, 3, 4, 10, 30, 0, 0, time.UTC)) {
+		t.Fatalf("user: %+v", u)
+	}
+	if u = users[1]; u.Status != 0 || u.Remark != "vip" || u.LoginAt != nil || !u.Birthday.IsZero() {
+		t.Fatalf("user: %+v", u)
+	}
+}
+
+func TestExportStreamStructTag(t *testing.T) {
+	users := tagUsers()
+	i := 0
+	dataBuilder := builder2.NewFuncDataBuilder(func() ([]interface{}, error) {
+		if i >= len(users) {
+			return nil, io.EOF
+		}
+		i++
+		return builder2.StructLine(users[i-1])
+	}).AddHeadByStruct(tagUser{})
+	buf := new(bytes.Buffer)
+	if err := builder2.NewFile().AddSheet(builder2.NewSheet().SetStreamDataBuilder(dataBuilder)).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := f.GetRows("Sheet1")
+	if len(rows) != 3 || rows[1][1] != "张三" || rows[1][2] != "启用" {
+		t.Fatalf("rows: %q", rows)
+	}
+	if width, _ := f.GetColWidth("Sheet1", "B"); width != 20 {
+		t.Fatalf("width: %v", width)
+	}
+	if style, _ := f.GetCellStyle("Sheet1", "E2"); style == 0 {
+		t.Fatal("date format not applied")
+	}
+}
+
+func TestExcelFormatToLayout(t *testing.T) {
+	cases := map[string]string{
+		"yyyy-mm-dd hh:mm:ss":   "2006-01-02 15:04:05",
+		"yyyy/m/d":              "2006/1/2",
+		"yy\"年\"mm\"月\"dd\"日\"": "06年01月02日",
+		"[$-409]h:mm AM/PM":     "3:04 PM",
+		"mm:ss":                 "04:05",
+	}
+	for format, want := range cases {
+		if got := util2.ExcelFormatToLayout(format); got != want {
+			t.Fatalf("%s: got %s want %s", format, got, want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package excel_test
+
+import (
+	"bytes"
+	"testing"
+
+	builder2 "github.com/actorbuf/iota/component/excel/builder"
+	"github.com/xuri/excelize/v2"
+)
+
+type templateOrder struct {
+	Title  string
+	Author string
+	Items  []*templateItem
+}
+
+type templateItem struct {
+	Name  string
+	Price float64
+	Count int
+}
+
+// newOrderTemplate 标题、重复的明细行与合计行
+func newOrderTemplate(t *testing.T) *builder2.Template {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	_ = f.SetCellStr(sheet, "A1", "{{.Title}}")
+	_ = f.MergeCell(sheet, "A1", "D1")
+	_ = f.SetSheetRow(sheet, "A2", &[]interface{}{"名称", "单价", "数量", "金额"})
+	_ = f.SetSheetRow(sheet, "A3", &[]interface{}{"{{range .Items}}{{.Name}}", "{{.Price}}", "{{.Count}}"})
+	_ = f.SetCellFormula(sheet, "D3", "B3*C3")
+	_ = f.SetCellStr(sheet, "E3", "{{end}}")
+	style, _ := f.NewStyle(&excelize.Style{NumFmt: 2})
+	_ = f.SetCellStyle(sheet, "B3", "B3", style)
+	_ = f.SetRowHeight(sheet, 3, 20)
+	_ = f.SetCellStr(sheet, "A4", "合计")
+	_ = f.SetCellFormula(sheet, "D4", "SUM(D3:D3)")
+	_ = f.SetCellStr(sheet, "A5", "制表人：{{.Author}}")
+	_ = f.MergeCell(sheet, "A5", "D5")
+
+	buf := new(bytes.Buffer)
+	if err := f.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := builder2.OpenTemplateReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tpl
+}
+
+func TestTemplateExport(t *testing.T) {
+	data := &templateOrder{
+		Title:  "订单",
+		Author: "张三",
+		Items: []*templateItem{
+			{Name: "苹果", Price: 1.5, Count: 2},
+			{Name: "香蕉", Price: 2, Count: 3},
+			{Name: "橙子", Price: 3, Count: 1},
+		},
+	}
+	buf := new(bytes.Buffer)
+	tpl := newOrderTemplate(t).SetData(data)
+	if err := builder2.NewFile().SetTemplate(tpl).ExportFile(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet := "Sheet1"
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0][0] != "订单" || rows[2][0] != "苹果" || rows[4][0] != "橙子" || rows[5][0] != "合计" || rows[6][0] != "制表人：张三" {
+		t.Fatalf("rows: %q", rows)
+	}
+	if len(rows[2]) > 4 && rows[2][4] != "" {
+		t.Fatalf("end marker not removed: %q", rows[2])
+	}
+	// 数字保持数字类型，样式复制到每一行
+	if cellType, _ := f.GetCellType(sheet, "C4"); cellType == excelize.CellTypeString {
+		t.Fatalf("count type: %v", cellType)
+	}
+	style3, _ := f.GetCellStyle(sheet, "B3")
+	style5, _ := f.GetCellStyle(sheet, "B5")
+	if style3 == 0 || style3 != style5 {
+		t.Fatalf("style: %d %d", style3, style5)
+	}
+	if height, _ := f.GetRowHeight(sheet, 5); height != 20 {
+		t.Fatalf("height: %v", height)
+	}
+	// 公式按行偏移，合计扩展到所有明细行
+	for axis, want := range map[string]string{"D3": "B3*C3", "D4": "B4*C4", "D5": "B5*C5", "D6": "SUM(D3:D5)"} {
+		if formula, _ := f.GetCellFormula(sheet, axis); formula != want {
+			t.Fatalf("%s formula: %s", axis, formula)
+		}
+	}
+	// 区域下方的合并单元格下移
+	merges, _ := f.GetMergeCells(sheet)
+	found := false
+	for _, merge := range merges {
+		if merge.GetStartAxis() == "A7" && merge.GetEndAxis() == "D7" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("merge not shifted")
+	}
+}
+
+func TestTemplateEmptyRange(t *testing.T) {
+	tpl := newOrderTemplate(t).SetData(map[string]interface{}{"Title": "空订单", "Author": "李四"})
+	f, err := tpl.Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "空订单" || rows[2][0] != "合计" || rows[3][0] != "制表人：李四" {
+		t.Fatalf("rows: %q", rows)
+	}
+	// 明细行全部删除后合计不引用上方的表头
+	if formula, _ := f.GetCellFormula("Sheet1", "D3"); formula != "SUM(0)" {
+		t.Fatalf("sum formula: %s", formula)
+	}
+}
+
+func TestTemplateMultiRange(t *testing.T) {
+	f := excelize.NewFile()
+	sheet := "Sheet1"
+	_ = f.SetSheetRow(sheet, "A1", &[]interface{}{"{{range .In}}{{.Price}}", "{{.Count}}"})
+	_ = f.SetCellFormula(sheet, "C1", "A1*B1")
+	_ = f.SetCellStr(sheet, "D1", "{{end}}")
+	_ = f.SetCellStr(sheet, "A2", "出库")
+	_ = f.SetSheetRow(sheet, "A3", &[]interface{}{"{{range .Out}}{{.Price}}", "{{.Count}}"})
+	_ = f.SetCellFormula(sheet, "C3", "A3*B3")
+	_ = f.SetCellStr(sheet, "D3", "{{end}}")
+	_ = f.SetCellStr(sheet, "A4", "合计")
+	_ = f.SetCellFormula(sheet, "C4", "SUM(C1:C1)-SUM(C3:C3)")
+	buf := new(bytes.Buffer)
+	if err := f.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := builder2.OpenTemplateReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := func(n int) []*templateItem {
+		list := make([]*templateItem, n)
+		for i := range list {
+			list[i] = &templateItem{Price: float64(i + 1), Count: 1}
+		}
+		return list
+	}
+	out, err := tpl.SetData(map[string]interface{}{"In": items(3), "Out": items(2)}).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 下方区域展开的公式随上方区域插入的行一起下移
+	for axis, want := range map[string]string{
+		"C1": "A1*B1", "C2": "A2*B2", "C3": "A3*B3",
+		"C5": "A5*B5", "C6": "A6*B6",
+		"C7": "SUM(C1:C3)-SUM(C5:C6)",
+	} {
+		if formula, _ := out.GetCellFormula(sheet, axis); formula != want {
+			t.Fatalf("%s formula: %s", axis, formula)
+		}
+	}
+	if value, _ := out.GetCellValue(sheet, "A4"); value != "出库" {
+		t.Fatalf("A4: %s", value)
+	}
+}
+
+func TestTemplateFunc(t *testing.T) {
+	f := excelize.NewFile()
+	_ = f.SetCellStr("Sheet1", "A1", "{{upper .Title}}")
+	_ = f.SetCellStr("Sheet1", "A2", "{{range .Items}}")
+	tpl := builder2.NewTemplate(f).AddFunc("upper", func(s string) string { return "《" + s + "》" }).
+		SetData(map[string]interface{}{"Title": "标题"})
+	if _, err := tpl.Execute(); err == nil {
+		t.Fatal("range not closed")
+	}
+	_ = f.SetCellStr("Sheet1", "A2", "")
+	if _, err := tpl.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := f.GetCellValue("Sheet1", "A1"); value != "《标题》" {
+		t.Fatalf("value: %s", value)
+	}
+}
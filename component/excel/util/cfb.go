@@ -0,0 +1,242 @@
+package util
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// 复合文档（Compound File Binary，MS-CFB）版本3的常量，加密后的 xlsx 以复合文档保存
+const (
+	cfbSectorSize     = 512
+	cfbMiniSectorSize = 64
+	cfbMiniCutoff     = 4096
+	cfbDirEntrySize   = 128
+	cfbFatPerSector   = cfbSectorSize / 4
+	cfbHeaderDifat    = 109
+
+	cfbFreeSect   uint32 = 0xFFFFFFFF
+	cfbEndOfChain uint32 = 0xFFFFFFFE
+	cfbFatSect    uint32 = 0xFFFFFFFD
+	cfbDifSect    uint32 = 0xFFFFFFFC
+	cfbNoStream   uint32 = 0xFFFFFFFF
+)
+
+// cfbStream 复合文档根目录下的一个流
+type cfbStream struct {
+	name string
+	data []byte
+}
+
+// cfbWriter 按顺序分配扇区
+type cfbWriter struct {
+	fat  []uint32
+	body []byte
+}
+
+// alloc 把数据写入连续的扇区，返回第一个扇区，不足一个扇区时用 pad 填充
+func (w *cfbWriter) alloc(data []byte, pad byte) uint32 {
+	n := (len(data) + cfbSectorSize - 1) / cfbSectorSize
+	if n == 0 {
+		return cfbEndOfChain
+	}
+	first := uint32(len(w.fat))
+	for i := 1; i < n; i++ {
+		w.fat = append(w.fat, first+uint32(i))
+	}
+	w.fat = append(w.fat, cfbEndOfChain)
+	w.body = append(w.body, data...)
+	for len(w.body)%cfbSectorSize != 0 {
+		w.body = append(w.body, pad)
+	}
+	return first
+}
+
+// cfbLess 目录项名称的排序：先比较长度，再比较大写后的字符
+func cfbLess(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	if len(ua) != len(ub) {
+		return len(ua) < len(ub)
+	}
+	return strings.ToUpper(a) < strings.ToUpper(b)
+}
+
+// cfbTree 按排序后的目录项构建平衡二叉树，返回根的下标，left、right 为每个目录项的左右兄弟
+func cfbTree(ids []int, left, right []uint32) uint32 {
+	if len(ids) == 0 {
+		return cfbNoStream
+	}
+	mid := len(ids) / 2
+	id := ids[mid]
+	left[id] = cfbTree(ids[:mid], left, right)
+	right[id] = cfbTree(ids[mid+1:], left, right)
+	return uint32(id)
+}
+
+// cfbDirEntry 目录项
+func cfbDirEntry(name string, objectType byte, left, right, child, start uint32, size int) []byte {
+	entry := make([]byte, cfbDirEntrySize)
+	if name != "" {
+		units := utf16.Encode([]rune(name))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(entry[i*2:], u)
+		}
+		binary.LittleEndian.PutUint16(entry[64:], uint16((len(units)+1)*2))
+	}
+	entry[66] = objectType
+	if objectType != 0 {
+		entry[67] = 1 // 全部为黑色
+	}
+	binary.LittleEndian.PutUint32(entry[68:], left)
+	binary.LittleEndian.PutUint32(entry[72:], right)
+	binary.LittleEndian.PutUint32(entry[76:], child)
+	binary.LittleEndian.PutUint32(entry[116:], start)
+	binary.LittleEndian.PutUint32(entry[120:], uint32(size))
+	return entry
+}
+
+// uint32Bytes 小端序
+func uint32Bytes(values []uint32) []byte {
+	buf := make([]byte, len(values)*4)
+	for i, value := range values {
+		binary.LittleEndian.PutUint32(buf[i*4:], value)
+	}
+	return buf
+}
+
+// compoundFile 生成只有根目录与若干个流的复合文档，小于 4096 字节的流保存在 mini stream 中；
+// 流的名称不能超过31个字符
+func compoundFile(streams []cfbStream) []byte {
+	w := new(cfbWriter)
+	starts := make([]uint32, len(streams))
+
+	// 小的流写入 mini stream
+	var mini []byte
+	var miniFat []uint32
+	for i, stream := range streams {
+		if len(stream.data) >= cfbMiniCutoff {
+			continue
+		}
+		n := (len(stream.data) + cfbMiniSectorSize - 1) / cfbMiniSectorSize
+		if n == 0 {
+			starts[i] = cfbEndOfChain
+			continue
+		}
+		first := uint32(len(miniFat))
+		for j := 1; j < n; j++ {
+			miniFat = append(miniFat, first+uint32(j))
+		}
+		miniFat = append(miniFat, cfbEndOfChain)
+		starts[i] = first
+		mini = append(mini, stream.data...)
+		for len(mini)%cfbMiniSectorSize != 0 {
+			mini = append(mini, 0)
+		}
+	}
+	for i, stream := range streams {
+		if len(stream.data) >= cfbMiniCutoff {
+			starts[i] = w.alloc(stream.data, 0)
+		}
+	}
+	miniStart := w.alloc(mini, 0)
+	miniFatStart := w.alloc(uint32Bytes(miniFat), 0xFF)
+	miniFatSectors := (len(miniFat)*4 + cfbSectorSize - 1) / cfbSectorSize
+
+	// 目录：根目录 + 每个流
+	ids := make([]int, len(streams))
+	for i := range streams {
+		ids[i] = i + 1
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return cfbLess(streams[ids[i]-1].name, streams[ids[j]-1].name)
+	})
+	left := make([]uint32, len(streams)+1)
+	right := make([]uint32, len(streams)+1)
+	child := cfbTree(ids, left, right)
+	dir := cfbDirEntry("Root Entry", 5, cfbNoStream, cfbNoStream, child, miniStart, len(mini))
+	for i, stream := range streams {
+		dir = append(dir, cfbDirEntry(stream.name, 2, left[i+1], right[i+1], cfbNoStream, starts[i], len(stream.data))...)
+	}
+	for len(dir)%cfbSectorSize != 0 {
+		dir = append(dir, cfbDirEntry("", 0, cfbNoStream, cfbNoStream, cfbNoStream, 0, 0)...)
+	}
+	dirStart := w.alloc(dir, 0)
+
+	// FAT 扇区与 DIFAT 扇区自身也要记录在 FAT 中
+	fatSectors, difatSectors := 0, 0
+	for {
+		total := len(w.fat) + fatSectors + difatSectors
+		needFat := (total + cfbFatPerSector - 1) / cfbFatPerSector
+		needDifat := 0
+		if needFat > cfbHeaderDifat {
+			needDifat = (needFat - cfbHeaderDifat + cfbFatPerSector - 2) / (cfbFatPerSector - 1)
+		}
+		if needFat == fatSectors && needDifat == difatSectors {
+			break
+		}
+		fatSectors, difatSectors = needFat, needDifat
+	}
+	fatStart := uint32(len(w.fat))
+	for i := 0; i < fatSectors; i++ {
+		w.fat = append(w.fat, cfbFatSect)
+	}
+	difatStart := uint32(len(w.fat))
+	for i := 0; i < difatSectors; i++ {
+		w.fat = append(w.fat, cfbDifSect)
+	}
+	fat := append([]uint32{}, w.fat...)
+	for len(fat) < fatSectors*cfbFatPerSector {
+		fat = append(fat, cfbFreeSect)
+	}
+	body := append(w.body, uint32Bytes(fat)...)
+
+	// DIFAT：前109个 FAT 扇区记录在文件头，其余记录在 DIFAT 扇区，每个扇区最后4字节指向下一个 DIFAT 扇区
+	difat := make([]uint32, 0, cfbHeaderDifat+difatSectors*cfbFatPerSector)
+	for i := 0; i < fatSectors; i++ {
+		difat = append(difat, fatStart+uint32(i))
+	}
+	for i := 0; i < difatSectors; i++ {
+		sector := make([]uint32, 0, cfbFatPerSector)
+		for j := 0; j < cfbFatPerSector-1; j++ {
+			k := cfbHeaderDifat + i*(cfbFatPerSector-1) + j
+			if k < len(difat) {
+				sector = append(sector, difat[k])
+			} else {
+				sector = append(sector, cfbFreeSect)
+			}
+		}
+		next := cfbEndOfChain
+		if i < difatSectors-1 {
+			next = difatStart + uint32(i) + 1
+		}
+		body = append(body, uint32Bytes(append(sector, next))...)
+	}
+
+	header := make([]byte, cfbSectorSize)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	binary.LittleEndian.PutUint16(header[24:], 0x003E)
+	binary.LittleEndian.PutUint16(header[26:], 0x0003)
+	binary.LittleEndian.PutUint16(header[28:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[30:], 9)
+	binary.LittleEndian.PutUint16(header[32:], 6)
+	binary.LittleEndian.PutUint32(header[44:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[48:], dirStart)
+	binary.LittleEndian.PutUint32(header[56:], cfbMiniCutoff)
+	binary.LittleEndian.PutUint32(header[60:], miniFatStart)
+	binary.LittleEndian.PutUint32(header[64:], uint32(miniFatSectors))
+	firstDifat := cfbEndOfChain
+	if difatSectors > 0 {
+		firstDifat = difatStart
+	}
+	binary.LittleEndian.PutUint32(header[68:], firstDifat)
+	binary.LittleEndian.PutUint32(header[72:], uint32(difatSectors))
+	for i := 0; i < cfbHeaderDifat; i++ {
+		value := cfbFreeSect
+		if i < len(difat) {
+			value = difat[i]
+		}
+		binary.LittleEndian.PutUint32(header[76+i*4:], value)
+	}
+	return append(header, body...)
+}
@@ -0,0 +1,199 @@
+package util
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// ECMA-376 agile 加密的参数，与 Excel 默认一致：AES-256-CBC、SHA512、10万次迭代
+const (
+	encryptSaltSize  = 16
+	encryptBlockSize = 16
+	encryptKeyBits   = 256
+	encryptHashSize  = 64
+	encryptSpinCount = 100000
+	encryptChunkSize = 4096
+)
+
+var (
+	encryptBlockKey          = []byte{0x14, 0x6e, 0x0b, 0xe7, 0xab, 0xac, 0xd0, 0xd6}
+	encryptBlockHmacKey      = []byte{0x5f, 0xb2, 0xad, 0x01, 0x0c, 0xb9, 0xe1, 0xf6}
+	encryptBlockHmacValue    = []byte{0xa0, 0x67, 0x7f, 0x02, 0xb2, 0x2c, 0x84, 0x33}
+	encryptBlockVerifierIn   = []byte{0xfe, 0xa7, 0xd2, 0x76, 0x3b, 0x4b, 0x9e, 0x79}
+	encryptBlockVerifierHash = []byte{0xd7, 0xaa, 0x0f, 0x6d, 0x30, 0x61, 0x34, 0x4e}
+)
+
+const encryptionInfoXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\r\n" +
+	`<encryption xmlns="http://schemas.microsoft.com/office/2006/encryption" ` +
+	`xmlns:p="http://schemas.microsoft.com/office/2006/keyEncryptor/password" ` +
+	`xmlns:c="http://schemas.microsoft.com/office/2006/keyEncryptor/certificate">` +
+	`<keyData saltSize="%[1]d" blockSize="%[2]d" keyBits="%[3]d" hashSize="%[4]d" cipherAlgorithm="AES" ` +
+	`cipherChaining="ChainingModeCBC" hashAlgorithm="SHA512" saltValue="%[5]s"/>` +
+	`<dataIntegrity encryptedHmacKey="%[6]s" encryptedHmacValue="%[7]s"/>` +
+	`<keyEncryptors><keyEncryptor uri="http://schemas.microsoft.com/office/2006/keyEncryptor/password">` +
+	`<p:encryptedKey spinCount="%[8]d" saltSize="%[1]d" blockSize="%[2]d" keyBits="%[3]d" hashSize="%[4]d" ` +
+	`cipherAlgorithm="AES" cipherChaining="ChainingModeCBC" hashAlgorithm="SHA512" saltValue="%[9]s" ` +
+	`encryptedVerifierHashInput="%[10]s" encryptedVerifierHashValue="%[11]s" encryptedKeyValue="%[12]s"/>` +
+	`</keyEncryptor></keyEncryptors></encryption>`
+
+// EncryptXlsx 用密码加密 xlsx 文件（ECMA-376 agile 加密），打开时需要输入密码；
+// excelize v2.5.0 的 Encrypt 还不支持生成加密文件，由这里实现，参数与 Excel 默认的加密方式相同
+func EncryptXlsx(raw []byte, password string) ([]byte, error) {
+	packageKey, err := randomBytes(encryptKeyBits / 8)
+	if err != nil {
+		return nil, err
+	}
+	keyDataSalt, err := randomBytes(encryptSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	passwordSalt, err := randomBytes(encryptSaltSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按4096字节分块加密，每块的 IV 由块序号生成
+	encryptedPackage := make([]byte, 8, 8+len(raw)+encryptBlockSize)
+	binary.LittleEndian.PutUint64(encryptedPackage, uint64(len(raw)))
+	for i := 0; i*encryptChunkSize < len(raw); i++ {
+		end := (i + 1) * encryptChunkSize
+		if end > len(raw) {
+			end = len(raw)
+		}
+		blockIndex := make([]byte, 4)
+		binary.LittleEndian.PutUint32(blockIndex, uint32(i))
+		chunk, err := aesCBC(packageKey, encryptIV(keyDataSalt, blockIndex), raw[i*encryptChunkSize:end])
+		if err != nil {
+			return nil, err
+		}
+		encryptedPackage = append(encryptedPackage, chunk...)
+	}
+
+	// 数据完整性校验
+	hmacKey, err := randomBytes(encryptHashSize)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha512.New, hmacKey)
+	_, _ = mac.Write(encryptedPackage)
+	encryptedHmacKey, err := aesCBC(packageKey, encryptIV(keyDataSalt, encryptBlockHmacKey), hmacKey)
+	if err != nil {
+		return nil, err
+	}
+	encryptedHmacValue, err := aesCBC(packageKey, encryptIV(keyDataSalt, encryptBlockHmacValue), mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	// 用密码生成的密钥加密校验值与 packageKey
+	passwordHash := passwordIterHash(password, passwordSalt)
+	verifierInput, err := randomBytes(encryptSaltSize)
+	if err != nil {
+		return nil, err
+	}
+	encryptedVerifierInput, err := aesCBC(passwordKey(passwordHash, encryptBlockVerifierIn), passwordSalt, verifierInput)
+	if err != nil {
+		return nil, err
+	}
+	verifierHash := sha512.Sum512(verifierInput)
+	encryptedVerifierHash, err := aesCBC(passwordKey(passwordHash, encryptBlockVerifierHash), passwordSalt, verifierHash[:])
+	if err != nil {
+		return nil, err
+	}
+	encryptedKeyValue, err := aesCBC(passwordKey(passwordHash, encryptBlockKey), passwordSalt, packageKey)
+	if err != nil {
+		return nil, err
+	}
+
+	b64 := base64.StdEncoding.EncodeToString
+	info := bytes.NewBuffer([]byte{0x04, 0x00, 0x04, 0x00, 0x40, 0x00, 0x00, 0x00}) // 版本4.4，agile
+	_, _ = fmt.Fprintf(info, encryptionInfoXML, encryptSaltSize, encryptBlockSize, encryptKeyBits, encryptHashSize,
+		b64(keyDataSalt), b64(encryptedHmacKey), b64(encryptedHmacValue), encryptSpinCount, b64(passwordSalt),
+		b64(encryptedVerifierInput), b64(encryptedVerifierHash), b64(encryptedKeyValue))
+
+	return compoundFile([]cfbStream{
+		{name: "EncryptionInfo", data: info.Bytes()},
+		{name: "EncryptedPackage", data: encryptedPackage},
+	}), nil
+}
+
+// ProtectionHash 工作簿、工作表保护密码的 SHA-512 哈希，返回算法名、base64 的哈希值、盐与迭代次数
+func ProtectionHash(password string) (algorithm, hash, salt string, spinCount int, err error) {
+	saltBytes, err := randomBytes(encryptSaltSize)
+	if err != nil {
+		return
+	}
+	h := sha512.Sum512(append(append([]byte{}, saltBytes...), utf16LE(password)...))
+	sum := h[:]
+	iterator := make([]byte, 4)
+	for i := 0; i < encryptSpinCount; i++ {
+		binary.LittleEndian.PutUint32(iterator, uint32(i))
+		h = sha512.Sum512(append(sum, iterator...))
+		sum = h[:]
+	}
+	return "SHA-512", base64.StdEncoding.EncodeToString(sum), base64.StdEncoding.EncodeToString(saltBytes), encryptSpinCount, nil
+}
+
+// passwordIterHash 加密密码的迭代哈希：H0 = H(salt + password)，Hn = H(iterator + Hn-1)
+func passwordIterHash(password string, salt []byte) []byte {
+	h := sha512.Sum512(append(append([]byte{}, salt...), utf16LE(password)...))
+	sum := h[:]
+	buf := make([]byte, 4+len(sum))
+	for i := 0; i < encryptSpinCount; i++ {
+		binary.LittleEndian.PutUint32(buf, uint32(i))
+		copy(buf[4:], sum)
+		h = sha512.Sum512(buf)
+		sum = h[:]
+	}
+	return sum
+}
+
+// passwordKey 由迭代哈希与 blockKey 生成密钥
+func passwordKey(passwordHash, blockKey []byte) []byte {
+	h := sha512.Sum512(append(append([]byte{}, passwordHash...), blockKey...))
+	return h[:encryptKeyBits/8]
+}
+
+// encryptIV 由盐与 blockKey 生成 IV
+func encryptIV(salt, blockKey []byte) []byte {
+	h := sha512.Sum512(append(append([]byte{}, salt...), blockKey...))
+	return h[:encryptBlockSize]
+}
+
+// aesCBC AES-CBC 加密，不足一个块时补0
+func aesCBC(key, iv, input []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	size := (len(input) + encryptBlockSize - 1) / encryptBlockSize * encryptBlockSize
+	output := make([]byte, size)
+	copy(output, input)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(output, output)
+	return output, nil
+}
+
+// utf16LE 密码按 UTF-16LE 编码
+func utf16LE(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[i*2:], u)
+	}
+	return buf
+}
+
+// randomBytes 安全的随机数
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	return b, err
+}
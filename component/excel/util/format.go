@@ -0,0 +1,153 @@
+package util
+
+import (
+	"strings"
+)
+
+// IsExcelDateFormat 数字格式是否为 Excel 的日期时间格式（包含 y、m、d、h、s 占位符）
+func IsExcelDateFormat(format string) bool {
+	return strings.ContainsAny(strings.ToLower(stripExcelLiteral(format)), "ymdhs")
+}
+
+// stripExcelLiteral 去掉引号、方括号与转义中的内容
+func stripExcelLiteral(format string) string {
+	var builder strings.Builder
+	inQuote, inBracket := false, false
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// ExcelFormatToLayout 把 Excel 的日期时间格式转为 Go 的时间格式，如 yyyy-mm-dd hh:mm:ss 转为 2006-01-02 15:04:05；
+// m 在 h 之后或者 s 之前时为分钟，否则为月份
+func ExcelFormatToLayout(format string) string {
+	type token struct {
+		char   byte
+		length int
+		text   string
+	}
+	tokens := make([]token, 0, len(format))
+	lower := strings.ToLower(format)
+	for i := 0; i < len(format); {
+		c := lower[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(format[i+1:], '"')
+			if end < 0 {
+				end = len(format) - i - 1
+			}
+			tokens = append(tokens, token{text: format[i+1 : i+1+end]})
+			i += end + 2
+		case c == '\\' && i+1 < len(format):
+			tokens = append(tokens, token{text: format[i+1 : i+2]})
+			i += 2
+		case c == '[':
+			end := strings.IndexByte(format[i:], ']')
+			if end < 0 {
+				end = len(format) - i - 1
+			}
+			i += end + 1
+		case strings.HasPrefix(lower[i:], "am/pm"):
+			tokens = append(tokens, token{text: "PM"})
+			i += len("am/pm")
+		case c == 'y' || c == 'm' || c == 'd' || c == 'h' || c == 's':
+			n := 1
+			for i+n < len(lower) && lower[i+n] == c {
+				n++
+			}
+			tokens = append(tokens, token{char: c, length: n})
+			i += n
+		default:
+			tokens = append(tokens, token{text: format[i : i+1]})
+			i++
+		}
+	}
+
+	hour12 := false
+	for _, t := range tokens {
+		hour12 = hour12 || t.text == "PM"
+	}
+	var builder strings.Builder
+	for i, t := range tokens {
+		switch t.char {
+		case 'y':
+			if t.length <= 2 {
+				builder.WriteString("06")
+			} else {
+				builder.WriteString("2006")
+			}
+		case 'm':
+			minute := false
+			for j := i - 1; j >= 0; j-- {
+				if tokens[j].char != 0 {
+					minute = tokens[j].char == 'h'
+					break
+				}
+			}
+			for j := i + 1; j < len(tokens) && !minute; j++ {
+				if tokens[j].char != 0 {
+					minute = tokens[j].char == 's'
+					break
+				}
+			}
+			switch {
+			case minute && t.length == 1:
+				builder.WriteString("4")
+			case minute:
+				builder.WriteString("04")
+			case t.length == 1:
+				builder.WriteString("1")
+			case t.length == 2:
+				builder.WriteString("01")
+			case t.length == 3:
+				builder.WriteString("Jan")
+			default:
+				builder.WriteString("January")
+			}
+		case 'd':
+			switch t.length {
+			case 1:
+				builder.WriteString("2")
+			case 2:
+				builder.WriteString("02")
+			case 3:
+				builder.WriteString("Mon")
+			default:
+				builder.WriteString("Monday")
+			}
+		case 'h':
+			switch {
+			case !hour12:
+				builder.WriteString("15")
+			case t.length == 1:
+				builder.WriteString("3")
+			default:
+				builder.WriteString("03")
+			}
+		case 's':
+			if t.length == 1 {
+				builder.WriteString("5")
+			} else {
+				builder.WriteString("05")
+			}
+		default:
+			builder.WriteString(t.text)
+		}
+	}
+	return builder.String()
+}
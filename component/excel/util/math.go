@@ -1,11 +1,22 @@
 package util
 
 import (
+	"encoding/json"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
-// NumToString 将数字转为string（TODO 存在float64转string的话可能会失真）
+// MaxNumDigits Excel 数字的最大有效位数，超出的部分会丢失精度
+const MaxNumDigits = 15
+
+// Decimal 十进制数，兼容 github.com/shopspring/decimal 的 Decimal
+type Decimal interface {
+	String() string
+	Float64() (f float64, exact bool)
+}
+
+// NumToString 将数字转为string，float32 按32位格式化，不会出现 0.10000000149011612 这样的误差
 func NumToString(s interface{}) (bool, string) {
 	field := reflect.ValueOf(s)
 	if field.Kind() == reflect.Ptr {
@@ -16,9 +27,75 @@ func NumToString(s interface{}) (bool, string) {
 		return true, strconv.FormatInt(field.Int(), 10)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		return true, strconv.FormatUint(field.Uint(), 10)
-	case reflect.Float32, reflect.Float64:
+	case reflect.Float32:
+		return true, strconv.FormatFloat(field.Float(), 'f', -1, 32)
+	case reflect.Float64:
 		return true, strconv.FormatFloat(field.Float(), 'f', -1, 64)
 	default:
 		return false, ""
 	}
 }
+
+// NumToNative 将数字转为保持数字类型写入的值：超过 MaxNumDigits 位有效数字的整数与十进制数转为文本，
+// 十进制数（Decimal、json.Number）按文本解析为 float64，不经过浮点运算，不会产生误差；不是数字时返回 false
+func NumToNative(s interface{}) (bool, interface{}) {
+	switch v := s.(type) {
+	case json.Number:
+		return decimalToNative(v.String())
+	case Decimal:
+		return decimalToNative(v.String())
+	}
+	field := reflect.ValueOf(s)
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return false, nil
+		}
+		return NumToNative(field.Elem().Interface())
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		text := strconv.FormatInt(field.Int(), 10)
+		if numDigits(text) > MaxNumDigits {
+			return true, text
+		}
+		return true, field.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		text := strconv.FormatUint(field.Uint(), 10)
+		if numDigits(text) > MaxNumDigits {
+			return true, text
+		}
+		return true, field.Uint()
+	case reflect.Float32:
+		// 按32位的文本转为 float64，避免 float32 直接转换产生的误差
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(field.Float(), 'f', -1, 32), 64)
+		return true, f
+	case reflect.Float64:
+		return true, field.Float()
+	default:
+		return false, nil
+	}
+}
+
+// decimalToNative 十进制数的文本，有效数字不超过 MaxNumDigits 位时转为 float64，否则保持文本
+func decimalToNative(text string) (bool, interface{}) {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return false, nil
+	}
+	if numDigits(text) > MaxNumDigits {
+		return true, text
+	}
+	return true, f
+}
+
+// numDigits 数字文本的有效位数：去掉符号、指数、整数部分开头的0与小数部分末尾的0
+func numDigits(text string) int {
+	text = strings.TrimLeft(text, "+-")
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		text = text[:i]
+	}
+	if i := strings.IndexByte(text, '.'); i >= 0 {
+		text = strings.TrimRight(text[:i]+text[i+1:], "0")
+	}
+	return len(strings.TrimLeft(text, "0"))
+}
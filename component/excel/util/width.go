@@ -0,0 +1,166 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/width"
+)
+
+// generalNumWidth 常规格式下数字最多显示的字符数，超出时 Excel 使用科学计数法
+const generalNumWidth = 11
+
+// StringWidth 文本的显示宽度：中日韩文字与全角字符算2个字符，多行文本取最长的一行
+func StringWidth(s string) int {
+	max := 0
+	for _, line := range strings.Split(s, "\n") {
+		w := 0
+		for _, r := range line {
+			switch width.LookupRune(r).Kind() {
+			case width.EastAsianWide, width.EastAsianFullwidth:
+				w += 2
+			default:
+				w++
+			}
+		}
+		if w > max {
+			max = w
+		}
+	}
+	return max
+}
+
+// ValueWidth 单元格按数字格式显示时的宽度：时间按格式输出，数字按小数位数、千分位、百分比与格式中的文字估算
+func ValueWidth(value interface{}, format string) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case string:
+		return StringWidth(v)
+	case []byte:
+		return StringWidth(string(v))
+	case bool:
+		if v {
+			return len("TRUE")
+		}
+		return len("FALSE")
+	case time.Time:
+		if v.IsZero() {
+			return 0
+		}
+		if format == "" || !IsExcelDateFormat(format) {
+			// 没有格式时 excelize 使用 m/d/yy h:mm
+			return StringWidth(v.Format("1/2/06 15:04"))
+		}
+		return StringWidth(v.Format(ExcelFormatToLayout(format)))
+	case fmt.Stringer:
+		return StringWidth(v.String())
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return 0
+		}
+		rv = rv.Elem()
+	}
+	var f float64
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f = float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f = float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		f = rv.Float()
+	default:
+		return StringWidth(fmt.Sprint(rv.Interface()))
+	}
+	if format == "" || strings.EqualFold(format, "General") || IsExcelDateFormat(format) {
+		w := len(strconv.FormatFloat(f, 'f', -1, 64))
+		if w > generalNumWidth {
+			w = generalNumWidth
+		}
+		return w
+	}
+	return numFormatWidth(f, format)
+}
+
+// numFormatWidth 数字按格式显示的宽度，只处理正数的格式段
+func numFormatWidth(f float64, format string) int {
+	section := format
+	if i := strings.IndexByte(format, ';'); i >= 0 {
+		section = format[:i]
+	}
+	decimals, literal := 0, 0
+	thousands, percent, afterPoint := false, false, false
+	for i := 0; i < len(section); i++ {
+		c := section[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(section[i+1:], '"')
+			if end < 0 {
+				end = len(section) - i - 1
+			}
+			literal += StringWidth(section[i+1 : i+1+end])
+			i += end + 1
+		case c == '[':
+			end := strings.IndexByte(section[i:], ']')
+			if end < 0 {
+				end = len(section) - i
+			}
+			// [$¥-804] 等货币符号
+			if text := section[i+1 : i+end]; strings.HasPrefix(text, "$") {
+				symbol := strings.SplitN(text[1:], "-", 2)[0]
+				literal += StringWidth(symbol)
+			}
+			i += end
+		case c == '\\' || c == '_':
+			if i+1 < len(section) {
+				literal++
+			}
+			i++
+		case c == '*':
+			i++
+		case c == '.':
+			afterPoint = true
+		case c == '0' || c == '#' || c == '?':
+			if afterPoint {
+				decimals++
+			}
+		case c == ',':
+			thousands = true
+		case c == '%':
+			percent = true
+			literal++
+		case c < 0x80:
+			literal++
+		default:
+			// 多字节字符，如 ¥、元
+			j := i + 1
+			for j < len(section) && section[j] >= 0x80 && section[j] < 0xC0 {
+				j++
+			}
+			literal += StringWidth(section[i:j])
+			i = j - 1
+		}
+	}
+	if percent {
+		f *= 100
+	}
+	text := strconv.FormatFloat(math.Abs(f), 'f', decimals, 64)
+	w := len(text)
+	if thousands {
+		intLen := len(text)
+		if i := strings.IndexByte(text, '.'); i >= 0 {
+			intLen = i
+		}
+		w += (intLen - 1) / 3
+	}
+	if f < 0 {
+		w++
+	}
+	return w + literal
+}
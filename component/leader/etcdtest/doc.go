@@ -0,0 +1,3 @@
+// Package etcdtest 使用进程内的 etcd 运行 leader 等依赖 etcd 的组件测试。
+// 单独作为一个模块，避免主模块依赖 go.etcd.io/etcd/server/v3
+package etcdtest
@@ -0,0 +1,10 @@
+module github.com/actorbuf/iota/component/leader/etcdtest
+
+go 1.16
+
+require (
+	github.com/actorbuf/iota v0.0.0
+	go.etcd.io/etcd/server/v3 v3.5.5
+)
+
+replace github.com/actorbuf/iota => ../../..
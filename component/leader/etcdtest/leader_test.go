@@ -0,0 +1,105 @@
+package etcdtest
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/actorbuf/iota/component/leader"
+	"github.com/actorbuf/iota/driver/etcd"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// freeURL 随机端口的本地地址
+func freeURL(t *testing.T) url.URL {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+// newTestClient 启动进程内的etcd，测试结束时关闭
+func newTestClient(t *testing.T) *etcd.Client {
+	config := embed.NewConfig()
+	config.Dir = t.TempDir()
+	config.LogLevel = "error"
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	config.LCUrls, config.ACUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	config.LPUrls, config.APUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	config.InitialCluster = config.InitialClusterFromName(config.Name)
+	server, err := embed.StartEtcd(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("embedded etcd not ready")
+	}
+
+	client, err := etcd.NewEtcdClient([]string{clientURL.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.GetClient().Close()
+	})
+	return client
+}
+
+func TestLeaderFailover(t *testing.T) {
+	client := newTestClient(t)
+	prefix := "/test/leader/" + time.Now().Format("20060102150405.000")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	elected := make(chan string, 2)
+	revoked := make(chan string, 2)
+	newNode := func(identity string) *leader.Leader {
+		return leader.NewLeader(client, prefix).SetIdentity(identity).SetTTL(5).
+			SetHandler(leader.HandlerFuncs{
+				Elected: func(ctx context.Context) {
+					elected <- identity
+					<-ctx.Done()
+				},
+				Revoked: func() { revoked <- identity },
+			})
+	}
+	a, b := newNode("a"), newNode("b")
+
+	if err := a.Campaign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-elected; got != "a" {
+		t.Fatalf("elected %s", got)
+	}
+	go b.Run(ctx)
+
+	observe := a.Observe(ctx)
+	if got := <-observe; got != "a" {
+		t.Fatalf("observe %s", got)
+	}
+
+	if err := a.Resign(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-revoked; got != "a" {
+		t.Fatalf("revoked %s", got)
+	}
+	if got := <-elected; got != "b" {
+		t.Fatalf("elected %s", got)
+	}
+	current, err := a.Leader(ctx)
+	if err != nil || current != "b" {
+		t.Fatalf("leader %s %v", current, err)
+	}
+	if a.IsLeader() || !b.IsLeader() {
+		t.Fatalf("is leader a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+	_ = b.Close()
+}
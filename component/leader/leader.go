@@ -0,0 +1,309 @@
+// Package leader 基于etcd session的leader选举
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/driver/etcd"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+var (
+	// ErrNoLeader 当前没有leader
+	ErrNoLeader = errors.New("leader: no leader")
+	// ErrAlreadyLeader 已经是leader，不需要再次竞选
+	ErrAlreadyLeader = errors.New("leader: already leader")
+	// ErrClientNil etcd client 未初始化
+	ErrClientNil = errors.New("leader: etcd client is nil")
+)
+
+// Handler 选举结果通知
+// OnElected 当选时调用（在新的协程中），ctx在失去leader时立即被cancel。
+// OnRevoked 失去leader时调用，调用前 OnElected 的ctx已经被cancel。
+type Handler interface {
+	OnElected(ctx context.Context)
+	OnRevoked()
+}
+
+// HandlerFuncs 通过方法实现 Handler
+type HandlerFuncs struct {
+	Elected func(ctx context.Context)
+	Revoked func()
+}
+
+func (h HandlerFuncs) OnElected(ctx context.Context) {
+	if h.Elected != nil {
+		h.Elected(ctx)
+	}
+}
+
+func (h HandlerFuncs) OnRevoked() {
+	if h.Revoked != nil {
+		h.Revoked()
+	}
+}
+
+// term 一次任期
+type term struct {
+	session  *concurrency.Session
+	election *concurrency.Election
+	cancel   context.CancelFunc
+	done     chan struct{} // 任期结束
+}
+
+// Leader leader选举
+type Leader struct {
+	client        *etcd.Client
+	prefix        string        // 选举的key前缀
+	identity      string        // 当前节点的标识
+	ttl           int           // session的ttl（秒）
+	retryInterval time.Duration // Run 中竞选失败后的重试间隔
+	handler       Handler
+
+	mu   sync.Mutex
+	term *term // 当前任期，不是leader时为nil
+}
+
+// NewLeader 创建选举；prefix 为选举的key前缀，如："/election/cron-worker"
+func NewLeader(client *etcd.Client, prefix string) *Leader {
+	host, _ := os.Hostname()
+	return &Leader{
+		client:        client,
+		prefix:        prefix,
+		identity:      fmt.Sprintf("%s-%d", host, os.Getpid()),
+		ttl:           10,
+		retryInterval: 3 * time.Second,
+		handler:       HandlerFuncs{},
+	}
+}
+
+// SetIdentity 设置当前节点标识，默认 hostname-pid
+func (l *Leader) SetIdentity(identity string) *Leader {
+	l.identity = identity
+	return l
+}
+
+// SetTTL 设置session的ttl（秒），默认10秒；失联超过ttl后leader会被其他节点取代
+func (l *Leader) SetTTL(ttl int) *Leader {
+	l.ttl = ttl
+	return l
+}
+
+// SetRetryInterval 设置 Run 中竞选失败后的重试间隔，默认3秒
+func (l *Leader) SetRetryInterval(interval time.Duration) *Leader {
+	l.retryInterval = interval
+	return l
+}
+
+// SetHandler 设置选举结果通知
+func (l *Leader) SetHandler(handler Handler) *Leader {
+	if handler == nil {
+		handler = HandlerFuncs{}
+	}
+	l.handler = handler
+	return l
+}
+
+// Identity 当前节点标识
+func (l *Leader) Identity() string {
+	return l.identity
+}
+
+// IsLeader 当前节点是否为leader
+func (l *Leader) IsLeader() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.term != nil
+}
+
+// Campaign 参与竞选，阻塞直到当选或者ctx结束
+// 当选后 OnElected 被调用；ctx结束、Resign、session过期或者leader key被删除都会失去leader
+func (l *Leader) Campaign(ctx context.Context) error {
+	if l.client == nil || l.client.GetClient() == nil {
+		return ErrClientNil
+	}
+	if l.IsLeader() {
+		return ErrAlreadyLeader
+	}
+
+	session, err := concurrency.NewSession(l.client.GetClient(), concurrency.WithTTL(l.ttl), concurrency.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	election := concurrency.NewElection(session, l.prefix)
+	if err = election.Campaign(ctx, l.identity); err != nil {
+		_ = session.Close()
+		return err
+	}
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	t := &term{
+		session:  session,
+		election: election,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	l.mu.Lock()
+	l.term = t
+	l.mu.Unlock()
+
+	go l.handler.OnElected(leaderCtx)
+	go l.monitor(leaderCtx, t)
+	return nil
+}
+
+// Run 持续参与竞选，失去leader后重新竞选，直到ctx结束
+func (l *Leader) Run(ctx context.Context) {
+	for {
+		err := l.Campaign(ctx)
+		if err == nil {
+			l.mu.Lock()
+			t := l.term
+			l.mu.Unlock()
+			if t != nil {
+				<-t.done
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(l.retryInterval):
+			}
+		}
+	}
+}
+
+// Resign 主动放弃leader
+func (l *Leader) Resign(ctx context.Context) error {
+	l.mu.Lock()
+	t := l.term
+	l.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+	// 先结束任期，保证业务在leader key删除前停止
+	l.revoke(t)
+	return t.election.Resign(ctx)
+}
+
+// Leader 获取当前leader的标识
+func (l *Leader) Leader(ctx context.Context) (string, error) {
+	if l.client == nil || l.client.GetClient() == nil {
+		return "", ErrClientNil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	resp, err := l.client.GetClient().Get(ctx, l.electionPrefix(), clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", ErrNoLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Observe 监听leader变化，ctx结束后chan关闭；没有leader时推送空字符串
+func (l *Leader) Observe(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		if l.client == nil || l.client.GetClient() == nil {
+			return
+		}
+		watchChan := l.client.GetClient().Watch(clientv3.WithRequireLeader(ctx), l.electionPrefix(), clientv3.WithPrefix())
+		last := ""
+		first := true
+		for {
+			current, err := l.Leader(ctx)
+			if err != nil && err != ErrNoLeader {
+				if ctx.Err() != nil {
+					return
+				}
+			} else if first || current != last {
+				select {
+				case ch <- current:
+				case <-ctx.Done():
+					return
+				}
+				first = false
+				last = current
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-watchChan:
+				if !ok || resp.Canceled {
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// Close 放弃leader并释放资源
+func (l *Leader) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return l.Resign(ctx)
+}
+
+// electionPrefix concurrency.Election 实际使用的前缀
+func (l *Leader) electionPrefix() string {
+	return l.prefix + "/"
+}
+
+// monitor 监控任期，session过期、leader key被删除或者ctx结束时失去leader
+func (l *Leader) monitor(ctx context.Context, t *term) {
+	watchChan := t.session.Client().Watch(clientv3.WithRequireLeader(ctx), t.election.Key(), clientv3.WithRev(t.election.Rev()+1))
+	for {
+		select {
+		case <-ctx.Done():
+			l.revoke(t)
+			return
+		case <-t.session.Done():
+			l.revoke(t)
+			return
+		case resp, ok := <-watchChan:
+			if !ok || resp.Err() != nil {
+				l.revoke(t)
+				return
+			}
+			for _, ev := range resp.Events {
+				if ev.Type == mvccpb.DELETE {
+					l.revoke(t)
+					return
+				}
+			}
+		}
+	}
+}
+
+// revoke 结束任期，只会执行一次
+func (l *Leader) revoke(t *term) {
+	l.mu.Lock()
+	if l.term != t {
+		l.mu.Unlock()
+		return
+	}
+	l.term = nil
+	l.mu.Unlock()
+
+	t.cancel()
+	// 释放session，租约撤销后leader key随之删除
+	_ = t.session.Close()
+	l.handler.OnRevoked()
+	close(t.done)
+}
@@ -0,0 +1,331 @@
+// Package redact 敏感信息脱敏：按字段名、正则与结构体 tag 匹配，支持部分打码、哈希与删除
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// TagName 结构体字段的脱敏 tag，取值 mask、hash、drop
+const TagName = "redact"
+
+// Redacted 删除策略用于文本时的替换内容
+const Redacted = "[REDACTED]"
+
+// Strategy 脱敏策略
+type Strategy int
+
+const (
+	// Mask 部分打码，保留前后若干位，中间替换为 *
+	Mask Strategy = iota
+	// Hash 替换为 HMAC-SHA256 的前 16 位，密钥相同时相同的值摘要相同，便于排查
+	Hash
+	// Drop 删除字段；用于文本时替换为 Redacted
+	Drop
+)
+
+// ParseStrategy 解析 tag 中的策略
+func ParseStrategy(s string) (Strategy, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "mask":
+		return Mask, true
+	case "hash":
+		return Hash, true
+	case "drop":
+		return Drop, true
+	}
+	return 0, false
+}
+
+var (
+	// PhonePattern 大陆手机号
+	PhonePattern = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+	// IDCardPattern 大陆身份证号
+	IDCardPattern = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	// BearerPattern Authorization 头中的 token
+	BearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[\w\-.~+/]+=*`)
+)
+
+// keyRule 字段名规则，pattern 为归一化后的通配符
+type keyRule struct {
+	pattern  string
+	strategy Strategy
+}
+
+// valueRule 内容规则
+type valueRule struct {
+	re       *regexp.Regexp
+	strategy Strategy
+}
+
+// Redactor 脱敏器，规则添加完成后可以并发使用
+type Redactor struct {
+	keys       []keyRule
+	values     []valueRule
+	keepPrefix int
+	keepSuffix int
+	hashKey    []byte
+}
+
+// New 创建没有规则的脱敏器，打码默认保留前 3 位与后 4 位；
+// 哈希使用随机密钥，需要跨进程比对摘要时通过 SetHashKey 设置相同的密钥
+func New() *Redactor {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &Redactor{keepPrefix: 3, keepSuffix: 4, hashKey: key}
+}
+
+// NewDefault 创建带常用规则的脱敏器：密码、token、secret 等字段打码，手机号与身份证号打码
+func NewDefault() *Redactor {
+	return New().
+		AddKey(Mask, "*password*", "*passwd*", "*token*", "*secret*", "authorization", "cookie").
+		AddPattern(Mask, PhonePattern, IDCardPattern, BearerPattern)
+}
+
+var (
+	_default   = NewDefault()
+	_defaultMu sync.RWMutex
+)
+
+// SetDefault 设置全局脱敏器，各组件未单独设置时使用；设置为 nil 时不脱敏
+func SetDefault(redactor *Redactor) {
+	_defaultMu.Lock()
+	defer _defaultMu.Unlock()
+	_default = redactor
+}
+
+// Default 获取全局脱敏器，默认为 NewDefault()
+func Default() *Redactor {
+	_defaultMu.RLock()
+	defer _defaultMu.RUnlock()
+	return _default
+}
+
+// SetKeep 设置打码保留的前后位数
+func (r *Redactor) SetKeep(prefix, suffix int) *Redactor {
+	r.keepPrefix = prefix
+	r.keepSuffix = suffix
+	return r
+}
+
+// SetHashKey 设置哈希策略的 HMAC 密钥；密钥应当保密，否则手机号等取值范围小的内容可以被穷举还原
+func (r *Redactor) SetHashKey(key []byte) *Redactor {
+	r.hashKey = append([]byte(nil), key...)
+	return r
+}
+
+// AddKey 添加字段名规则，忽略大小写、下划线与中划线，支持 * 通配符，如 *token* 匹配 access_token
+func (r *Redactor) AddKey(strategy Strategy, keys ...string) *Redactor {
+	for _, key := range keys {
+		r.keys = append(r.keys, keyRule{pattern: normalizeKey(key), strategy: strategy})
+	}
+	return r
+}
+
+// AddPattern 添加内容规则，字符串中匹配的部分按策略替换
+func (r *Redactor) AddPattern(strategy Strategy, patterns ...*regexp.Regexp) *Redactor {
+	for _, re := range patterns {
+		r.values = append(r.values, valueRule{re: re, strategy: strategy})
+	}
+	return r
+}
+
+// normalizeKey 字段名归一化
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(key)
+}
+
+// matchKey 字段名命中的策略
+func (r *Redactor) matchKey(key string) (Strategy, bool) {
+	key = normalizeKey(key)
+	for _, rule := range r.keys {
+		if ok, _ := path.Match(rule.pattern, key); ok {
+			return rule.strategy, true
+		}
+	}
+	return 0, false
+}
+
+// apply 按策略处理一个值
+func (r *Redactor) apply(strategy Strategy, s string) string {
+	switch strategy {
+	case Hash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		return "hmac:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	case Drop:
+		return Redacted
+	}
+	runes := []rune(s)
+	if len(runes) <= r.keepPrefix+r.keepSuffix {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:r.keepPrefix]) + strings.Repeat("*", len(runes)-r.keepPrefix-r.keepSuffix) +
+		string(runes[len(runes)-r.keepSuffix:])
+}
+
+// String 处理文本中匹配内容规则的部分
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, rule := range r.values {
+		strategy := rule.strategy
+		s = rule.re.ReplaceAllStringFunc(s, func(match string) string {
+			return r.apply(strategy, match)
+		})
+	}
+	return s
+}
+
+// JSON 处理 JSON 文本：解析后按字段名与内容规则处理再序列化；不是合法 JSON 时按普通文本处理。
+// 数字按 json.Number 原样保留，避免大整数经 float64 转换后丢失精度
+func (r *Redactor) JSON(s string) string {
+	if r == nil {
+		return s
+	}
+	var data interface{}
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return r.String(s)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return r.String(s)
+	}
+	switch data.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return r.String(s)
+	}
+	b, err := json.Marshal(r.Value(data))
+	if err != nil {
+		return r.String(s)
+	}
+	return string(b)
+}
+
+// Map 返回处理后的副本，原 map 不变
+func (r *Redactor) Map(m map[string]interface{}) map[string]interface{} {
+	if r == nil || m == nil {
+		return m
+	}
+	out := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if strategy, ok := r.matchKey(key); ok {
+			if strategy == Drop {
+				continue
+			}
+			out[key] = r.apply(strategy, fmt.Sprint(value))
+			continue
+		}
+		out[key] = r.Value(value)
+	}
+	return out
+}
+
+// Value 处理任意值：map 与结构体转换为 map[string]interface{}，切片转换为 []interface{}，
+// 结构体字段按 redact tag、json 名称与字段名规则处理；其他类型原样返回
+func (r *Redactor) Value(v interface{}) interface{} {
+	if r == nil || v == nil {
+		return v
+	}
+	switch value := v.(type) {
+	case string:
+		return r.String(value)
+	case map[string]interface{}:
+		return r.Map(value)
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i := range value {
+			out[i] = r.Value(value[i])
+		}
+		return out
+	case json.Number, fmt.Stringer, error:
+		return v
+	}
+	return r.reflectValue(reflect.ValueOf(v))
+}
+
+func (r *Redactor) reflectValue(rv reflect.Value) interface{} {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return r.String(rv.String())
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return rv.Interface()
+		}
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[iter.Key().String()] = iter.Value().Interface()
+		}
+		return r.Map(m)
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Interface()
+		}
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = r.reflectValue(rv.Index(i))
+		}
+		return out
+	case reflect.Struct:
+		return r.structValue(rv)
+	}
+	if rv.CanInterface() {
+		return rv.Interface()
+	}
+	return nil
+}
+
+// structValue 结构体按 json 名称转换为 map，redact tag 优先于字段名规则
+func (r *Redactor) structValue(rv reflect.Value) interface{} {
+	rt := rv.Type()
+	out := make(map[string]interface{}, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		strategy, ok := ParseStrategy(field.Tag.Get(TagName))
+		if !ok {
+			strategy, ok = r.matchKey(name)
+		}
+		if !ok {
+			out[name] = r.reflectValue(rv.Field(i))
+			continue
+		}
+		if strategy == Drop {
+			continue
+		}
+		out[name] = r.apply(strategy, fmt.Sprint(rv.Field(i).Interface()))
+	}
+	return out
+}
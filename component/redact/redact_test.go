@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type user struct {
+	Name     string `json:"name"`
+	Phone    string `json:"phone" redact:"mask"`
+	IDCard   string `json:"id_card" redact:"hash"`
+	Password string `json:"password"`
+	Remark   string `json:"remark" redact:"drop"`
+	Friends  []*user
+}
+
+func TestRedactorString(t *testing.T) {
+	r := NewDefault()
+	got := r.String("user 13800138000 id 11010519491231002X token Bearer abc.def")
+	if got != "user 138****8000 id 110***********002X token Bea*******.def" {
+		t.Fatalf("got: %s", got)
+	}
+	if got = New().AddPattern(Drop, regexp.MustCompile(`secret-\w+`)).String("k=secret-1"); got != "k="+Redacted {
+		t.Fatalf("got: %s", got)
+	}
+	var nilRedactor *Redactor
+	if nilRedactor.String("13800138000") != "13800138000" {
+		t.Fatal("nil redactor changed value")
+	}
+}
+
+func TestRedactorMap(t *testing.T) {
+	r := NewDefault().AddKey(Drop, "cvv").AddKey(Hash, "open_id")
+	in := map[string]interface{}{
+		"Access_Token": "abcdefghijk",
+		"cvv":          "123",
+		"openId":       "o-1",
+		"msg":          "call 13800138000",
+		"nested":       map[string]interface{}{"password": "p@ssw0rd1"},
+	}
+	out := r.Map(in)
+	if out["Access_Token"] != "abc****hijk" || out["msg"] != "call 138****8000" {
+		t.Fatalf("out: %v", out)
+	}
+	if _, has := out["cvv"]; has {
+		t.Fatalf("cvv not dropped: %v", out)
+	}
+	if !strings.HasPrefix(out["openId"].(string), "hmac:") {
+		t.Fatalf("open id: %v", out["openId"])
+	}
+	if out["nested"].(map[string]interface{})["password"] != "p@s**0rd1" {
+		t.Fatalf("nested: %v", out["nested"])
+	}
+	if in["cvv"] != "123" {
+		t.Fatal("input modified")
+	}
+}
+
+func TestRedactorStruct(t *testing.T) {
+	r := NewDefault()
+	u := &user{Name: "a", Phone: "13800138000", IDCard: "x", Password: "123456789", Remark: "r",
+		Friends: []*user{{Name: "b", Phone: "13900139000"}}}
+	out := r.Value(u).(map[string]interface{})
+	if out["phone"] != "138****8000" || out["password"] != "123**6789" || out["name"] != "a" {
+		t.Fatalf("out: %v", out)
+	}
+	if _, has := out["remark"]; has {
+		t.Fatalf("remark not dropped: %v", out)
+	}
+	if !strings.HasPrefix(out["id_card"].(string), "hmac:") {
+		t.Fatalf("id card: %v", out["id_card"])
+	}
+	friend := out["Friends"].([]interface{})[0].(map[string]interface{})
+	if friend["phone"] != "139****9000" {
+		t.Fatalf("friend: %v", friend)
+	}
+
+	if got := r.JSON(`{"filter":{"phone":"13800138000"},"token":"abcdefgh"}`); got != `{"filter":{"phone":"138****8000"},"token":"abc*efgh"}` {
+		t.Fatalf("json: %s", got)
+	}
+}
+
+func TestRedactorJSONNumber(t *testing.T) {
+	r := NewDefault()
+	in := `{"id":1234567890123456789,"amount":12.50,"list":[9007199254740993],"token":"abcdefgh"}`
+	want := `{"amount":12.50,"id":1234567890123456789,"list":[9007199254740993],"token":"abc*efgh"}`
+	if got := r.JSON(in); got != want {
+		t.Fatalf("json: %s", got)
+	}
+	if got := r.JSON(`{"a":1} trailing`); got != `{"a":1} trailing` {
+		t.Fatalf("trailing: %s", got)
+	}
+}
+
+func TestRedactorHashKey(t *testing.T) {
+	a := New().SetHashKey([]byte("k1")).AddKey(Hash, "phone")
+	b := New().SetHashKey([]byte("k1")).AddKey(Hash, "phone")
+	c := New().SetHashKey([]byte("k2")).AddKey(Hash, "phone")
+	in := map[string]interface{}{"phone": "13800138000"}
+	ha, hb, hc := a.Map(in)["phone"], b.Map(in)["phone"], c.Map(in)["phone"]
+	if ha != hb || ha == hc {
+		t.Fatalf("hash: %v %v %v", ha, hb, hc)
+	}
+	// 未设置密钥时使用随机密钥
+	if New().AddKey(Hash, "phone").Map(in)["phone"] == New().AddKey(Hash, "phone").Map(in)["phone"] {
+		t.Fatal("random hash key reused")
+	}
+	if Default() == nil || Default().String("13800138000") != "138****8000" {
+		t.Fatal("default redactor not set")
+	}
+}
@@ -0,0 +1,72 @@
+package scheduler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	common "github.com/actorbuf/iota/component/distributed_lock/redis_lock"
+	"github.com/actorbuf/iota/component/leader"
+	"github.com/go-redis/redis/v8"
+)
+
+// Guard 保证每一次tick在所有实例中只执行一次
+type Guard interface {
+	// Acquire 抢占某个任务的某次tick，返回true表示由当前实例执行
+	Acquire(ctx context.Context, job string, tick time.Time) (bool, error)
+}
+
+var _ Guard = new(RedisGuard)
+var _ Guard = new(LeaderGuard)
+
+// RedisGuard 通过redis分布式锁抢占tick，锁不主动释放，过期前同一tick不会被再次执行
+type RedisGuard struct {
+	redis  redis.UniversalClient
+	prefix string // key前缀
+	ttl    int    // 锁的过期时间（秒）
+}
+
+// NewRedisGuard 初始化redis抢占
+func NewRedisGuard(redis redis.UniversalClient) *RedisGuard {
+	return &RedisGuard{
+		redis:  redis,
+		prefix: "iota:scheduler:tick:",
+		ttl:    3600,
+	}
+}
+
+// SetPrefix 设置key前缀，初始为 "iota:scheduler:tick:"
+func (guard *RedisGuard) SetPrefix(prefix string) *RedisGuard {
+	guard.prefix = prefix
+	return guard
+}
+
+// SetTTL 设置tick锁的过期时间（秒）初始为3600秒；需要大于各实例之间的时钟误差与补跑窗口
+func (guard *RedisGuard) SetTTL(ttl int) *RedisGuard {
+	guard.ttl = ttl
+	return guard
+}
+
+func (guard *RedisGuard) Acquire(ctx context.Context, job string, tick time.Time) (bool, error) {
+	lockKey := guard.prefix + job + ":" + strconv.FormatInt(tick.Unix(), 10)
+	return common.NewLock(guard.redis).
+		WithContext(ctx).
+		SetLockKey(lockKey).
+		SetTTl(guard.ttl).
+		SetWait(false).
+		GetLock()
+}
+
+// LeaderGuard 只有leader执行
+type LeaderGuard struct {
+	leader *leader.Leader
+}
+
+// NewLeaderGuard 初始化leader抢占，leader需要调用方自行参与竞选（如：go leader.Run(ctx)）
+func NewLeaderGuard(leader *leader.Leader) *LeaderGuard {
+	return &LeaderGuard{leader: leader}
+}
+
+func (guard *LeaderGuard) Acquire(context.Context, string, time.Time) (bool, error) {
+	return guard.leader.IsLeader(), nil
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/actorbuf/iota/driver/mongodb"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Run 一次执行记录
+type Run struct {
+	Job         string    `json:"job" bson:"job"`
+	ScheduledAt time.Time `json:"scheduled_at" bson:"scheduled_at"` // 计划执行时间（tick）
+	StartAt     time.Time `json:"start_at" bson:"start_at"`
+	EndAt       time.Time `json:"end_at" bson:"end_at"`
+	Error       string    `json:"error" bson:"error"`
+	Host        string    `json:"host" bson:"host"`
+	TraceID     string    `json:"trace_id" bson:"trace_id"`
+}
+
+// History 执行记录存储
+type History interface {
+	// Record 记录一次执行
+	Record(ctx context.Context, run *Run) error
+	// Last 最近一次执行，没有记录时返回nil
+	Last(ctx context.Context, job string) (*Run, error)
+	// List 最近的执行记录，按时间倒序
+	List(ctx context.Context, job string, limit int64) ([]*Run, error)
+}
+
+var _ History = new(RedisHistory)
+var _ History = new(MongoHistory)
+
+// RedisHistory 执行记录存到redis list中
+type RedisHistory struct {
+	redis  redis.UniversalClient
+	prefix string // key前缀
+	max    int64  // 每个任务保留的最大记录数
+}
+
+// NewRedisHistory 初始化redis执行记录
+func NewRedisHistory(redis redis.UniversalClient) *RedisHistory {
+	return &RedisHistory{
+		redis:  redis,
+		prefix: "iota:scheduler:history:",
+		max:    100,
+	}
+}
+
+// SetPrefix 设置key前缀，初始为 "iota:scheduler:history:"
+func (history *RedisHistory) SetPrefix(prefix string) *RedisHistory {
+	history.prefix = prefix
+	return history
+}
+
+// SetMax 设置每个任务保留的最大记录数，初始为100
+func (history *RedisHistory) SetMax(max int64) *RedisHistory {
+	history.max = max
+	return history
+}
+
+func (history *RedisHistory) Record(ctx context.Context, run *Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	key := history.prefix + run.Job
+	pipe := history.redis.TxPipeline()
+	pipe.LPush(ctx, key, data)
+	pipe.LTrim(ctx, key, 0, history.max-1)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (history *RedisHistory) Last(ctx context.Context, job string) (*Run, error) {
+	runs, err := history.List(ctx, job, 1)
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return runs[0], nil
+}
+
+func (history *RedisHistory) List(ctx context.Context, job string, limit int64) ([]*Run, error) {
+	list, err := history.redis.LRange(ctx, history.prefix+job, 0, limit-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*Run, 0, len(list))
+	for i := range list {
+		run := new(Run)
+		if err = json.Unmarshal([]byte(list[i]), run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// MongoHistory 执行记录存到mongo中，建议对 job + scheduled_at 建立索引
+type MongoHistory struct {
+	col *mongodb.Collection
+}
+
+// NewMongoHistory 初始化mongo执行记录
+func NewMongoHistory(col *mongodb.Collection) *MongoHistory {
+	return &MongoHistory{col: col}
+}
+
+func (history *MongoHistory) Record(ctx context.Context, run *Run) error {
+	_, err := history.col.InsertOne(ctx, run)
+	return err
+}
+
+func (history *MongoHistory) Last(ctx context.Context, job string) (*Run, error) {
+	run := new(Run)
+	err := history.col.FindOne(ctx, bson.M{"job": job},
+		options.FindOne().SetSort(bson.D{{Key: "scheduled_at", Value: -1}})).Decode(run)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (history *MongoHistory) List(ctx context.Context, job string, limit int64) ([]*Run, error) {
+	cur, err := history.col.Find(ctx, bson.M{"job": job},
+		options.Find().SetSort(bson.D{{Key: "scheduled_at", Value: -1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*Run, 0)
+	if err = cur.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
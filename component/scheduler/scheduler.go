@@ -0,0 +1,322 @@
+// Package scheduler 分布式定时任务，每一次tick在所有实例中只执行一次
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log2 "log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iota/trace"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	operationCron     = "Cron-"
+	traceTagJob       = "cron.job"
+	traceTagScheduled = "cron.scheduled_at"
+)
+
+// MissedPolicy 错过执行（服务停机、进程卡顿等）的处理策略
+type MissedPolicy int
+
+const (
+	// MissedSkip 跳过错过的tick，只执行准时的tick
+	MissedSkip MissedPolicy = iota
+	// MissedRunOnce 错过的tick合并为一次执行（以最近一次tick执行）
+	MissedRunOnce
+	// MissedRunAll 错过的tick逐个补跑（最多补跑 maxCatchUp 次）
+	MissedRunAll
+)
+
+var (
+	// ErrSchedulerStarted 调度已启动，不能再添加任务
+	ErrSchedulerStarted = errors.New("scheduler: already started")
+	// ErrJobExists 任务名重复
+	ErrJobExists = errors.New("scheduler: job exists")
+)
+
+// JobFunc 任务执行方法，ctx 中带有本次执行的根span
+type JobFunc func(ctx context.Context) error
+
+// Logger 调度内部的日志
+type Logger interface {
+	Info(ctx context.Context, step string, str string)
+	Error(ctx context.Context, step string, err error)
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule cron.Schedule
+	handler  JobFunc
+	missed   MissedPolicy
+}
+
+// Scheduler 分布式定时任务调度
+type Scheduler struct {
+	guard            Guard
+	history          History
+	logger           Logger
+	parser           cron.Parser
+	location         *time.Location
+	misfireThreshold time.Duration // tick 超过该时间未执行视为错过
+	maxCatchUp       int           // MissedRunAll 最多补跑次数
+	host             string
+	now              func() time.Time
+
+	mu      sync.Mutex
+	jobs    []*job
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewScheduler 初始化调度，guard 保证单次执行，如：NewRedisGuard(redis)
+func NewScheduler(guard Guard) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{
+		guard:            guard,
+		parser:           cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor),
+		location:         time.Local,
+		misfireThreshold: time.Second,
+		maxCatchUp:       10,
+		host:             host,
+		now:              time.Now,
+	}
+}
+
+// SetHistory 设置执行记录存储；错过执行的检测依赖执行记录
+func (s *Scheduler) SetHistory(history History) *Scheduler {
+	s.history = history
+	return s
+}
+
+// SetLogger 设置日志
+func (s *Scheduler) SetLogger(logger Logger) *Scheduler {
+	s.logger = logger
+	return s
+}
+
+// SetLocation 设置cron表达式的时区，默认 time.Local
+func (s *Scheduler) SetLocation(location *time.Location) *Scheduler {
+	s.location = location
+	return s
+}
+
+// SetMisfireThreshold 设置错过判定时间，初始为1秒
+func (s *Scheduler) SetMisfireThreshold(threshold time.Duration) *Scheduler {
+	s.misfireThreshold = threshold
+	return s
+}
+
+// SetMaxCatchUp 设置 MissedRunAll 最多补跑次数，初始为10
+func (s *Scheduler) SetMaxCatchUp(maxCatchUp int) *Scheduler {
+	s.maxCatchUp = maxCatchUp
+	return s
+}
+
+// AddJob 添加任务；spec 支持5位/6位（带秒）cron表达式以及 "@every 1m"、"@daily" 等描述
+// name 在所有实例中需要一致，用于抢占tick与记录执行历史
+func (s *Scheduler) AddJob(name, spec string, handler JobFunc, missed MissedPolicy) error {
+	schedule, err := s.parser.Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrSchedulerStarted
+	}
+	for i := range s.jobs {
+		if s.jobs[i].name == name {
+			return ErrJobExists
+		}
+	}
+	s.jobs = append(s.jobs, &job{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		handler:  handler,
+		missed:   missed,
+	})
+	return nil
+}
+
+// Start 启动调度（非阻塞）
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrSchedulerStarted
+	}
+	s.started = true
+	ctx, s.cancel = context.WithCancel(ctx)
+	for i := range s.jobs {
+		s.wg.Add(1)
+		go func(j *job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(s.jobs[i])
+	}
+	return nil
+}
+
+// Stop 停止调度，等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop 单个任务的调度循环
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	last := s.lastTick(ctx, j)
+	for {
+		now := s.now().In(s.location)
+		ticks := s.dueTicks(j, last, now)
+		if len(ticks) > 0 {
+			last = ticks[len(ticks)-1]
+			for _, tick := range s.applyMissed(j, ticks, now) {
+				s.wg.Add(1)
+				go func(tick time.Time) {
+					defer s.wg.Done()
+					s.run(ctx, j, tick)
+				}(tick)
+			}
+		}
+
+		timer := time.NewTimer(j.schedule.Next(last).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// lastTick 最近一次执行的tick，用于检测错过的执行
+func (s *Scheduler) lastTick(ctx context.Context, j *job) time.Time {
+	now := s.now().In(s.location)
+	if j.missed == MissedSkip || s.history == nil {
+		return now
+	}
+	run, err := s.history.Last(ctx, j.name)
+	if err != nil {
+		s.error(ctx, "scheduler.lastTick", fmt.Errorf("job %s: %w", j.name, err))
+		return now
+	}
+	if run == nil || run.ScheduledAt.After(now) {
+		return now
+	}
+	return run.ScheduledAt.In(s.location)
+}
+
+// dueTicks last 之后到 now（含）之间的tick，只保留最近的 maxCatchUp+1 个
+func (s *Scheduler) dueTicks(j *job, last, now time.Time) []time.Time {
+	ticks := make([]time.Time, 0, 1)
+	for tick := j.schedule.Next(last); !tick.IsZero() && !tick.After(now); tick = j.schedule.Next(tick) {
+		ticks = append(ticks, tick)
+		if len(ticks) > s.maxCatchUp+1 {
+			ticks = ticks[1:]
+		}
+	}
+	return ticks
+}
+
+// applyMissed 根据策略得到需要执行的tick
+func (s *Scheduler) applyMissed(j *job, ticks []time.Time, now time.Time) []time.Time {
+	onTime := make([]time.Time, 0, 1)
+	missed := make([]time.Time, 0)
+	for _, tick := range ticks {
+		if now.Sub(tick) <= s.misfireThreshold {
+			onTime = append(onTime, tick)
+		} else {
+			missed = append(missed, tick)
+		}
+	}
+	if len(missed) == 0 {
+		return onTime
+	}
+
+	switch j.missed {
+	case MissedRunOnce:
+		return ticks[len(ticks)-1:]
+	case MissedRunAll:
+		if len(missed) > s.maxCatchUp {
+			missed = missed[len(missed)-s.maxCatchUp:]
+		}
+		return append(missed, onTime...)
+	default:
+		return onTime
+	}
+}
+
+// run 执行一次tick
+func (s *Scheduler) run(ctx context.Context, j *job, tick time.Time) {
+	ok, err := s.guard.Acquire(ctx, j.name, tick)
+	if err != nil {
+		s.error(ctx, "scheduler.run", fmt.Errorf("job %s acquire: %w", j.name, err))
+		return
+	}
+	if !ok {
+		return
+	}
+
+	span := trace.NewRootSpan(operationCron + j.name)
+	span.SetTag(traceTagJob, j.name)
+	span.SetTag(traceTagScheduled, tick.Format(time.RFC3339))
+	runCtx := trace.NewTracerContext(ctx, span)
+
+	record := &Run{
+		Job:         j.name,
+		ScheduledAt: tick,
+		StartAt:     s.now(),
+		Host:        s.host,
+		TraceID:     trace.ObtainTraceID(runCtx),
+	}
+	err = s.call(runCtx, j)
+	record.EndAt = s.now()
+	if err != nil {
+		record.Error = err.Error()
+		ext.Error.Set(span, true)
+		span.SetTag(trace.TagError, err.Error())
+		s.error(runCtx, "scheduler.run", fmt.Errorf("job %s: %w", j.name, err))
+	}
+	span.Finish()
+
+	if s.history == nil {
+		return
+	}
+	// 执行记录不受任务ctx取消影响
+	if err = s.history.Record(context.Background(), record); err != nil {
+		s.error(ctx, "scheduler.record", fmt.Errorf("job %s: %w", j.name, err))
+	}
+}
+
+// call 执行任务，panic转为error
+func (s *Scheduler) call(ctx context.Context, j *job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.handler(ctx)
+}
+
+func (s *Scheduler) error(ctx context.Context, step string, err error) {
+	if s.logger == nil {
+		log2.Printf("【error】 step %s: %s \n", step, err.Error())
+		return
+	}
+	s.logger.Error(ctx, step, err)
+}
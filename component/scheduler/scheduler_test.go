@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestSchedulerRunOnceAcrossInstances(t *testing.T) {
+	server, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	history := NewRedisHistory(client)
+
+	var mu sync.Mutex
+	ticks := make(map[int64]int)
+	handler := func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ticks[time.Now().Unix()]++
+		return nil
+	}
+
+	schedulers := make([]*Scheduler, 0, 2)
+	for i := 0; i < 2; i++ {
+		s := NewScheduler(NewRedisGuard(client)).SetHistory(history)
+		if err := s.AddJob("report", "* * * * * *", handler, MissedSkip); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		schedulers = append(schedulers, s)
+	}
+	time.Sleep(2500 * time.Millisecond)
+	for _, s := range schedulers {
+		s.Stop()
+	}
+
+	runs, err := history.List(context.Background(), "report", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) < 2 {
+		t.Fatalf("runs: %d", len(runs))
+	}
+	scheduled := make(map[time.Time]struct{})
+	for _, run := range runs {
+		if _, has := scheduled[run.ScheduledAt]; has {
+			t.Fatalf("tick %v run twice", run.ScheduledAt)
+		}
+		scheduled[run.ScheduledAt] = struct{}{}
+	}
+	for tick, count := range ticks {
+		if count != 1 {
+			t.Fatalf("tick %d run %d times", tick, count)
+		}
+	}
+}
+
+func TestSchedulerMissedPolicy(t *testing.T) {
+	s := NewScheduler(nil).SetLocation(time.UTC)
+	_ = s.AddJob("skip", "* * * * *", nil, MissedSkip)
+	_ = s.AddJob("once", "* * * * *", nil, MissedRunOnce)
+	_ = s.AddJob("all", "* * * * *", nil, MissedRunAll)
+	last := time.Date(2022, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		now  time.Time
+		want map[string]int
+	}{
+		// 10:05 准时，10:01~10:04 错过
+		{now: last.Add(5*time.Minute + 500*time.Millisecond), want: map[string]int{"skip": 1, "once": 1, "all": 5}},
+		// 10:01~10:05 全部错过
+		{now: last.Add(5*time.Minute + 30*time.Second), want: map[string]int{"skip": 0, "once": 1, "all": 5}},
+		// 超过补跑上限
+		{now: last.Add(30*time.Minute + 30*time.Second), want: map[string]int{"skip": 0, "once": 1, "all": 10}},
+	}
+	for _, c := range cases {
+		for _, j := range s.jobs {
+			ticks := s.applyMissed(j, s.dueTicks(j, last, c.now), c.now)
+			if len(ticks) != c.want[j.name] {
+				t.Fatalf("now %v job %s: %d ticks, want %d", c.now, j.name, len(ticks), c.want[j.name])
+			}
+			if len(ticks) > 0 && !ticks[len(ticks)-1].Equal(c.now.Truncate(time.Minute)) {
+				t.Fatalf("now %v job %s: last tick %v", c.now, j.name, ticks[len(ticks)-1])
+			}
+		}
+	}
+}
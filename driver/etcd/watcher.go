@@ -72,6 +72,15 @@ func NewEtcdWatcher(servers []string) (*Watcher, error) {
 // prefix允许对具有匹配前缀的键进行操作。例如，“ Get（foo，WithPrefix（））” 可以返回“ foo1”，“ foo2”，依此类推.
 // listener当监听到对应的事件时，将动作转发到Listener对应的实现. 使用前，请先实现Listener接口.
 func (mgr *Watcher) AddWatch(key string, prefix bool, listener Listener) bool {
+	return mgr.addWatch(key, prefix, false, listener)
+}
+
+// AddWatchWithLoad 添加监视，开始监听前先用已有的值调用 listener.Set，之后的变更从读取时的版本开始通知.
+func (mgr *Watcher) AddWatchWithLoad(key string, prefix bool, listener Listener) bool {
+	return mgr.addWatch(key, prefix, true, listener)
+}
+
+func (mgr *Watcher) addWatch(key string, prefix, load bool, listener Listener) bool {
 	mgr.mu.Lock()
 	defer mgr.mu.Unlock()
 	if _, ok := mgr.closeHandler[key]; ok {
@@ -81,7 +90,7 @@ func (mgr *Watcher) AddWatch(key string, prefix bool, listener Listener) bool {
 	mgr.closeHandler[key] = cancel
 
 	go func() {
-		_ = mgr.watch(ctx, key, prefix, listener)
+		_ = mgr.watch(ctx, key, prefix, load, listener)
 	}()
 
 	return true
@@ -121,8 +130,9 @@ func (mgr *Watcher) Close() {
 // watch 是内部实现的监视逻辑.
 // ctx是上下文；key是需要监听的键（如果有）.
 // prefix允许对具有匹配前缀的键进行操作。例如，“ Get（foo，WithPrefix（））” 可以返回“ foo1”，“ foo2”，依此类推.
+// load为true时先用已有的值调用listener.Set.
 // listener当监听到对应的事件时，将动作转发到Listener对应的实现.
-func (mgr *Watcher) watch(ctx context.Context, key string, prefix bool, listener Listener) error {
+func (mgr *Watcher) watch(ctx context.Context, key string, prefix, load bool, listener Listener) error {
 	ctx1, cancel := context.WithTimeout(context.Background(), timeOut)
 	defer cancel()
 	var getResp *clientv3.GetResponse
@@ -135,6 +145,11 @@ func (mgr *Watcher) watch(ctx context.Context, key string, prefix bool, listener
 	if err != nil {
 		return err
 	}
+	if load {
+		for _, kv := range getResp.Kvs {
+			listener.Set(kv.Key, kv.Value, kv.Version)
+		}
+	}
 
 	leaderCtx := clientv3.WithRequireLeader(ctx)
 	var watchChan clientv3.WatchChan
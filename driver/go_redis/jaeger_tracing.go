@@ -2,6 +2,7 @@ package goRedis
 
 import (
 	"context"
+	"github.com/actorbuf/iota/component/redact"
 	"github.com/actorbuf/iota/trace"
 	"github.com/go-redis/redis/v8"
 	"github.com/opentracing/opentracing-go"
@@ -26,13 +27,20 @@ const (
 )
 
 // jaegerHook is go-redis jaeger hook
-type jaegerHook struct{}
+type jaegerHook struct {
+	redactor *redact.Redactor // 写入span前对参数与结果脱敏，为空时使用 redact.Default()
+}
 
 // NewJaegerHook return jaegerHook
 func NewJaegerHook() redis.Hook {
 	return &jaegerHook{}
 }
 
+// NewJaegerHookWithRedactor return jaegerHook，参数与结果按 redactor 脱敏
+func NewJaegerHookWithRedactor(redactor *redact.Redactor) redis.Hook {
+	return &jaegerHook{redactor: redactor}
+}
+
 // BeforeProcess redis before execute action do something
 func (jh *jaegerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
 	span := trace.ObtainChildSpan(ctx, operationRedis+cmd.Name())
@@ -52,8 +60,8 @@ func (jh *jaegerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 	setCommonTag(ctx, span)
 
 	span.LogFields(tracerLog.String(logCmdName, cmd.Name()))
-	span.LogFields(tracerLog.Object(logCmdArgs, cmd.Args()))
-	span.LogFields(tracerLog.Object(logCmdResult, cmd.String()))
+	span.LogFields(tracerLog.Object(logCmdArgs, jh.args(cmd)))
+	span.LogFields(tracerLog.Object(logCmdResult, jh.getRedactor().String(cmd.String())))
 
 	if err := cmd.Err(); isRedisError(err) {
 		span.LogFields(tracerLog.Error(err))
@@ -86,8 +94,8 @@ func (jh *jaegerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmd
 			hasErr = true
 		}
 		span.LogFields(tracerLog.String(jh.getPipeLineLogKey(logCmdName, idx), cmd.Name()))
-		span.LogFields(tracerLog.Object(jh.getPipeLineLogKey(logCmdArgs, idx), cmd.Args()))
-		span.LogFields(tracerLog.String(jh.getPipeLineLogKey(logCmdResult, idx), cmd.String()))
+		span.LogFields(tracerLog.Object(jh.getPipeLineLogKey(logCmdArgs, idx), jh.args(cmd)))
+		span.LogFields(tracerLog.String(jh.getPipeLineLogKey(logCmdResult, idx), jh.getRedactor().String(cmd.String())))
 	}
 	if !hasErr {
 		span.SetTag(string(ext.Error), true)
@@ -96,6 +104,22 @@ func (jh *jaegerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmd
 	return nil
 }
 
+func (jh *jaegerHook) getRedactor() *redact.Redactor {
+	if jh.redactor != nil {
+		return jh.redactor
+	}
+	return redact.Default()
+}
+
+// args 脱敏后的命令参数
+func (jh *jaegerHook) args(cmd redis.Cmder) interface{} {
+	redactor := jh.getRedactor()
+	if redactor == nil {
+		return cmd.Args()
+	}
+	return redactor.Value(cmd.Args())
+}
+
 func (jh *jaegerHook) getPipeLineLogKey(logField string, idx int) string {
 	return logField + "-" + strconv.Itoa(idx)
 }
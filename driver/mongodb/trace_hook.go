@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"github.com/actorbuf/iota/component/redact"
 	"github.com/actorbuf/iota/trace"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -25,6 +26,9 @@ type jaegerHook struct {
 
 	// execStrLenMax 记录执行结果的最大长度，默认 DefaultExecStrLenMax
 	execStrLenMax int
+
+	// redactor 写入span前脱敏，为空时使用 redact.Default()
+	redactor *redact.Redactor
 }
 
 var _jaegerHook = &jaegerHook{useCur: true, execEncode: execStr, execStrLenMax: DefaultExecStrLenMax}
@@ -38,6 +42,16 @@ func NewJaegerHook(useCur bool) HandlerFunc {
 	}
 }
 
+// NewJaegerHookWithRedactor 写入span前按 redactor 对 filter、document 等脱敏
+func NewJaegerHookWithRedactor(useCur bool, redactor *redact.Redactor) HandlerFunc {
+	_jaegerHook := &jaegerHook{useCur: useCur, execEncode: execStr, execStrLenMax: DefaultExecStrLenMax, redactor: redactor}
+	return func(op *OpTrace) {
+		_ = _jaegerHook.Before(op)
+		op.Next()
+		_ = _jaegerHook.After(op)
+	}
+}
+
 // CustomJaegerHook 自定义jaegerHook
 func CustomJaegerHook(useCur bool, execEncode func(exec interface{}) string, execStrLenMax int) HandlerFunc {
 	_jaegerHook := &jaegerHook{useCur: useCur, execEncode: execEncode, execStrLenMax: execStrLenMax}
@@ -64,33 +78,33 @@ func (j *jaegerHook) Before(op *OpTrace) error {
 	span.SetTag(trace.TagPeerService, _tracePeerService)
 
 	logField := []log.Field{
-		log.String("db.exec.options", j.execStrLimitLen(j.execEncode(op.Opts))),
+		log.String("db.exec.options", j.encode(op.Opts)),
 	}
 
 	// 限制部分字符长度
 	switch op.Op {
 	case OpInsertOne, OpInsertMany:
-		logField = append(logField, log.String("db.exec.documents", j.execStrLimitLen(j.execEncode(op.InsertDocuments))))
+		logField = append(logField, log.String("db.exec.documents", j.encode(op.InsertDocuments)))
 	case OpDeleteOne, OpDeleteMany, OpCountDocuments, OpFind, OpFindOne, OpFindOneAndDelete:
-		logField = append(logField, log.String("db.exec.filter", j.execStrLimitLen(j.execEncode(op.Filter))))
+		logField = append(logField, log.String("db.exec.filter", j.encode(op.Filter)))
 	case OpUpdateOne, OpUpdateMany, OpFindOneAndUpdate:
 		logField = append(logField,
-			log.String("db.exec.filter", j.execStrLimitLen(j.execEncode(op.Filter))),
-			log.String("db.exec.update", j.execStrLimitLen(j.execEncode(op.Update))),
+			log.String("db.exec.filter", j.encode(op.Filter)),
+			log.String("db.exec.update", j.encode(op.Update)),
 		)
 	case OpReplaceOne, OpFindOneAndReplace:
 		logField = append(logField,
-			log.String("db.exec.filter", j.execStrLimitLen(j.execEncode(op.Filter))),
-			log.String("db.exec.replacement", j.execStrLimitLen(j.execEncode(op.Update))),
+			log.String("db.exec.filter", j.encode(op.Filter)),
+			log.String("db.exec.replacement", j.encode(op.Update)),
 		)
 	case OpAggregate, OpWatch:
 		logField = append(logField,
-			log.String("db.exec.pipeline", j.execStrLimitLen(j.execEncode(op.Pipeline))),
+			log.String("db.exec.pipeline", j.encode(op.Pipeline)),
 		)
 	case OpDistinct:
 		logField = append(logField,
-			log.String("db.exec.fieldName", j.execStrLimitLen(j.execEncode(op.FieldName))),
-			log.String("db.exec.filter", j.execStrLimitLen(j.execEncode(op.Filter))),
+			log.String("db.exec.fieldName", j.encode(op.FieldName)),
+			log.String("db.exec.filter", j.encode(op.Filter)),
 		)
 	case OpBulkWrite:
 		spanLogs := bson.M{
@@ -102,7 +116,7 @@ func (j *jaegerHook) Before(op *OpTrace) error {
 			spanLogs["info"] = "数据过多,只显示前5项"
 		}
 		logField = append(logField,
-			log.String("db.exec.models", j.execStrLimitLen(j.execEncode(spanLogs))),
+			log.String("db.exec.models", j.encode(spanLogs)),
 		)
 	}
 	// 植入对应的log
@@ -150,11 +164,23 @@ func (j *jaegerHook) After(op *OpTrace) error {
 	// 记录错误信息
 	if op.ResErr != nil {
 		ext.Error.Set(span, true)
-		span.LogFields(log.String("db.exec.err", op.ResErr.Error()))
+		span.LogFields(log.String("db.exec.err", j.getRedactor().String(op.ResErr.Error())))
 	}
 	return nil
 }
 
+// encode 序列化、脱敏后限制长度
+func (j *jaegerHook) encode(exec interface{}) string {
+	return j.execStrLimitLen(j.getRedactor().JSON(j.execEncode(exec)))
+}
+
+func (j *jaegerHook) getRedactor() *redact.Redactor {
+	if j.redactor != nil {
+		return j.redactor
+	}
+	return redact.Default()
+}
+
 func (j *jaegerHook) execStrLimitLen(execStr string) string {
 	if len(execStr) > j.execStrLenMax {
 		return execStr[0:j.execStrLenMax]
@@ -110,6 +110,9 @@ const (
 	NackDiscard
 	// NackRequeue deliver this message to a different consumer.
 	NackRequeue
+	// Manual the handler acks or nacks the delivery itself later, e.g. after a batch containing it is written.
+	// PrefetchCount limits how many deliveries per channel can wait for it.
+	Manual
 )
 
 func (c *consumer) Run(handler func(context.Context, *amqp.Delivery) Action) {
@@ -54,24 +54,35 @@ func (p *producer) Run() {
 }
 
 func (p *producer) Send(body []byte, channel *channel) {
+	_ = p.publish(body, channel)
+}
+
+// publish 发送消息，连接断开会尝试重连，超出重试次数返回错误
+func (p *producer) publish(body []byte, channel *channel) error {
+	channel.l.Lock()
+	defer channel.l.Unlock()
 	retryCount := 0
 	maxReconnectCount := 3
 	for {
-		err := channel.Chan[0].Publish(
-			p.exchange,
-			p.key,
-			false,
-			false,
-			amqp.Publishing{
-				ContentType:  p.contentType,
-				DeliveryMode: p.deliveryMode,
-				Body:         body,
-				Type:         p.msgType,
-			})
+		var err error
+		if len(channel.Chan) == 0 {
+			err = amqp.ErrClosed
+		} else {
+			err = channel.Chan[0].Publish(
+				p.exchange,
+				p.key,
+				false,
+				false,
+				amqp.Publishing{
+					ContentType:  p.contentType,
+					DeliveryMode: p.deliveryMode,
+					Body:         body,
+					Type:         p.msgType,
+				})
+		}
 		if err == amqp.ErrClosed {
-			if err := channel.Init(); err != nil {
-				retryCount++
-				continue
+			if initErr := channel.Init(); initErr != nil {
+				err = initErr
 			}
 		}
 		if err != nil {
@@ -85,11 +96,11 @@ func (p *producer) Send(body []byte, channel *channel) {
 					})
 					_ = p.alarm.Do()
 				}
-				return
+				return err
 			}
 			retryCount++
 			continue
 		}
-		return
+		return nil
 	}
 }
@@ -106,3 +106,42 @@ func (r *RabbitMQ) StartProducer() (chan<- []byte, error) {
 	time.Sleep(time.Second)
 	return r.producer.sendBody, nil
 }
+
+// StartSyncProducer 初始化同步生产者，通过 Publish 发送消息并拿到发送结果
+func (r *RabbitMQ) StartSyncProducer() error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	r.producer = &producer{
+		key:            r.config.BindKey,
+		exchange:       r.config.ExchangeName,
+		deliveryMode:   r.config.DeliveryMode,
+		sendBodyLength: 1,
+		alarm:          r.alarm,
+		logger:         r.logger,
+	}
+	r.producer.channel = &channel{
+		config: r.config,
+	}
+	r.config.ChannelNum = 1
+	if err := r.producer.channel.Init(); err != nil {
+		return err
+	}
+	return r.producer.Validate()
+}
+
+// Publish 同步发送消息（需要先 StartSyncProducer），连接断开会尝试重连
+func (r *RabbitMQ) Publish(body []byte) error {
+	if r.producer == nil {
+		return errors.New("producer is nil, please start producer")
+	}
+	return r.producer.publish(body, r.producer.channel)
+}
+
+// CloseProducer 关闭生产者连接
+func (r *RabbitMQ) CloseProducer() {
+	if r.producer == nil || r.producer.channel == nil || r.producer.channel.Conn == nil {
+		return
+	}
+	r.producer.channel.Close()
+}